@@ -0,0 +1,178 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"encoding/binary"
+	"sync"
+	"time"
+
+	"go.opencensus.io/trace"
+)
+
+// Defaults of AdaptiveSampler.
+const (
+	defaultAdaptiveInterval           = 10 * time.Second
+	defaultAdaptiveInitialProbability = 0.001
+	defaultAdaptiveMinProbability     = 0.00001
+)
+
+// AdaptiveSampler samples each operation, named after its span, with a
+// probability adjusted to the throughput observed for it, so that about
+// TargetSpansPerSecond spans are sampled per operation whatever its traffic,
+// as Jaeger's adaptive sampling does. Use its Sampler as the DefaultSampler
+// of the handlers.
+//
+// Spans whose parent is sampled are always sampled. Callers control the
+// method names received by servers, so only the first 1024 operations get
+// their own probability: the others share the one reported under
+// AdaptiveOverflowOperation.
+type AdaptiveSampler struct {
+	// TargetSpansPerSecond is the number of spans sampled per second and
+	// operation aimed at.
+	TargetSpansPerSecond float64
+
+	// Interval is the period over which throughput is measured before
+	// adjusting probabilities. It defaults to 10 seconds.
+	Interval time.Duration
+
+	// InitialProbability is the probability of operations until their
+	// throughput is known. It defaults to 0.001.
+	InitialProbability float64
+
+	// MinProbability bounds the probability of the busiest operations. It
+	// defaults to 0.00001.
+	MinProbability float64
+
+	mu       sync.Mutex
+	ops      map[string]*adaptiveOperation
+	overflow *adaptiveOperation
+}
+
+// AdaptiveOverflowOperation is the name under which
+// AdaptiveSampler.Probabilities reports the probability shared by the
+// operations beyond the first 1024.
+const AdaptiveOverflowOperation = "*"
+
+type adaptiveOperation struct {
+	probability float64
+	count       int
+	windowStart time.Time
+}
+
+// NewAdaptiveSampler returns an AdaptiveSampler aiming at
+// targetSpansPerSecond sampled spans per second and operation.
+func NewAdaptiveSampler(targetSpansPerSecond float64) *AdaptiveSampler {
+	return &AdaptiveSampler{TargetSpansPerSecond: targetSpansPerSecond}
+}
+
+// Sampler returns the trace.Sampler applying the probabilities of a.
+func (a *AdaptiveSampler) Sampler() trace.Sampler {
+	return func(p trace.SamplingParameters) trace.SamplingDecision {
+		if p.ParentContext.IsSampled() {
+			return trace.SamplingDecision{Sample: true}
+		}
+		probability := a.observe(p.Name)
+		// Decide on the lower bits of the trace ID, as
+		// trace.ProbabilitySampler does, so the decision is stable across
+		// the spans of a trace and 64-bit trace IDs, whose upper bits are
+		// zero, aren't all sampled.
+		bound := uint64(probability * (1 << 63))
+		x := binary.BigEndian.Uint64(p.TraceID[8:16]) >> 1
+		return trace.SamplingDecision{Sample: x < bound}
+	}
+}
+
+// Probabilities returns the current probability of each operation.
+func (a *AdaptiveSampler) Probabilities() map[string]float64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	probabilities := make(map[string]float64, len(a.ops))
+	for name, op := range a.ops {
+		probabilities[name] = op.probability
+	}
+	if a.overflow != nil {
+		probabilities[AdaptiveOverflowOperation] = a.overflow.probability
+	}
+	return probabilities
+}
+
+// observe counts a span of the operation name and returns its probability,
+// adjusted at the end of each interval.
+func (a *AdaptiveSampler) observe(name string) float64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := time.Now()
+	if a.ops == nil {
+		a.ops = make(map[string]*adaptiveOperation)
+	}
+	op, ok := a.ops[name]
+	if !ok {
+		switch {
+		case len(a.ops) < maxSpanNames:
+			op = &adaptiveOperation{probability: a.initialProbability(), windowStart: now}
+			a.ops[name] = op
+		case a.overflow == nil:
+			a.overflow = &adaptiveOperation{probability: a.initialProbability(), windowStart: now}
+			op = a.overflow
+		default:
+			op = a.overflow
+		}
+	}
+	op.count++
+	if elapsed := now.Sub(op.windowStart); elapsed >= a.interval() {
+		op.probability = a.adjust(op.probability, float64(op.count)/elapsed.Seconds())
+		op.count = 0
+		op.windowStart = now
+	}
+	return op.probability
+}
+
+// adjust returns the probability sampling TargetSpansPerSecond spans at qps,
+// at most doubling the current probability to avoid oscillations.
+func (a *AdaptiveSampler) adjust(current, qps float64) float64 {
+	p := a.TargetSpansPerSecond / qps
+	if p > 2*current {
+		p = 2 * current
+	}
+	if p > 1 {
+		p = 1
+	}
+	if floor := a.minProbability(); p < floor {
+		p = floor
+	}
+	return p
+}
+
+func (a *AdaptiveSampler) interval() time.Duration {
+	if a.Interval > 0 {
+		return a.Interval
+	}
+	return defaultAdaptiveInterval
+}
+
+func (a *AdaptiveSampler) initialProbability() float64 {
+	if a.InitialProbability > 0 {
+		return a.InitialProbability
+	}
+	return defaultAdaptiveInitialProbability
+}
+
+func (a *AdaptiveSampler) minProbability() float64 {
+	if a.MinProbability > 0 {
+		return a.MinProbability
+	}
+	return defaultAdaptiveMinProbability
+}
@@ -0,0 +1,91 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"encoding/binary"
+	"math/rand"
+	"strconv"
+	"sync"
+	"testing"
+
+	"go.opencensus.io/trace"
+)
+
+func TestAdaptiveSamplerBoundsOperations(t *testing.T) {
+	a := NewAdaptiveSampler(1)
+	s := a.Sampler()
+	for i := 0; i < maxSpanNames+100; i++ {
+		s(trace.SamplingParameters{Name: "op" + strconv.Itoa(i)})
+	}
+	p := a.Probabilities()
+	if got, want := len(p), maxSpanNames+1; got != want {
+		t.Errorf("got %d operations, want %d", got, want)
+	}
+	if _, ok := p[AdaptiveOverflowOperation]; !ok {
+		t.Errorf("no %q operation for the operations over the limit", AdaptiveOverflowOperation)
+	}
+}
+
+func TestAdaptiveSampler64BitTraceIDs(t *testing.T) {
+	a := &AdaptiveSampler{TargetSpansPerSecond: 1, InitialProbability: 0.1}
+	s := a.Sampler()
+	r := rand.New(rand.NewSource(1))
+	sampled := 0
+	const n = 10000
+	for i := 0; i < n; i++ {
+		// A Jaeger or B3 64-bit trace ID: the upper bytes are zero.
+		var id trace.TraceID
+		binary.BigEndian.PutUint64(id[8:], r.Uint64())
+		if s(trace.SamplingParameters{TraceID: id, Name: "op"}).Sample {
+			sampled++
+		}
+	}
+	if sampled < n/20 || sampled > n/5 {
+		t.Errorf("sampled %d of %d 64-bit trace IDs at probability 0.1", sampled, n)
+	}
+}
+
+func TestAdaptiveSamplerSampledParent(t *testing.T) {
+	s := (&AdaptiveSampler{TargetSpansPerSecond: 1, InitialProbability: 0.00001}).Sampler()
+	if !s(trace.SamplingParameters{ParentContext: trace.SpanContext{TraceOptions: 1}, Name: "op"}).Sample {
+		t.Error("span of a sampled parent not sampled")
+	}
+}
+
+func TestAdaptiveSamplerConcurrently(t *testing.T) {
+	a := &AdaptiveSampler{TargetSpansPerSecond: 1, Interval: 1}
+	s := a.Sampler()
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				s(trace.SamplingParameters{Name: "op" + strconv.Itoa(j%5)})
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			a.Probabilities()
+		}()
+	}
+	wg.Wait()
+	for name, p := range a.Probabilities() {
+		if p <= 0 || p > 1 {
+			t.Errorf("probability of %s = %v, want in (0, 1]", name, p)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"context"
+
+	propag "github.com/akhenakh/ocgrpc_propagation/propagation"
+	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// AllowlistUnaryInterceptor removes the outgoing metadata keys not in l from
+// the context passed to the handler. Chained after the propagation
+// interceptors, it ensures only the keys of l are copied from incoming to
+// outgoing metadata. Metadata added by the handler itself isn't filtered.
+func AllowlistUnaryInterceptor(l propag.Allowlist) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(filterOutgoingMetadata(ctx, l), req)
+	}
+}
+
+// AllowlistStreamInterceptor removes the outgoing metadata keys not in l from
+// the context passed to the handler, see AllowlistUnaryInterceptor.
+func AllowlistStreamInterceptor(l propag.Allowlist) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		wrapped := grpc_middleware.WrapServerStream(stream)
+		wrapped.WrappedContext = filterOutgoingMetadata(stream.Context(), l)
+		return handler(srv, wrapped)
+	}
+}
+
+func filterOutgoingMetadata(ctx context.Context, l propag.Allowlist) context.Context {
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if !ok {
+		return ctx
+	}
+	filtered := metadata.MD{}
+	for k, vs := range md {
+		if l.Allows(k) {
+			filtered[k] = vs
+		}
+	}
+	return metadata.NewOutgoingContext(ctx, filtered)
+}
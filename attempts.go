@@ -0,0 +1,73 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"context"
+	"sync/atomic"
+
+	"google.golang.org/grpc"
+)
+
+// Attributes of the client spans of retried RPCs.
+const (
+	attemptAttribute          = "grpc.attempt"
+	transparentRetryAttribute = "grpc.transparent_retry"
+)
+
+// callAttempts counts the attempts of a client call. gRPC calls
+// ClientHandler.TagRPC once per attempt, with the context of the call.
+type callAttempts struct {
+	n int64 // access atomically
+}
+
+type callAttemptsKey struct{}
+
+type attemptKey struct{}
+
+// AttemptsUnaryClientInterceptor counts the attempts of each call, made when
+// gRPC retries it, so ClientHandler records the attempt number of each client
+// span in the grpc.attempt attribute and counts retries in ClientRetries.
+// Without it, only transparent retries are visible.
+func AttemptsUnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(context.WithValue(ctx, callAttemptsKey{}, &callAttempts{}), method, req, reply, cc, opts...)
+	}
+}
+
+// AttemptsStreamClientInterceptor counts the attempts of each stream, see
+// AttemptsUnaryClientInterceptor.
+func AttemptsStreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(context.WithValue(ctx, callAttemptsKey{}, &callAttempts{}), desc, cc, method, opts...)
+	}
+}
+
+// attemptTagRPC returns a copy of ctx with the number of the attempt it is
+// the context of, starting at 1, if the attempts of the call are counted.
+func attemptTagRPC(ctx context.Context) context.Context {
+	a, ok := ctx.Value(callAttemptsKey{}).(*callAttempts)
+	if !ok {
+		return ctx
+	}
+	return context.WithValue(ctx, attemptKey{}, atomic.AddInt64(&a.n, 1))
+}
+
+// attemptFromContext returns the number of the attempt of ctx, or 0 if the
+// attempts of the call aren't counted.
+func attemptFromContext(ctx context.Context) int64 {
+	n, _ := ctx.Value(attemptKey{}).(int64)
+	return n
+}
@@ -0,0 +1,88 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"go.opencensus.io/tag"
+	"go.opencensus.io/trace"
+)
+
+// AttributeTags maps the keys of the span attributes added with AddAttributes
+// to the tag keys their values are also recorded with, so the attributes set
+// by application handlers can break down the metrics of their RPCs.
+type AttributeTags map[string]tag.Key
+
+// AddAttributes adds attrs to the span of the RPC of ctx, from the handler of
+// the RPC, unless the span isn't sampled. The attributes mapped by the
+// AttributeTags of the ServerHandler are also upserted as tags of the
+// measures recorded at the end of the RPC, whether the span is sampled or not.
+func AddAttributes(ctx context.Context, attrs ...trace.Attribute) {
+	if span := trace.FromContext(ctx); span.IsRecordingEvents() {
+		span.AddAttributes(attrs...)
+	}
+	if d, ok := ctx.Value(rpcDataKey{}).(*rpcData); ok {
+		d.attributeTags.add(attrs)
+	}
+}
+
+// rpcAttributeTags holds the tags set by AddAttributes during an RPC. The
+// handler of a stream may add attributes while the RPC ends, so mutators is
+// only accessed with mu held.
+type rpcAttributeTags struct {
+	keys AttributeTags
+
+	mu       sync.Mutex
+	mutators []tag.Mutator
+}
+
+func newRPCAttributeTags(keys AttributeTags) *rpcAttributeTags {
+	if len(keys) == 0 {
+		return nil
+	}
+	return &rpcAttributeTags{keys: keys}
+}
+
+func (t *rpcAttributeTags) add(attrs []trace.Attribute) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, a := range attrs {
+		if k, ok := t.keys[a.Key()]; ok {
+			t.mutators = append(t.mutators, tag.Upsert(k, fmt.Sprint(a.Value())))
+		}
+	}
+}
+
+// apply upserts the tags added so far to the tags of ctx. Mutators are
+// applied one by one, as tag.New rejects them all when one value is invalid.
+func (t *rpcAttributeTags) apply(ctx context.Context) context.Context {
+	if t == nil {
+		return ctx
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, m := range t.mutators {
+		if tagged, err := tag.New(ctx, m); err == nil {
+			ctx = tagged
+		}
+	}
+	return ctx
+}
@@ -0,0 +1,188 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/akhenakh/ocgrpc_propagation/baggage"
+	propag "github.com/akhenakh/ocgrpc_propagation/propagation"
+	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	ocstats "go.opencensus.io/stats"
+	"go.opencensus.io/tag"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// JaegerBaggagePropagateUnaryInterceptor propagates incoming Jaeger and W3C
+// baggage to gRPC client, as Jaeger baggage. Items are dropped or truncated according to rm, which may be
+// nil to allow every key, then according to limits.
+func JaegerBaggagePropagateUnaryInterceptor(rm baggage.RestrictionManager, limits baggage.Limits) grpc.UnaryServerInterceptor {
+	return baggagePropagateUnaryInterceptor(fixedRestrictions(rm), limits)
+}
+
+// JaegerBaggagePropagateStreamInterceptor propagates incoming Jaeger and W3C
+// baggage to gRPC client, as Jaeger baggage. Items are dropped or truncated according to rm, which may be
+// nil to allow every key, then according to limits.
+func JaegerBaggagePropagateStreamInterceptor(rm baggage.RestrictionManager, limits baggage.Limits) grpc.StreamServerInterceptor {
+	return baggagePropagateStreamInterceptor(fixedRestrictions(rm), limits)
+}
+
+// restrictionsFunc returns the restrictions of the baggage propagated by the
+// RPC of ctx, calling method. nil allows every key.
+type restrictionsFunc func(ctx context.Context, method string) baggage.RestrictionManager
+
+func fixedRestrictions(rm baggage.RestrictionManager) restrictionsFunc {
+	return func(context.Context, string) baggage.RestrictionManager { return rm }
+}
+
+func baggagePropagateUnaryInterceptor(restrictions restrictionsFunc, limits baggage.Limits) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(propagateJaegerBaggage(ctx, info.FullMethod, restrictions(ctx, info.FullMethod), limits), req)
+	}
+}
+
+func baggagePropagateStreamInterceptor(restrictions restrictionsFunc, limits baggage.Limits) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := stream.Context()
+		wrapped := grpc_middleware.WrapServerStream(stream)
+		wrapped.WrappedContext = propagateJaegerBaggage(ctx, info.FullMethod, restrictions(ctx, info.FullMethod), limits)
+		return handler(srv, wrapped)
+	}
+}
+
+func propagateJaegerBaggage(ctx context.Context, method string, rm baggage.RestrictionManager, limits baggage.Limits) context.Context {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+	items := restrictBaggage(incomingBaggage(md), rm)
+	// Sort the items so the limits are applied deterministically.
+	sort.Slice(items, func(i, j int) bool { return items[i].Key < items[j].Key })
+	items, truncated, dropped := limits.Apply(items)
+	recordBaggageLimited(ctx, method, "truncated", truncated)
+	recordBaggageLimited(ctx, method, "dropped", dropped)
+	for _, item := range items {
+		ctx = metadata.AppendToOutgoingContext(ctx, propag.JaegerBaggagePrefix+item.Key, item.Value)
+	}
+	return ctx
+}
+
+// restrictBaggage drops or truncates items, in place, according to rm, which
+// may be nil to allow every key.
+func restrictBaggage(items []baggage.Item, rm baggage.RestrictionManager) []baggage.Item {
+	if rm == nil {
+		return items
+	}
+	kept := items[:0]
+	for _, item := range items {
+		var ok bool
+		if item.Value, ok = baggage.Restrict(rm, item.Key, item.Value); ok {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+// incomingBaggage returns the Jaeger and W3C baggage items of md. Jaeger items
+// win over W3C items with the same key. W3C values that can't be carried by
+// gRPC metadata, such as non-ASCII text, are dropped.
+func incomingBaggage(md metadata.MD) []baggage.Item {
+	var items []baggage.Item
+	seen := map[string]bool{}
+	for k, vs := range md {
+		k = propag.CanonicalKey(k)
+		if !strings.HasPrefix(k, propag.JaegerBaggagePrefix) || len(vs) == 0 {
+			continue
+		}
+		item := baggage.Item{Key: strings.TrimPrefix(k, propag.JaegerBaggagePrefix), Value: vs[0]}
+		items = append(items, item)
+		seen[item.Key] = true
+	}
+	for _, v := range propag.Lookup(md, propag.BaggageKey) {
+		w3c, err := baggage.ParseW3C(v)
+		if err != nil {
+			continue
+		}
+		for _, item := range w3c {
+			item.Key = propag.CanonicalKey(item.Key)
+			if seen[item.Key] || !isPrintableASCII(item.Value) {
+				continue
+			}
+			items = append(items, item)
+			seen[item.Key] = true
+		}
+	}
+	return items
+}
+
+func isPrintableASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < 0x20 || s[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// baggageTagRPC enforces c.BaggageRestrictions, then c.BaggageLimits, on the
+// baggage of ctx, and writes it to the W3C baggage metadata when
+// c.InjectW3CBaggage is set.
+func (c *ClientHandler) baggageTagRPC(ctx context.Context) context.Context {
+	restricted := c.BaggageRestrictions != nil || c.BaggageLimits != baggage.Limits{}
+	if !restricted && !c.InjectW3CBaggage {
+		return ctx
+	}
+	items := baggage.Items(ctx)
+	if len(items) == 0 {
+		return ctx
+	}
+	md, _ := metadata.FromOutgoingContext(ctx)
+	md = md.Copy()
+	if restricted {
+		items, _, _ = c.BaggageLimits.Apply(restrictBaggage(items, c.BaggageRestrictions))
+		setJaegerBaggage(md, items)
+	}
+	if c.InjectW3CBaggage && len(items) > 0 {
+		md.Set(propag.BaggageKey, baggage.FormatW3C(items))
+	}
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+// setJaegerBaggage replaces the Jaeger baggage of md with items.
+func setJaegerBaggage(md metadata.MD, items []baggage.Item) {
+	for k := range md {
+		if strings.HasPrefix(propag.CanonicalKey(k), propag.JaegerBaggagePrefix) {
+			delete(md, k)
+		}
+	}
+	for _, item := range items {
+		md.Set(propag.JaegerBaggagePrefix+item.Key, item.Value)
+	}
+}
+
+func recordBaggageLimited(ctx context.Context, method, action string, n int) {
+	if n == 0 {
+		return
+	}
+	ocstats.RecordWithTags(ctx,
+		[]tag.Mutator{
+			tag.Upsert(KeyServerMethod, methodName(method)),
+			tag.Upsert(KeyBaggageLimitAction, action),
+		},
+		ServerBaggageLimitedItems.M(int64(n)))
+}
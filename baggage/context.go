@@ -0,0 +1,64 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package baggage
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	propag "github.com/akhenakh/ocgrpc_propagation/propagation"
+	"google.golang.org/grpc/metadata"
+)
+
+// The baggage of a context is kept in its outgoing gRPC metadata, under the
+// propagation.JaegerBaggagePrefix keys, so it is propagated to every RPC made
+// with the context and goes through the same interceptors as the baggage
+// forwarded from incoming RPCs.
+
+// Set returns a copy of ctx with the baggage item key set to value, replacing
+// any previous value. Keys are case-insensitive.
+func Set(ctx context.Context, key, value string) context.Context {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	md = md.Copy()
+	md.Set(propag.JaegerBaggagePrefix+key, value)
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+// Get returns the value of the baggage item key of ctx. Baggage received by a
+// server is only found once copied by the baggage propagation interceptors of
+// the ocgrpc package, after restrictions and limits were applied.
+func Get(ctx context.Context, key string) (string, bool) {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	if vs := propag.Lookup(md, propag.JaegerBaggagePrefix+key); len(vs) > 0 {
+		return vs[len(vs)-1], true
+	}
+	return "", false
+}
+
+// Items returns the baggage items of ctx, sorted by key.
+func Items(ctx context.Context) []Item {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	var items []Item
+	for k, vs := range md {
+		k = propag.CanonicalKey(k)
+		if !strings.HasPrefix(k, propag.JaegerBaggagePrefix) || len(vs) == 0 {
+			continue
+		}
+		items = append(items, Item{Key: strings.TrimPrefix(k, propag.JaegerBaggagePrefix), Value: vs[len(vs)-1]})
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Key < items[j].Key })
+	return items
+}
@@ -0,0 +1,87 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package baggage
+
+// Item is a baggage key and its value.
+type Item struct {
+	Key   string
+	Value string
+}
+
+// LimitPolicy controls what happens to a baggage item whose value is longer
+// than Limits.MaxValueLength.
+type LimitPolicy int
+
+const (
+	// TruncateValues truncates over-long values to Limits.MaxValueLength.
+	TruncateValues LimitPolicy = iota
+
+	// DropItems drops items with over-long values.
+	DropItems
+)
+
+// Limits bounds the baggage propagated by a service, so a misbehaving
+// upstream can't inflate every downstream request. Zero values mean no limit.
+type Limits struct {
+	// MaxItems is the maximum number of baggage items.
+	MaxItems int
+
+	// MaxKeyLength is the maximum length of a baggage key. Items with longer
+	// keys are dropped.
+	MaxKeyLength int
+
+	// MaxValueLength is the maximum length of a baggage value, in bytes.
+	// Values are truncated on a UTF-8 character boundary.
+	MaxValueLength int
+
+	// MaxTotalBytes is the maximum sum of the key and value lengths of all
+	// the baggage items.
+	MaxTotalBytes int
+
+	// Policy applied to values longer than MaxValueLength.
+	Policy LimitPolicy
+}
+
+// Apply enforces l on items, in order. It returns the items to propagate and
+// the number of truncated and dropped items.
+func (l Limits) Apply(items []Item) (kept []Item, truncated, dropped int) {
+	total := 0
+	for _, it := range items {
+		if l.MaxItems > 0 && len(kept) >= l.MaxItems {
+			dropped++
+			continue
+		}
+		if l.MaxKeyLength > 0 && len(it.Key) > l.MaxKeyLength {
+			dropped++
+			continue
+		}
+		if l.MaxValueLength > 0 && len(it.Value) > l.MaxValueLength {
+			if l.Policy == DropItems {
+				dropped++
+				continue
+			}
+			it.Value = truncate(it.Value, l.MaxValueLength)
+			truncated++
+		}
+		size := len(it.Key) + len(it.Value)
+		if l.MaxTotalBytes > 0 && total+size > l.MaxTotalBytes {
+			dropped++
+			continue
+		}
+		total += size
+		kept = append(kept, it)
+	}
+	return kept, truncated, dropped
+}
@@ -0,0 +1,51 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package baggage
+
+import (
+	"reflect"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestLimitsApply(t *testing.T) {
+	items := []Item{{"a", "héllo"}, {"b", "world"}, {"c", "!"}, {"long", "x"}}
+	for _, tc := range []struct {
+		name               string
+		limits             Limits
+		want               []Item
+		truncated, dropped int
+	}{
+		{"none", Limits{}, items, 0, 0},
+		{"max items", Limits{MaxItems: 2}, items[:2], 0, 2},
+		{"max key length", Limits{MaxKeyLength: 1}, items[:3], 0, 1},
+		{"truncated", Limits{MaxValueLength: 3}, []Item{{"a", "hé"}, {"b", "wor"}, {"c", "!"}, {"long", "x"}}, 2, 0},
+		{"truncated on a rune boundary", Limits{MaxValueLength: 2}, []Item{{"a", "h"}, {"b", "wo"}, {"c", "!"}, {"long", "x"}}, 2, 0},
+		{"dropped", Limits{MaxValueLength: 3, Policy: DropItems}, []Item{{"c", "!"}, {"long", "x"}}, 0, 2},
+		{"max total bytes", Limits{MaxTotalBytes: 9}, []Item{{"a", "héllo"}, {"c", "!"}}, 0, 2},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, truncated, dropped := tc.limits.Apply(items)
+			if !reflect.DeepEqual(got, tc.want) || truncated != tc.truncated || dropped != tc.dropped {
+				t.Errorf("Apply() = %q, %d, %d; want %q, %d, %d", got, truncated, dropped, tc.want, tc.truncated, tc.dropped)
+			}
+			for _, it := range got {
+				if !utf8.ValidString(it.Value) {
+					t.Errorf("value %q isn't valid UTF-8", it.Value)
+				}
+			}
+		})
+	}
+}
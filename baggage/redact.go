@@ -0,0 +1,66 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package baggage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces the values redacted by a Redactor without Hash.
+const Redacted = "[redacted]"
+
+// Redactor replaces the baggage values matching one of its patterns, so
+// secrets accidentally placed in baggage don't spread.
+type Redactor struct {
+	// Patterns are matched against whole values.
+	Patterns []*regexp.Regexp
+
+	// Prefixes are matched against the start of values, such as "Bearer ".
+	Prefixes []string
+
+	// Hash may be set to true to replace values by a hash, so equal values
+	// can still be correlated, instead of Redacted.
+	Hash bool
+}
+
+// Matches reports whether value must be redacted.
+func (r *Redactor) Matches(value string) bool {
+	for _, p := range r.Prefixes {
+		if strings.HasPrefix(value, p) {
+			return true
+		}
+	}
+	for _, re := range r.Patterns {
+		if re.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Redact returns the replacement of value, and whether it was redacted.
+func (r *Redactor) Redact(value string) (string, bool) {
+	if !r.Matches(value) {
+		return value, false
+	}
+	if r.Hash {
+		sum := sha256.Sum256([]byte(value))
+		return "sha256:" + hex.EncodeToString(sum[:16]), true
+	}
+	return Redacted, true
+}
@@ -0,0 +1,267 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package baggage implements Jaeger baggage: the baggage items carried by a
+// context, and restrictions on which baggage keys may be propagated
+// downstream and how long their values may be.
+package baggage
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"google.golang.org/grpc/grpclog"
+)
+
+const (
+	// DefaultMaxValueLength is the maximum length of a baggage value used
+	// when no restriction has been received yet, as in jaeger-client-go.
+	DefaultMaxValueLength = 2048
+
+	defaultHostPort        = "localhost:5778"
+	defaultRefreshInterval = time.Minute
+)
+
+// Restriction determines whether a baggage key is allowed and contains any
+// restrictions on the baggage value.
+type Restriction struct {
+	KeyAllowed     bool
+	MaxValueLength int
+}
+
+// RestrictionManager keeps track of valid baggage keys and their restrictions.
+type RestrictionManager interface {
+	GetRestriction(key string) Restriction
+}
+
+// Restrict applies the restriction of key to value. It returns false if the
+// key isn't allowed, otherwise value is truncated to the maximum length,
+// without splitting a UTF-8 character. A negative maximum length is treated
+// as zero.
+func Restrict(m RestrictionManager, key, value string) (string, bool) {
+	r := m.GetRestriction(key)
+	if !r.KeyAllowed {
+		return "", false
+	}
+	return truncate(value, r.MaxValueLength), true
+}
+
+// truncate returns the longest prefix of value of at most n bytes that doesn't
+// split a UTF-8 sequence.
+func truncate(value string, n int) string {
+	if len(value) <= n {
+		return value
+	}
+	if n < 0 {
+		n = 0
+	}
+	for n > 0 && !utf8.RuneStart(value[n]) {
+		n--
+	}
+	return value[:n]
+}
+
+// DefaultRestrictionManager allows any baggage key with values up to
+// MaxValueLength.
+type DefaultRestrictionManager struct {
+	// MaxValueLength defaults to DefaultMaxValueLength.
+	MaxValueLength int
+}
+
+// GetRestriction implements RestrictionManager.
+func (m DefaultRestrictionManager) GetRestriction(key string) Restriction {
+	if m.MaxValueLength == 0 {
+		return Restriction{KeyAllowed: true, MaxValueLength: DefaultMaxValueLength}
+	}
+	return Restriction{KeyAllowed: true, MaxValueLength: m.MaxValueLength}
+}
+
+// AllowlistRestrictionManager allows only the baggage keys listed in Keys,
+// with values up to MaxValueLength. With no keys, every item is denied, as
+// suits the baggage sent by untrusted callers.
+type AllowlistRestrictionManager struct {
+	Keys []string
+
+	// MaxValueLength defaults to DefaultMaxValueLength.
+	MaxValueLength int
+}
+
+// GetRestriction implements RestrictionManager.
+func (m AllowlistRestrictionManager) GetRestriction(key string) Restriction {
+	for _, k := range m.Keys {
+		if k == key {
+			return DefaultRestrictionManager{MaxValueLength: m.MaxValueLength}.GetRestriction(key)
+		}
+	}
+	return Restriction{KeyAllowed: false}
+}
+
+// RemoteOptions configures a RemoteRestrictionManager.
+type RemoteOptions struct {
+	// HostPort of the jaeger-agent restrictions endpoint, defaults to
+	// localhost:5778.
+	HostPort string
+
+	// RefreshInterval between two polls of the agent, defaults to one minute.
+	RefreshInterval time.Duration
+
+	// DenyBaggageOnInitializationFailure may be set to true to deny every
+	// baggage key until the restrictions have been fetched once. Otherwise
+	// any key is allowed with values up to DefaultMaxValueLength.
+	DenyBaggageOnInitializationFailure bool
+
+	// Client used to poll the agent, defaults to http.DefaultClient.
+	Client *http.Client
+
+	// Logger receives the polling errors. It defaults to grpclog.
+	Logger Logger
+}
+
+// Logger receives the warnings of a RemoteRestrictionManager. The
+// ocgrpc.Logger of the handlers implements it.
+type Logger interface {
+	Warningf(format string, args ...interface{})
+}
+
+// RemoteRestrictionManager polls a jaeger-agent for the baggage restrictions
+// of a service, following the jaeger-client-go protocol. Keys missing from
+// the restrictions returned by the agent are denied.
+type RemoteRestrictionManager struct {
+	url             string
+	refreshInterval time.Duration
+	deny            bool
+	client          *http.Client
+	logger          Logger
+
+	mu           sync.RWMutex
+	initialized  bool
+	restrictions map[string]Restriction
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	stopped  sync.WaitGroup
+}
+
+// NewRemoteRestrictionManager returns a RemoteRestrictionManager for
+// serviceName and starts polling the agent. Close must be called to stop
+// polling.
+func NewRemoteRestrictionManager(serviceName string, o RemoteOptions) *RemoteRestrictionManager {
+	if o.HostPort == "" {
+		o.HostPort = defaultHostPort
+	}
+	if o.RefreshInterval <= 0 {
+		o.RefreshInterval = defaultRefreshInterval
+	}
+	if o.Client == nil {
+		o.Client = http.DefaultClient
+	}
+	m := &RemoteRestrictionManager{
+		url:             fmt.Sprintf("http://%s/baggageRestrictions?service=%s", o.HostPort, url.QueryEscape(serviceName)),
+		refreshInterval: o.RefreshInterval,
+		deny:            o.DenyBaggageOnInitializationFailure,
+		client:          o.Client,
+		logger:          o.Logger,
+		stop:            make(chan struct{}),
+	}
+	m.stopped.Add(1)
+	go m.pollManager()
+	return m
+}
+
+// GetRestriction implements RestrictionManager.
+func (m *RemoteRestrictionManager) GetRestriction(key string) Restriction {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if !m.initialized {
+		if m.deny {
+			return Restriction{}
+		}
+		return Restriction{KeyAllowed: true, MaxValueLength: DefaultMaxValueLength}
+	}
+	return m.restrictions[key]
+}
+
+// Close stops polling the agent. It may be called several times.
+func (m *RemoteRestrictionManager) Close() error {
+	m.stopOnce.Do(func() { close(m.stop) })
+	m.stopped.Wait()
+	return nil
+}
+
+func (m *RemoteRestrictionManager) pollManager() {
+	defer m.stopped.Done()
+	m.updateRestrictions()
+	ticker := time.NewTicker(m.refreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			m.updateRestrictions()
+		case <-m.stop:
+			return
+		}
+	}
+}
+
+// restriction is the JSON representation of a restriction returned by the
+// agent.
+type restriction struct {
+	BaggageKey     string `json:"baggageKey"`
+	MaxValueLength int    `json:"maxValueLength"`
+}
+
+func (m *RemoteRestrictionManager) updateRestrictions() {
+	restrictions, err := m.fetchRestrictions()
+	if err != nil {
+		m.warningf("opencensus: Failed to fetch baggage restrictions: %v", err)
+		return
+	}
+	byKey := make(map[string]Restriction, len(restrictions))
+	for _, r := range restrictions {
+		byKey[r.BaggageKey] = Restriction{KeyAllowed: true, MaxValueLength: r.MaxValueLength}
+	}
+	m.mu.Lock()
+	m.initialized = true
+	m.restrictions = byKey
+	m.mu.Unlock()
+}
+
+func (m *RemoteRestrictionManager) warningf(format string, args ...interface{}) {
+	if m.logger == nil {
+		grpclog.Warningf(format, args...)
+		return
+	}
+	m.logger.Warningf(format, args...)
+}
+
+func (m *RemoteRestrictionManager) fetchRestrictions() ([]restriction, error) {
+	resp, err := m.client.Get(m.url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %q from %s", resp.Status, m.url)
+	}
+	var restrictions []restriction
+	if err := json.NewDecoder(resp.Body).Decode(&restrictions); err != nil {
+		return nil, err
+	}
+	return restrictions, nil
+}
@@ -0,0 +1,103 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package baggage
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type maxLength int
+
+func (m maxLength) GetRestriction(string) Restriction {
+	return Restriction{KeyAllowed: true, MaxValueLength: int(m)}
+}
+
+func TestRestrict(t *testing.T) {
+	for _, tc := range []struct {
+		max   int
+		value string
+		want  string
+	}{
+		{max: 5, value: "abc", want: "abc"},
+		{max: 3, value: "abcdef", want: "abc"},
+		{max: 0, value: "abc", want: ""},
+		{max: -1, value: "abc", want: ""},
+		{max: 2, value: "héllo", want: "h"},
+		{max: 3, value: "héllo", want: "hé"},
+		{max: 1, value: "é", want: ""},
+		{max: 3, value: "日本", want: "日"},
+		{max: 5, value: "日本", want: "日"},
+	} {
+		got, ok := Restrict(maxLength(tc.max), "key", tc.value)
+		if !ok || got != tc.want {
+			t.Errorf("Restrict(max %d, %q) = %q, %v, want %q, true", tc.max, tc.value, got, ok, tc.want)
+		}
+	}
+	if _, ok := Restrict(AllowlistRestrictionManager{}, "key", "value"); ok {
+		t.Error("Restrict allowed a key missing from the allowlist")
+	}
+}
+
+type recordingLogger chan string
+
+func (l recordingLogger) Warningf(format string, args ...interface{}) {
+	select {
+	case l <- fmt.Sprintf(format, args...):
+	default:
+	}
+}
+
+func TestRemoteRestrictionManagerLogger(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	logger := make(recordingLogger, 1)
+	m := NewRemoteRestrictionManager("svc", RemoteOptions{
+		HostPort:                           strings.TrimPrefix(srv.URL, "http://"),
+		RefreshInterval:                    time.Hour,
+		DenyBaggageOnInitializationFailure: true,
+		Logger:                             logger,
+	})
+	defer m.Close()
+
+	select {
+	case msg := <-logger:
+		if !strings.Contains(msg, "baggage restrictions") {
+			t.Errorf("logged %q", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the polling error wasn't logged")
+	}
+	if r := m.GetRestriction("key"); r.KeyAllowed {
+		t.Error("key allowed before the restrictions were fetched")
+	}
+}
+
+func TestRemoteRestrictionManagerCloseTwice(t *testing.T) {
+	m := NewRemoteRestrictionManager("svc", RemoteOptions{
+		HostPort:        "127.0.0.1:1",
+		RefreshInterval: time.Hour,
+		Logger:          make(recordingLogger),
+	})
+	m.Close()
+	m.Close()
+}
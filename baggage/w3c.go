@@ -0,0 +1,55 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package baggage
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ParseW3C parses the members of a W3C baggage header of the form
+// key1=value1;property,key2=value2, decoding their percent-encoded values.
+// Member properties are ignored.
+func ParseW3C(v string) ([]Item, error) {
+	var items []Item
+	for _, member := range strings.Split(v, ",") {
+		member, _, _ = strings.Cut(member, ";")
+		if member = strings.TrimSpace(member); member == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(member, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || strings.ContainsAny(key, " \t\"\\") {
+			return nil, fmt.Errorf("baggage: malformed member %q", member)
+		}
+		value, err := url.PathUnescape(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("baggage: malformed value of %q: %v", key, err)
+		}
+		items = append(items, Item{Key: key, Value: value})
+	}
+	return items, nil
+}
+
+// FormatW3C formats items as a W3C baggage header, percent-encoding their
+// values.
+func FormatW3C(items []Item) string {
+	members := make([]string, len(items))
+	for i, it := range items {
+		members[i] = it.Key + "=" + url.PathEscape(it.Value)
+	}
+	return strings.Join(members, ",")
+}
@@ -0,0 +1,88 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package baggage
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseW3C(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		v       string
+		want    []Item
+		wantErr bool
+	}{
+		{name: "empty", v: ""},
+		{name: "members", v: "userId=alice,serverNode=DF%2028", want: []Item{{"userId", "alice"}, {"serverNode", "DF 28"}}},
+		{name: "whitespace", v: " userId = alice , tenant=acme ", want: []Item{{"userId", "alice"}, {"tenant", "acme"}}},
+		{name: "properties ignored", v: "userId=alice;ttl=60;secret,tenant=acme", want: []Item{{"userId", "alice"}, {"tenant", "acme"}}},
+		{name: "empty members skipped", v: "userId=alice,,tenant=acme,", want: []Item{{"userId", "alice"}, {"tenant", "acme"}}},
+		{name: "empty value", v: "userId=", want: []Item{{"userId", ""}}},
+		{name: "reserved characters", v: "k=a%2Cb%3Bc%3Dd%25e", want: []Item{{"k", "a,b;c=d%e"}}},
+		{name: "equal sign in value", v: "k=a=b", want: []Item{{"k", "a=b"}}},
+		{name: "plus is not a space", v: "k=a+b", want: []Item{{"k", "a+b"}}},
+		{name: "utf-8", v: "k=h%C3%A9llo", want: []Item{{"k", "héllo"}}},
+		{name: "no equal sign", v: "userId", wantErr: true},
+		{name: "empty key", v: "=alice", wantErr: true},
+		{name: "space in key", v: "user Id=alice", wantErr: true},
+		{name: "quote in key", v: "\"userId\"=alice", wantErr: true},
+		{name: "invalid escape", v: "k=%zz", wantErr: true},
+		{name: "truncated escape", v: "k=abc%4", wantErr: true},
+		{name: "lone percent", v: "k=100%", wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ParseW3C(tc.v)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ParseW3C(%q) error = %v, want error %v", tc.v, err, tc.wantErr)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("ParseW3C(%q) = %q, want %q", tc.v, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFormatW3C(t *testing.T) {
+	for _, tc := range []struct {
+		items []Item
+		want  string
+	}{
+		{nil, ""},
+		{[]Item{{"userId", "alice"}, {"serverNode", "DF 28"}}, "userId=alice,serverNode=DF%2028"},
+		{[]Item{{"k", "a,b;c%d"}}, "k=a%2Cb%3Bc%25d"},
+		{[]Item{{"k", "\"quoted\\\""}}, "k=%22quoted%5C%22"},
+		{[]Item{{"k", "héllo"}}, "k=h%C3%A9llo"},
+	} {
+		if got := FormatW3C(tc.items); got != tc.want {
+			t.Errorf("FormatW3C(%q) = %q, want %q", tc.items, got, tc.want)
+		}
+	}
+}
+
+func TestW3CRoundTrip(t *testing.T) {
+	items := []Item{
+		{"reserved", ",;=%+/?#&"},
+		{"spaces", " leading and trailing "},
+		{"quotes", "\"\\"},
+		{"utf-8", "héllo, 世界"},
+		{"empty", ""},
+	}
+	got, err := ParseW3C(FormatW3C(items))
+	if err != nil || !reflect.DeepEqual(got, items) {
+		t.Errorf("ParseW3C(FormatW3C(%q)) = %q, %v", items, got, err)
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"context"
+
+	"github.com/akhenakh/ocgrpc_propagation/baggage"
+	propag "github.com/akhenakh/ocgrpc_propagation/propagation"
+	"go.opencensus.io/tag"
+	"google.golang.org/grpc/metadata"
+)
+
+// BaggageTags maps baggage keys to the tag keys their values are exchanged
+// with, so the tags breaking down metrics and the baggage of traces stay
+// consistent across services using either. Baggage keys are lower case.
+type BaggageTags map[string]tag.Key
+
+// tagsToBaggage sets the baggage items of c.BaggageTags to the values of their
+// tags in ctx. Items already in the baggage of ctx are kept.
+func (c *ClientHandler) tagsToBaggage(ctx context.Context) context.Context {
+	if len(c.BaggageTags) == 0 {
+		return ctx
+	}
+	tags := tag.FromContext(ctx)
+	if tags == nil {
+		return ctx
+	}
+	for key, k := range c.BaggageTags {
+		v, ok := tags.Value(k)
+		if !ok {
+			continue
+		}
+		if _, ok := baggage.Get(ctx, key); !ok {
+			ctx = baggage.Set(ctx, key, v)
+		}
+	}
+	return ctx
+}
+
+// baggageToTags upserts the tags of s.BaggageTags with the values of their
+// incoming baggage items, Jaeger or W3C. Values that aren't valid tag values
+// are ignored.
+func (s *ServerHandler) baggageToTags(ctx context.Context) context.Context {
+	if len(s.BaggageTags) == 0 {
+		return ctx
+	}
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+	for _, item := range incomingBaggage(md) {
+		k, ok := s.BaggageTags[propag.CanonicalKey(item.Key)]
+		if !ok {
+			continue
+		}
+		// Mutators are applied one by one, as tag.New rejects them all
+		// when one value is invalid.
+		if tagged, err := tag.New(ctx, tag.Upsert(k, item.Value)); err == nil {
+			ctx = tagged
+		}
+	}
+	return ctx
+}
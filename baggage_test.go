@@ -0,0 +1,94 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/akhenakh/ocgrpc_propagation/baggage"
+	propag "github.com/akhenakh/ocgrpc_propagation/propagation"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestPublicEndpointBaggagePerRPC(t *testing.T) {
+	c := Config{
+		IsPublicEndpointFunc:  PublicMethods("/svc/Public"),
+		PublicEndpointBaggage: baggage.AllowlistRestrictionManager{},
+	}
+	if !c.propagatesBaggage() {
+		t.Fatal("propagatesBaggage() = false with PublicEndpointBaggage and IsPublicEndpointFunc")
+	}
+	interceptor := baggagePropagateUnaryInterceptor(c.baggageRestrictions(), c.BaggageLimits)
+	for _, tc := range []struct {
+		method string
+		want   bool
+	}{
+		{"/svc/Public", false},
+		{"/svc/Private", true},
+	} {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(propag.JaegerBaggagePrefix+"user", "alice"))
+		var got bool
+		handler := func(ctx context.Context, _ interface{}) (interface{}, error) {
+			md, _ := metadata.FromOutgoingContext(ctx)
+			got = len(md.Get(propag.JaegerBaggagePrefix+"user")) > 0
+			return nil, nil
+		}
+		if _, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tc.method}, handler); err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.want {
+			t.Errorf("%s: baggage propagated = %v, want %v", tc.method, got, tc.want)
+		}
+	}
+}
+
+func TestPublicEndpointBaggageUnused(t *testing.T) {
+	c := Config{PublicEndpointBaggage: baggage.AllowlistRestrictionManager{}}
+	if c.propagatesBaggage() {
+		t.Error("propagatesBaggage() = true with no public endpoint")
+	}
+}
+
+func TestClientBaggageRestrictions(t *testing.T) {
+	c := Config{BaggageRestrictions: baggage.AllowlistRestrictionManager{Keys: []string{"user"}, MaxValueLength: 3}}.ClientHandler()
+	c.InjectW3CBaggage = true
+	ctx := baggage.Set(context.Background(), "user", "alice")
+	ctx = baggage.Set(ctx, "secret", "value")
+	ctx = c.baggageTagRPC(ctx)
+
+	md, _ := metadata.FromOutgoingContext(ctx)
+	if vs := md.Get(propag.JaegerBaggagePrefix + "user"); len(vs) != 1 || vs[0] != "ali" {
+		t.Errorf("user baggage %q, want it truncated to %q", vs, "ali")
+	}
+	if vs := md.Get(propag.JaegerBaggagePrefix + "secret"); len(vs) != 0 {
+		t.Errorf("secret baggage %q sent", vs)
+	}
+	if vs := md.Get(propag.BaggageKey); len(vs) != 1 || vs[0] != "user=ali" {
+		t.Errorf("W3C baggage %q, want %q", vs, "user=ali")
+	}
+}
+
+func TestClientBaggageLimits(t *testing.T) {
+	c := Config{BaggageLimits: baggage.Limits{MaxItems: 1, MaxValueLength: 2}}.ClientHandler()
+	ctx := baggage.Set(context.Background(), "b", "second")
+	ctx = baggage.Set(ctx, "a", "éa")
+	ctx = c.baggageTagRPC(ctx)
+
+	if items := baggage.Items(ctx); len(items) != 1 || items[0] != (baggage.Item{Key: "a", Value: "é"}) {
+		t.Errorf("baggage sent %q, want only a=é", items)
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import "go.opencensus.io/stats/view"
+
+// Buckets holds bucket boundaries replacing the default distributions of the
+// views of this package, such as sub-millisecond latency buckets for RPCs
+// within a datacenter. Nil boundaries keep the default distribution.
+type Buckets struct {
+	// Milliseconds replaces DefaultMillisecondsDistribution, used by the
+	// latency views.
+	Milliseconds []float64
+
+	// Bytes replaces DefaultBytesDistribution, used by the payload size
+	// views.
+	Bytes []float64
+
+	// MessageCount replaces DefaultMessageCountDistribution, used by the
+	// message count views.
+	MessageCount []float64
+}
+
+// Views returns copies of views, such as DefaultServerViews, whose default
+// distributions are replaced by the bucket boundaries of b. Register them
+// instead of views: views of the same name can't be registered with
+// different aggregations.
+func (b Buckets) Views(views ...*view.View) []*view.View {
+	customized := make([]*view.View, len(views))
+	for i, v := range views {
+		c := *v
+		switch {
+		case v.Aggregation == DefaultMillisecondsDistribution && b.Milliseconds != nil:
+			c.Aggregation = view.Distribution(b.Milliseconds...)
+		case v.Aggregation == DefaultBytesDistribution && b.Bytes != nil:
+			c.Aggregation = view.Distribution(b.Bytes...)
+		case v.Aggregation == DefaultMessageCountDistribution && b.MessageCount != nil:
+			c.Aggregation = view.Distribution(b.MessageCount...)
+		}
+		customized[i] = &c
+	}
+	return customized
+}
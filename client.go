@@ -15,8 +15,13 @@
 package ocgrpc
 
 import (
+	"context"
+	"time"
+
+	"github.com/akhenakh/ocgrpc_propagation/baggage"
+	propag "github.com/akhenakh/ocgrpc_propagation/propagation"
 	"go.opencensus.io/trace"
-	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
 
 	"google.golang.org/grpc/stats"
 )
@@ -27,30 +32,277 @@ type ClientHandler struct {
 	// StartOptions allows configuring the StartOptions used to create new spans.
 	//
 	// StartOptions.SpanKind will always be set to trace.SpanKindClient
-	// for spans started by this handler.
+	// for spans started by this handler, unless overridden for a call with
+	// WithStartOptions.
 	StartOptions trace.StartOptions
+
+	// MethodSamplers may be set to sample the spans of some methods, keyed
+	// by full method name such as "/pkg.Service/Method", with their own
+	// sampler instead of StartOptions.Sampler and DefaultSampler.
+	MethodSamplers map[string]trace.Sampler
+
+	// FormatSpanName may be set to name the spans of RPCs, such as to keep
+	// the full /pkg.Service/Method form, add a prefix or strip version
+	// suffixes. It defaults to DefaultSpanName.
+	FormatSpanName func(rti *stats.RPCTagInfo) string
+
+	// OnSpanStart may be set to add attributes to the span of every RPC.
+	OnSpanStart SpanStartHook
+
+	// DefaultSampler is used when StartOptions.Sampler is nil. If both are
+	// nil, the global default sampler applies and a warning is logged once.
+	DefaultSampler trace.Sampler
+
+	// ClassifyContextErrors may be set to true to tell caller cancellations
+	// from expired deadlines when an RPC ends with codes.Canceled or
+	// codes.DeadlineExceeded, whatever code the transport surfaced. The
+	// precise code is set as the span status and the cause is recorded in
+	// the grpc.context_error attribute, telling deadlines set locally from
+	// deadlines propagated from the inbound RPC handled by a ServerHandler.
+	ClassifyContextErrors bool
+
+	// ErrorCodes may be set to only set the span status of RPCs ending with
+	// one of these codes, such as OTelClientErrorCodes. The code of every
+	// RPC is recorded in the rpc.grpc.status_code attribute anyway. If nil,
+	// every code but OK sets the span status.
+	ErrorCodes map[codes.Code]bool
+
+	// DisableMessageEvents may be set to true to not add a message event to
+	// the span for every message sent or received, which is costly on long
+	// streams. Spans are still started, ended and given a status.
+	DisableMessageEvents bool
+
+	// MessageEventLimit may be set to cap the message events added to the
+	// span of long-lived streams, such as the first 100 and then one in
+	// 1000. The number of messages is then recorded in the
+	// grpc.sent_messages and grpc.received_messages attributes.
+	MessageEventLimit MessageEventLimit
+
+	// MessageGapThreshold may be set to annotate the span of an RPC when the
+	// time since the previous message sent or received, in the same
+	// direction, reaches it, so backpressure from flow control and slow peers
+	// is visible on streams. The longest gaps are recorded in the
+	// grpc.max_send_gap_ms and grpc.max_receive_gap_ms attributes.
+	MessageGapThreshold time.Duration
+
+	// SpanLimits may be set to cap the annotations, message events and
+	// attributes added to the span of each RPC, dropping and counting the
+	// events over the limits.
+	SpanLimits SpanLimits
+
+	// TransportAttributes may be set to true to record the connection of
+	// each RPC on its span: the address and port of the server in the
+	// net.peer.ip and net.peer.port attributes, the local address in
+	// net.host.ip and net.host.port, and the transport in net.transport.
+	TransportAttributes bool
+
+	// PayloadAnnotations may be set to annotate sampled spans with the
+	// messages sent and received, for debugging. It is off by default as
+	// messages may hold personal data or secrets.
+	PayloadAnnotations *PayloadAnnotations
+
+	// FormatStatus may be set to customize the span status of the RPCs
+	// ending with an error. It defaults to DefaultStatusFormatter.
+	FormatStatus StatusFormatter
+
+	// RedactStatusMessage may be set to redact the span status messages,
+	// which are error messages that may hold personal data.
+	RedactStatusMessage func(code codes.Code, msg string) string
+
+	// StatusMessageMaxLength may be set to truncate the span status
+	// messages to this length.
+	StatusMessageMaxLength int
+
+	// MessageSpans may be set to true to create a grpc.message child span
+	// per message of sampled streaming RPCs, with the index and size of
+	// messages as attributes. In lock-step streams, a request and its
+	// response share a span, measuring the time taken to answer.
+	MessageSpans bool
+
+	// ConnectionSpans may be set to true to trace connections: a
+	// grpc.connection span lasts from the establishment of each connection
+	// to its end, with the addresses of both ends and the transport as
+	// attributes, so connection churn shows next to RPC traces.
+	ConnectionSpans bool
+
+	// SkipUnsampledRoots may be set to true to not trace nor propagate a
+	// trace context for the RPCs made without a parent span that the
+	// sampler doesn't sample, saving their cost. The sampler decides on the
+	// IDs of the span, which is then dropped. Servers make their own
+	// sampling decision. It has no effect when the global default sampler
+	// applies.
+	SkipUnsampledRoots bool
+
+	// DisableTracing may be set to true to not trace RPCs, such as when a
+	// sidecar traces them, rather than sampling them out.
+	DisableTracing bool
+
+	// DisableStats may be set to true to not record measures, nor propagate
+	// tags, when only tracing is wanted.
+	DisableStats bool
+
+	// MaxTagsSize is the size above which the encoded tags of the context
+	// aren't propagated in the grpc-tags-bin metadata. It defaults to
+	// DefaultMaxTagsSize.
+	MaxTagsSize int
+
+	// InjectTraceContext may be set to true to also write the span context
+	// to the W3C traceparent and tracestate metadata, for peers such as
+	// Envoy or OpenTelemetry services. The tracestate received by a
+	// ServerHandler is propagated to the RPCs made while handling it.
+	InjectTraceContext bool
+
+	// InjectB3 may be set to true to also write the span context to the B3
+	// multi-header metadata, for Zipkin instrumented peers.
+	InjectB3 bool
+
+	// InjectB3Single may be set to true to also write the span context to
+	// the b3 single header metadata, as Istio and newer Zipkin clients do.
+	InjectB3Single bool
+
+	// InjectJaeger may be set to true to also write the span context to the
+	// uber-trace-id metadata, for peers instrumented with jaeger-client. It
+	// replaces the uber-trace-id forwarded by the Jaeger propagation
+	// interceptors, so the peer's span is a child of this client span.
+	InjectJaeger bool
+
+	// Jaeger configures how outgoing uber-trace-id metadata is written,
+	// notably how 128-bit trace IDs are handled.
+	Jaeger propag.JaegerOptions
+
+	// TraceID64 may be set to true to write only the lower 64 bits of trace
+	// IDs to the text formats, for legacy Zipkin and Jaeger backends
+	// rejecting 128-bit trace IDs: the other bits are zeroed, and the B3 and
+	// Jaeger formats are written with 64-bit trace IDs. The binary format
+	// keeps the full trace ID, also recorded in the grpc.trace_id attribute
+	// of client spans.
+	TraceID64 bool
+
+	// InjectXRay may be set to true to also write the span context to the
+	// AWS X-Ray x-amzn-trace-id metadata.
+	InjectXRay bool
+
+	// InjectCloudTrace may be set to true to also write the span context to
+	// the Google Cloud x-cloud-trace-context metadata.
+	InjectCloudTrace bool
+
+	// SamplingPriorityKey may be set, such as to
+	// DefaultSamplingPriorityKey, to write the numeric sampling priority of
+	// the trace to this metadata key, for priority-based samplers
+	// downstream: the priority read by the ServerHandler of the incoming
+	// RPC, 2 if it forced the trace, or 1 and 0 for sampled and unsampled
+	// spans otherwise.
+	SamplingPriorityKey string
+
+	// InjectW3CBaggage may be set to true to also write the baggage of the
+	// context, see baggage.Items, to the W3C baggage metadata.
+	InjectW3CBaggage bool
+
+	// BaggageRestrictions may be set to drop or truncate the baggage items
+	// of the context, such as those set with baggage.Set, before they are
+	// sent.
+	BaggageRestrictions baggage.RestrictionManager
+
+	// BaggageLimits may be set to bound the baggage items of the context
+	// before they are sent, applied in key order.
+	BaggageLimits baggage.Limits
+
+	// BaggageTags may be set to export the values of these tags of the
+	// context as baggage items of outgoing RPCs, under the mapped keys.
+	// Items already in the baggage of the context win.
+	BaggageTags BaggageTags
+
+	// Propagators may be set to write the span context in these formats,
+	// replacing the formats enabled by the Inject fields. Include
+	// propagation.BinaryPropagator to keep propagating to OpenCensus peers.
+	Propagators []propag.Propagator
+
+	// Signer may be set to sign the outgoing trace contexts, so servers
+	// with the same Signer can verify them.
+	Signer Signer
+
+	// Filter may be set to ignore some RPCs, such as health checks: they get
+	// no span and no stats.
+	Filter FilterFunc
+
+	// TracingFlags may be set to turn tracing off for some methods at
+	// runtime. Stats are still recorded.
+	TracingFlags FeatureFlags
+
+	// Logger receives the warnings of the handler, such as panics recovered
+	// while handling RPC events. It defaults to grpclog.
+	Logger Logger
+
+	fallback   samplerFallback
+	duplicates duplicateDetector
 }
 
-// HandleConn exists to satisfy gRPC stats.Handler.
+// HandleConn implements connection stats, and ends the span of connections
+// when ConnectionSpans is set.
 func (c *ClientHandler) HandleConn(ctx context.Context, cs stats.ConnStats) {
-	// no-op
+	statsHandleConn(ctx, cs)
+	handleConnSpan(ctx, cs)
 }
 
-// TagConn exists to satisfy gRPC stats.Handler.
+// TagConn implements per-connection context management.
 func (c *ClientHandler) TagConn(ctx context.Context, cti *stats.ConnTagInfo) context.Context {
-	// no-op
+	if !c.DisableStats {
+		ctx = c.statsTagConn(ctx, cti)
+	}
+	if c.ConnectionSpans && !c.DisableTracing {
+		ctx = startConnSpan(ctx, c.sampler(""), transportAttributes(cti.LocalAddr, cti.RemoteAddr, true))
+	}
 	return ctx
 }
 
 // HandleRPC implements per-RPC tracing and stats instrumentation.
 func (c *ClientHandler) HandleRPC(ctx context.Context, rs stats.RPCStats) {
-	traceHandleRPC(ctx, rs)
-	statsHandleRPC(ctx, rs)
+	if !handles(ctx, clientOwnerKey{}, c, rs) {
+		return
+	}
+	defer recoverHandleRPC(ctx, c.Logger, rs)
+	if traced(ctx, true) {
+		traceHandleRPC(ctx, rs, traceOptions{
+			classifyContextErrors: c.ClassifyContextErrors,
+			errorCodes:            c.ErrorCodes,
+			disableMessageEvents:  c.DisableMessageEvents,
+			messageEventLimit:     c.MessageEventLimit,
+			messageGapThreshold:   c.MessageGapThreshold,
+			spanLimits:            c.SpanLimits,
+			transportAttributes:   c.TransportAttributes,
+			payloads:              c.PayloadAnnotations,
+			status: statusOptions{
+				format:    c.FormatStatus,
+				redact:    c.RedactStatusMessage,
+				maxLength: c.StatusMessageMaxLength,
+			},
+		})
+	}
+	if !c.DisableStats {
+		statsHandleRPC(ctx, rs)
+	}
 }
 
 // TagRPC implements per-RPC context management.
-func (c *ClientHandler) TagRPC(ctx context.Context, rti *stats.RPCTagInfo) context.Context {
-	ctx = c.traceTagRPC(ctx, rti)
-	ctx = c.statsTagRPC(ctx, rti)
+func (c *ClientHandler) TagRPC(ctx context.Context, rti *stats.RPCTagInfo) (ret context.Context) {
+	defer recoverTagRPC(ctx, &ctx, c.Logger, &ret)
+	if filtered(c.Filter, rti.FullMethodName) {
+		// Not claiming the RPC makes HandleRPC ignore it too.
+		return ctx
+	}
+	ctx, ok := c.duplicates.claim(ctx, clientOwnerKey{}, c, "ClientHandler", c.Logger)
+	if !ok {
+		return ctx
+	}
+	ctx = attemptTagRPC(ctx)
+	ctx, tracing := tagTracing(ctx, c.DisableTracing, c.TracingFlags, rti.FullMethodName, true)
+	if tracing {
+		ctx = c.traceTagRPC(ctx, rti)
+	}
+	ctx = c.tagsToBaggage(ctx)
+	ctx = c.baggageTagRPC(ctx)
+	if !c.DisableStats {
+		ctx = c.statsTagRPC(ctx, rti)
+	}
 	return ctx
 }
@@ -28,9 +28,50 @@ var (
 	ClientReceivedMessagesPerRPC = stats.Int64("grpc.io/client/received_messages_per_rpc", "Number of response messages received per RPC (always 1 for non-streaming RPCs).", stats.UnitDimensionless)
 	ClientReceivedBytesPerRPC    = stats.Int64("grpc.io/client/received_bytes_per_rpc", "Total bytes received across all response messages per RPC.", stats.UnitBytes)
 	ClientRoundtripLatency       = stats.Float64("grpc.io/client/roundtrip_latency", "Time between first byte of request sent to last byte of response received, or terminal error.", stats.UnitMilliseconds)
+	ClientRetries                = stats.Int64("grpc.io/client/retries", "Number of retry attempts, transparent or configured by a retry policy.", stats.UnitDimensionless)
+	ClientActiveRPCs             = stats.Int64("grpc.io/client/active_rpcs", "Change in the number of active RPCs, 1 when an RPC starts and -1 when it ends.", stats.UnitDimensionless)
 	ClientServerLatency          = stats.Float64("grpc.io/client/server_latency", `Propagated from the server and should have the same value as "grpc.io/server/latency".`, stats.UnitMilliseconds)
 )
 
+// The following variables are measures of the compressed size of messages
+// recorded by ClientHandler. They are the same as ClientSentBytesPerRPC and
+// ClientReceivedBytesPerRPC when messages aren't compressed.
+var (
+	ClientSentCompressedBytesPerRPC     = stats.Int64("grpc.io/client/sent_compressed_message_bytes_per_rpc", "Total compressed bytes sent across all request messages per RPC, without gRPC framing.", stats.UnitBytes)
+	ClientReceivedCompressedBytesPerRPC = stats.Int64("grpc.io/client/received_compressed_message_bytes_per_rpc", "Total compressed bytes received across all response messages per RPC, without gRPC framing.", stats.UnitBytes)
+)
+
+// The following variables are measures recorded by ClientHandler for
+// streaming RPCs only, so streams aren't drowned among unary RPCs:
+var (
+	ClientStreamDuration         = stats.Float64("grpc.io/client/stream_duration", "Time between the start of a stream and its end.", stats.UnitMilliseconds)
+	ClientStreamSentMessages     = stats.Int64("grpc.io/client/stream_sent_messages", "Number of messages sent per stream.", stats.UnitDimensionless)
+	ClientStreamReceivedMessages = stats.Int64("grpc.io/client/stream_received_messages", "Number of messages received per stream.", stats.UnitDimensionless)
+)
+
+// The following variables are measures of the metadata of the RPCs of
+// ClientHandler, since oversized metadata fails RPCs: the received header and
+// trailer are measured by their wire length, the sent header by its size
+// before compression, see metadataSize.
+var (
+	ClientSentHeaderBytes      = stats.Int64("grpc.io/client/sent_header_bytes", "Size of the header metadata sent per RPC, before compression.", stats.UnitBytes)
+	ClientReceivedHeaderBytes  = stats.Int64("grpc.io/client/received_header_bytes", "Wire length of the header metadata received per RPC.", stats.UnitBytes)
+	ClientReceivedTrailerBytes = stats.Int64("grpc.io/client/received_trailer_bytes", "Wire length of the trailer metadata received per RPC.", stats.UnitBytes)
+)
+
+// The following variables are measures of the connections of ClientHandler,
+// tagged with KeyClientTarget:
+var (
+	ClientOpenedConnections  = stats.Int64("grpc.io/client/opened_connections", "Number of connections opened.", stats.UnitDimensionless)
+	ClientOpenConnections    = stats.Int64("grpc.io/client/open_connections", "Change in the number of open connections, 1 when a connection opens and -1 when it closes.", stats.UnitDimensionless)
+	ClientConnectionDuration = stats.Float64("grpc.io/client/connection_duration", "Time between the establishment of a connection and its end.", stats.UnitMilliseconds)
+)
+
+// ClientSpanLimitDrops is the measure of the events dropped from the spans of
+// ClientHandler by its SpanLimits, tagged with KeyClientMethod and
+// KeySpanLimit.
+var ClientSpanLimitDrops = stats.Int64("grpc.io/client/span_limit_drops", "Number of span events dropped because of the span limits.", stats.UnitDimensionless)
+
 // Predefined views may be registered to collect data for the above measures.
 // As always, you may also define your own custom views over measures collected by this
 // package. These are declared as a convenience only; none are registered by
@@ -52,6 +93,22 @@ var (
 		Aggregation: DefaultBytesDistribution,
 	}
 
+	ClientSentCompressedBytesPerRPCView = &view.View{
+		Measure:     ClientSentCompressedBytesPerRPC,
+		Name:        "grpc.io/client/sent_compressed_message_bytes_per_rpc",
+		Description: "Distribution of compressed bytes sent per RPC, by method.",
+		TagKeys:     []tag.Key{KeyClientMethod},
+		Aggregation: DefaultBytesDistribution,
+	}
+
+	ClientReceivedCompressedBytesPerRPCView = &view.View{
+		Measure:     ClientReceivedCompressedBytesPerRPC,
+		Name:        "grpc.io/client/received_compressed_message_bytes_per_rpc",
+		Description: "Distribution of compressed bytes received per RPC, by method.",
+		TagKeys:     []tag.Key{KeyClientMethod},
+		Aggregation: DefaultBytesDistribution,
+	}
+
 	ClientRoundtripLatencyView = &view.View{
 		Measure:     ClientRoundtripLatency,
 		Name:        "grpc.io/client/roundtrip_latency",
@@ -68,6 +125,30 @@ var (
 		Aggregation: view.Count(),
 	}
 
+	ClientEndReasonsView = &view.View{
+		Measure:     ClientRoundtripLatency,
+		Name:        "grpc.io/client/end_reasons",
+		Description: "Count of RPCs by method and end reason.",
+		TagKeys:     []tag.Key{KeyClientMethod, KeyClientEndReason},
+		Aggregation: view.Count(),
+	}
+
+	ClientRoundtripLatencyByTargetView = &view.View{
+		Measure:     ClientRoundtripLatency,
+		Name:        "grpc.io/client/roundtrip_latency_by_target",
+		Description: "Distribution of round-trip latency, by target and method.",
+		TagKeys:     []tag.Key{KeyClientDialTarget, KeyClientMethod},
+		Aggregation: DefaultMillisecondsDistribution,
+	}
+
+	ClientCompletedRPCsByTargetView = &view.View{
+		Measure:     ClientRoundtripLatency,
+		Name:        "grpc.io/client/completed_rpcs_by_target",
+		Description: "Count of RPCs by target, method and status.",
+		TagKeys:     []tag.Key{KeyClientDialTarget, KeyClientMethod, KeyClientStatus},
+		Aggregation: view.Count(),
+	}
+
 	ClientSentMessagesPerRPCView = &view.View{
 		Measure:     ClientSentMessagesPerRPC,
 		Name:        "grpc.io/client/sent_messages_per_rpc",
@@ -84,6 +165,94 @@ var (
 		Aggregation: DefaultMessageCountDistribution,
 	}
 
+	ClientSentHeaderBytesView = &view.View{
+		Measure:     ClientSentHeaderBytes,
+		Name:        "grpc.io/client/sent_header_bytes",
+		Description: "Distribution of sent header size, by method.",
+		TagKeys:     []tag.Key{KeyClientMethod},
+		Aggregation: DefaultBytesDistribution,
+	}
+
+	ClientReceivedHeaderBytesView = &view.View{
+		Measure:     ClientReceivedHeaderBytes,
+		Name:        "grpc.io/client/received_header_bytes",
+		Description: "Distribution of received header wire length, by method.",
+		TagKeys:     []tag.Key{KeyClientMethod},
+		Aggregation: DefaultBytesDistribution,
+	}
+
+	ClientReceivedTrailerBytesView = &view.View{
+		Measure:     ClientReceivedTrailerBytes,
+		Name:        "grpc.io/client/received_trailer_bytes",
+		Description: "Distribution of received trailer wire length, by method.",
+		TagKeys:     []tag.Key{KeyClientMethod},
+		Aggregation: DefaultBytesDistribution,
+	}
+
+	ClientStreamDurationView = &view.View{
+		Measure:     ClientStreamDuration,
+		Name:        "grpc.io/client/stream_duration",
+		Description: "Distribution of stream duration, by method.",
+		TagKeys:     []tag.Key{KeyClientMethod},
+		Aggregation: DefaultMillisecondsDistribution,
+	}
+
+	ClientStreamSentMessagesView = &view.View{
+		Measure:     ClientStreamSentMessages,
+		Name:        "grpc.io/client/stream_sent_messages",
+		Description: "Distribution of sent messages count per stream, by method.",
+		TagKeys:     []tag.Key{KeyClientMethod},
+		Aggregation: DefaultMessageCountDistribution,
+	}
+
+	ClientStreamReceivedMessagesView = &view.View{
+		Measure:     ClientStreamReceivedMessages,
+		Name:        "grpc.io/client/stream_received_messages",
+		Description: "Distribution of received messages count per stream, by method.",
+		TagKeys:     []tag.Key{KeyClientMethod},
+		Aggregation: DefaultMessageCountDistribution,
+	}
+
+	ClientRetriesView = &view.View{
+		Measure:     ClientRetries,
+		Name:        "grpc.io/client/retries",
+		Description: "Count of retry attempts, by method.",
+		TagKeys:     []tag.Key{KeyClientMethod},
+		Aggregation: view.Count(),
+	}
+
+	ClientActiveRPCsView = &view.View{
+		Measure:     ClientActiveRPCs,
+		Name:        "grpc.io/client/active_rpcs",
+		Description: "Number of active RPCs, by method.",
+		TagKeys:     []tag.Key{KeyClientMethod},
+		Aggregation: view.Sum(),
+	}
+
+	ClientOpenedConnectionsView = &view.View{
+		Measure:     ClientOpenedConnections,
+		Name:        "grpc.io/client/opened_connections",
+		Description: "Count of connections opened, by target.",
+		TagKeys:     []tag.Key{KeyClientTarget},
+		Aggregation: view.Count(),
+	}
+
+	ClientOpenConnectionsView = &view.View{
+		Measure:     ClientOpenConnections,
+		Name:        "grpc.io/client/open_connections",
+		Description: "Number of open connections, by target.",
+		TagKeys:     []tag.Key{KeyClientTarget},
+		Aggregation: view.Sum(),
+	}
+
+	ClientConnectionDurationView = &view.View{
+		Measure:     ClientConnectionDuration,
+		Name:        "grpc.io/client/connection_duration",
+		Description: "Distribution of connection duration, by target.",
+		TagKeys:     []tag.Key{KeyClientTarget},
+		Aggregation: DefaultMillisecondsDistribution,
+	}
+
 	ClientServerLatencyView = &view.View{
 		Measure:     ClientServerLatency,
 		Name:        "grpc.io/client/server_latency",
@@ -91,6 +260,14 @@ var (
 		TagKeys:     []tag.Key{KeyClientMethod},
 		Aggregation: DefaultMillisecondsDistribution,
 	}
+
+	ClientSpanLimitDropsView = &view.View{
+		Measure:     ClientSpanLimitDrops,
+		Name:        "grpc.io/client/span_limit_drops",
+		Description: "Sum of span events dropped by the span limits, by method and limit.",
+		TagKeys:     []tag.Key{KeyClientMethod, KeySpanLimit},
+		Aggregation: view.Sum(),
+	}
 )
 
 // DefaultClientViews are the default client views provided by this package.
@@ -99,6 +276,9 @@ var DefaultClientViews = []*view.View{
 	ClientReceivedBytesPerRPCView,
 	ClientRoundtripLatencyView,
 	ClientCompletedRPCsView,
+	ClientOpenedConnectionsView,
+	ClientOpenConnectionsView,
+	ClientConnectionDurationView,
 }
 
 // TODO(jbd): Add roundtrip_latency, uncompressed_request_bytes, uncompressed_response_bytes, request_count, response_count.
@@ -16,16 +16,18 @@
 package ocgrpc
 
 import (
+	"context"
 	"time"
 
+	ocstats "go.opencensus.io/stats"
 	"go.opencensus.io/tag"
-	"golang.org/x/net/context"
 	"google.golang.org/grpc/grpclog"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/stats"
 )
 
 // statsTagRPC gets the tag.Map populated by the application code, serializes
-// its tags into the GRPC metadata in order to be sent to the server.
+// its tags into the grpc-tags-bin metadata in order to be sent to the server.
 func (h *ClientHandler) statsTagRPC(ctx context.Context, info *stats.RPCTagInfo) context.Context {
 	startTime := time.Now()
 	if info == nil {
@@ -41,9 +43,66 @@ func (h *ClientHandler) statsTagRPC(ctx context.Context, info *stats.RPCTagInfo)
 	}
 	ts := tag.FromContext(ctx)
 	if ts != nil {
-		encoded := tag.Encode(ts)
-		ctx = stats.SetTags(ctx, encoded)
+		ctx = h.injectTags(ctx, tag.Encode(ts))
 	}
+	// The target is tagged after the tags are injected, as it only makes
+	// sense to the client.
+	ctx = targetTagRPC(ctx)
 
-	return context.WithValue(ctx, rpcDataKey, d)
+	return context.WithValue(ctx, rpcDataKey{}, d)
+}
+
+// injectTags sets the grpc-tags-bin metadata of ctx to encoded, unless it is
+// empty or larger than h.MaxTagsSize. The metadata is written directly, as
+// stats.SetTags is deprecated.
+func (h *ClientHandler) injectTags(ctx context.Context, encoded []byte) context.Context {
+	if len(encoded) == 0 {
+		return ctx
+	}
+	if len(encoded) > maxTagsSize(h.MaxTagsSize) {
+		if grpclog.V(2) {
+			grpclog.Warningf("opencensus: not propagating %d bytes of tags, above the limit of %d", len(encoded), maxTagsSize(h.MaxTagsSize))
+		}
+		return ctx
+	}
+	md, _ := metadata.FromOutgoingContext(ctx)
+	md.Set(tagsKey, string(encoded))
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+type connDataKey struct{}
+
+// connData holds the data of a client connection needed when it ends.
+type connData struct {
+	startTime time.Time
+	target    string
+}
+
+// statsTagConn remembers when the connection described by info was
+// established, and its target.
+func (h *ClientHandler) statsTagConn(ctx context.Context, info *stats.ConnTagInfo) context.Context {
+	d := &connData{startTime: time.Now()}
+	if info.RemoteAddr != nil {
+		d.target = info.RemoteAddr.String()
+	}
+	return context.WithValue(ctx, connDataKey{}, d)
+}
+
+// statsHandleConn records the measures of the connection of ctx.
+func statsHandleConn(ctx context.Context, s stats.ConnStats) {
+	d, ok := ctx.Value(connDataKey{}).(*connData)
+	if !ok {
+		return
+	}
+	mutators := []tag.Mutator{tag.Upsert(KeyClientTarget, d.target)}
+	switch s.(type) {
+	case *stats.ConnBegin:
+		ocstats.RecordWithTags(ctx, mutators,
+			ClientOpenedConnections.M(1),
+			ClientOpenConnections.M(1))
+	case *stats.ConnEnd:
+		ocstats.RecordWithTags(ctx, mutators,
+			ClientOpenConnections.M(-1),
+			ClientConnectionDuration.M(float64(time.Since(d.startTime))/float64(time.Millisecond)))
+	}
 }
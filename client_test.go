@@ -0,0 +1,91 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/akhenakh/ocgrpc_propagation/propagationtest"
+	"go.opencensus.io/trace"
+	"google.golang.org/grpc/stats"
+)
+
+// oddTraceIDSampler samples the traces whose ID ends with an odd byte, and
+// records the trace IDs it decided on.
+type oddTraceIDSampler struct {
+	seen []trace.TraceID
+}
+
+func (s *oddTraceIDSampler) sample(p trace.SamplingParameters) trace.SamplingDecision {
+	s.seen = append(s.seen, p.TraceID)
+	return trace.SamplingDecision{Sample: p.TraceID[15]%2 == 1}
+}
+
+func TestSkipUnsampledRootsDecidesOnSpanIDs(t *testing.T) {
+	propagationtest.DeterministicIDs(t)
+	sampler := &oddTraceIDSampler{}
+	c := &ClientHandler{
+		StartOptions:       trace.StartOptions{Sampler: sampler.sample},
+		SkipUnsampledRoots: true,
+	}
+
+	// The sequential trace IDs end with 1, then 2.
+	ctx := c.TagRPC(context.Background(), testRTI)
+	span := trace.FromContext(ctx)
+	if span == nil {
+		t.Fatal("the RPC with an odd trace ID isn't traced")
+	}
+	if len(sampler.seen) != 1 || sampler.seen[0] != span.SpanContext().TraceID {
+		t.Errorf("the sampler decided on %v, the span has trace ID %v", sampler.seen, span.SpanContext().TraceID)
+	}
+	if want := (trace.TraceID{7: 1, 15: 1}); span.SpanContext().TraceID != want {
+		t.Errorf("trace ID %v, want the deterministic %v", span.SpanContext().TraceID, want)
+	}
+	c.HandleRPC(ctx, &stats.End{EndTime: time.Now()})
+
+	ctx = c.TagRPC(context.Background(), testRTI)
+	if trace.FromContext(ctx) != nil {
+		t.Error("the RPC with an even trace ID is traced")
+	}
+	if len(sampler.seen) != 2 {
+		t.Errorf("the sampler was called %d times, want 2", len(sampler.seen))
+	}
+	c.HandleRPC(ctx, &stats.End{EndTime: time.Now()})
+}
+
+func BenchmarkClientTagRPC(b *testing.B) {
+	for _, bc := range []struct {
+		name    string
+		handler *ClientHandler
+	}{
+		{"sampled", &ClientHandler{StartOptions: trace.StartOptions{Sampler: trace.AlwaysSample()}}},
+		{"unsampled", &ClientHandler{StartOptions: trace.StartOptions{Sampler: trace.NeverSample()}}},
+		{"skip unsampled", &ClientHandler{
+			StartOptions:       trace.StartOptions{Sampler: trace.NeverSample()},
+			SkipUnsampledRoots: true,
+		}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			ctx := context.Background()
+			end := &stats.End{}
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				bc.handler.HandleRPC(bc.handler.TagRPC(ctx, testRTI), end)
+			}
+		})
+	}
+}
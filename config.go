@@ -0,0 +1,574 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"context"
+	"time"
+
+	"github.com/akhenakh/ocgrpc_propagation/baggage"
+	propag "github.com/akhenakh/ocgrpc_propagation/propagation"
+	"go.opencensus.io/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/stats"
+)
+
+// Config gathers the settings needed to build the handlers and interceptors
+// of this package in one place. It is meant to be filled from a configuration
+// file or a dependency injection container (see the ocgrpcfx and ocgrpcwire
+// packages) so every service ends up wired the same way.
+type Config struct {
+	// IsPublicEndpoint is copied to ServerHandler.IsPublicEndpoint.
+	IsPublicEndpoint bool
+
+	// IsPublicEndpointFunc is copied to ServerHandler.IsPublicEndpointFunc.
+	IsPublicEndpointFunc PublicEndpointFunc
+
+	// OnUntrustedParent is copied to ServerHandler.OnUntrustedParent.
+	OnUntrustedParent UntrustedParentPolicy
+
+	// UntrustedParentFunc is copied to ServerHandler.UntrustedParentFunc.
+	UntrustedParentFunc UntrustedParentFunc
+
+	// ServerStartOptions is copied to ServerHandler.StartOptions.
+	ServerStartOptions trace.StartOptions
+
+	// Jaeger is copied to ServerHandler.Jaeger and ClientHandler.Jaeger.
+	Jaeger propag.JaegerOptions
+
+	// HonorJaegerDebugID is copied to ServerHandler.HonorJaegerDebugID.
+	HonorJaegerDebugID bool
+
+	// ForceTraceKey is copied to ServerHandler.ForceTraceKey.
+	ForceTraceKey string
+
+	// SamplingPriorityKey is copied to ServerHandler.SamplingPriorityKey and
+	// ClientHandler.SamplingPriorityKey.
+	SamplingPriorityKey string
+
+	// RespectUpstreamSamplingDecision is copied to
+	// ServerHandler.RespectUpstreamSamplingDecision.
+	RespectUpstreamSamplingDecision bool
+
+	// DecodeBase64Binary is copied to ServerHandler.DecodeBase64Binary.
+	DecodeBase64Binary bool
+
+	// OnConflictingParents is copied to ServerHandler.OnConflictingParents.
+	OnConflictingParents ConflictingParentPolicy
+
+	// PreferredFormat is copied to ServerHandler.PreferredFormat.
+	PreferredFormat string
+
+	// OnExtractionFailure is copied to ServerHandler.OnExtractionFailure.
+	// The enforcing interceptors are installed when it is RejectRPC.
+	OnExtractionFailure ExtractionFailurePolicy
+
+	// LogInvalidSpanContexts is copied to
+	// ServerHandler.LogInvalidSpanContexts.
+	LogInvalidSpanContexts bool
+
+	// Signer is copied to ServerHandler.Signer and ClientHandler.Signer.
+	Signer Signer
+
+	// OnSignatureFailure is copied to ServerHandler.OnSignatureFailure.
+	OnSignatureFailure SignatureFailurePolicy
+
+	// SampledParentsPerPeer is copied to
+	// ServerHandler.SampledParentsPerPeer.
+	SampledParentsPerPeer int
+
+	// Principal is copied to ServerHandler.Principal.
+	Principal PrincipalFunc
+
+	// PrincipalTag is copied to ServerHandler.PrincipalTag.
+	PrincipalTag bool
+
+	// TLSAttributes is copied to ServerHandler.TLSAttributes.
+	TLSAttributes bool
+
+	// MetadataAttributes is copied to ServerHandler.MetadataAttributes.
+	MetadataAttributes []string
+
+	// MetadataAttributeMaxLength is copied to
+	// ServerHandler.MetadataAttributeMaxLength.
+	MetadataAttributeMaxLength int
+
+	// RedactMetadataAttribute is copied to
+	// ServerHandler.RedactMetadataAttribute.
+	RedactMetadataAttribute MetadataAttributeRedactor
+
+	// Tenants is copied to ServerHandler.Tenants.
+	Tenants *TenantHooks
+
+	// PeerAddress is copied to ServerHandler.PeerAddress.
+	PeerAddress PeerAddressPolicy
+
+	// PeerAddressHashKey is copied to ServerHandler.PeerAddressHashKey.
+	PeerAddressHashKey []byte
+
+	// ClientStartOptions is copied to ClientHandler.StartOptions.
+	ClientStartOptions trace.StartOptions
+
+	// InjectTraceContext is copied to ClientHandler.InjectTraceContext.
+	InjectTraceContext bool
+
+	// InjectB3 is copied to ClientHandler.InjectB3.
+	InjectB3 bool
+
+	// InjectB3Single is copied to ClientHandler.InjectB3Single.
+	InjectB3Single bool
+
+	// InjectJaeger is copied to ClientHandler.InjectJaeger.
+	InjectJaeger bool
+
+	// InjectXRay is copied to ClientHandler.InjectXRay.
+	InjectXRay bool
+
+	// InjectCloudTrace is copied to ClientHandler.InjectCloudTrace.
+	InjectCloudTrace bool
+
+	// InjectW3CBaggage is copied to ClientHandler.InjectW3CBaggage.
+	InjectW3CBaggage bool
+
+	// TraceID64 is copied to ClientHandler.TraceID64.
+	TraceID64 bool
+
+	// BaggageTags is copied to ServerHandler.BaggageTags and
+	// ClientHandler.BaggageTags.
+	BaggageTags BaggageTags
+
+	// Propagators is copied to ServerHandler.Propagators and
+	// ClientHandler.Propagators.
+	Propagators []propag.Propagator
+
+	// ClassifyContextErrors is copied to ServerHandler.ClassifyContextErrors
+	// and ClientHandler.ClassifyContextErrors.
+	ClassifyContextErrors bool
+
+	// ServerErrorCodes is copied to ServerHandler.ErrorCodes.
+	ServerErrorCodes map[codes.Code]bool
+
+	// ClientErrorCodes is copied to ClientHandler.ErrorCodes.
+	ClientErrorCodes map[codes.Code]bool
+
+	// DisableMessageEvents is copied to ServerHandler.DisableMessageEvents
+	// and ClientHandler.DisableMessageEvents.
+	DisableMessageEvents bool
+
+	// MessageEventLimit is copied to ServerHandler.MessageEventLimit and
+	// ClientHandler.MessageEventLimit.
+	MessageEventLimit MessageEventLimit
+
+	// MessageGapThreshold is copied to ServerHandler.MessageGapThreshold and
+	// ClientHandler.MessageGapThreshold.
+	MessageGapThreshold time.Duration
+
+	// SpanLimits is copied to ServerHandler.SpanLimits and
+	// ClientHandler.SpanLimits.
+	SpanLimits SpanLimits
+
+	// TransportAttributes is copied to ServerHandler.TransportAttributes and
+	// ClientHandler.TransportAttributes.
+	TransportAttributes bool
+
+	// PayloadAnnotations is copied to ServerHandler.PayloadAnnotations and
+	// ClientHandler.PayloadAnnotations.
+	PayloadAnnotations *PayloadAnnotations
+
+	// FormatStatus is copied to ServerHandler.FormatStatus and
+	// ClientHandler.FormatStatus.
+	FormatStatus StatusFormatter
+
+	// RedactStatusMessage is copied to ServerHandler.RedactStatusMessage and
+	// ClientHandler.RedactStatusMessage.
+	RedactStatusMessage func(code codes.Code, msg string) string
+
+	// StatusMessageMaxLength is copied to
+	// ServerHandler.StatusMessageMaxLength and
+	// ClientHandler.StatusMessageMaxLength.
+	StatusMessageMaxLength int
+
+	// MessageSpans is copied to ServerHandler.MessageSpans and
+	// ClientHandler.MessageSpans.
+	MessageSpans bool
+
+	// ConnectionSpans is copied to ServerHandler.ConnectionSpans and
+	// ClientHandler.ConnectionSpans.
+	ConnectionSpans bool
+
+	// SkipUnsampledRoots is copied to ClientHandler.SkipUnsampledRoots.
+	SkipUnsampledRoots bool
+
+	// DisableTracing is copied to ServerHandler.DisableTracing and
+	// ClientHandler.DisableTracing.
+	DisableTracing bool
+
+	// DisableStats is copied to ServerHandler.DisableStats and
+	// ClientHandler.DisableStats.
+	DisableStats bool
+
+	// MaxTagsSize is copied to ServerHandler.MaxTagsSize and
+	// ClientHandler.MaxTagsSize.
+	MaxTagsSize int
+
+	// CountAttempts may be set to true to also install the Attempts client
+	// interceptors, numbering the attempts of retried calls.
+	CountAttempts bool
+
+	// RecordTarget may be set to true to also install the Target client
+	// interceptors, recording the dialed target of each RPC.
+	RecordTarget bool
+
+	// DialSpans may be set to true to also install TracedDialer, sampled by
+	// DefaultSampler, tracing the TCP connection attempts of clients.
+	// Connections to Unix sockets aren't traced, nor is the time spent by
+	// gRPC resolvers.
+	DialSpans bool
+
+	// DefaultSampler is copied to ServerHandler.DefaultSampler and
+	// ClientHandler.DefaultSampler.
+	DefaultSampler trace.Sampler
+
+	// TracingFlags is copied to ServerHandler.TracingFlags and
+	// ClientHandler.TracingFlags.
+	TracingFlags FeatureFlags
+
+	// Decisions is copied to ServerHandler.Decisions.
+	Decisions *DecisionLog
+
+	// ServerMethodSamplers is copied to ServerHandler.MethodSamplers.
+	ServerMethodSamplers map[string]trace.Sampler
+
+	// ClientMethodSamplers is copied to ClientHandler.MethodSamplers.
+	ClientMethodSamplers map[string]trace.Sampler
+
+	// Filter is copied to ServerHandler.Filter and ClientHandler.Filter.
+	Filter FilterFunc
+
+	// FormatSpanName is copied to ServerHandler.FormatSpanName and
+	// ClientHandler.FormatSpanName.
+	FormatSpanName func(rti *stats.RPCTagInfo) string
+
+	// OnSpanStart is copied to ServerHandler.OnSpanStart and
+	// ClientHandler.OnSpanStart.
+	OnSpanStart SpanStartHook
+
+	// OnSamplingDecision is copied to ServerHandler.OnSamplingDecision.
+	OnSamplingDecision SamplingDecisionFunc
+
+	// Logger is copied to ServerHandler.Logger and ClientHandler.Logger.
+	Logger Logger
+
+	// PropagateJaeger is copied to ServerHandler.PropagateJaeger.
+	PropagateJaeger bool
+
+	// PropagateRequestID is copied to ServerHandler.PropagateRequestID.
+	PropagateRequestID bool
+
+	// AttributeTags is copied to ServerHandler.AttributeTags.
+	AttributeTags AttributeTags
+
+	// BaggageRestrictions may be set to also install the Jaeger baggage
+	// propagation interceptors, enforcing these restrictions. It is also
+	// copied to ClientHandler.BaggageRestrictions.
+	BaggageRestrictions baggage.RestrictionManager
+
+	// PublicEndpointBaggage may be set to restrict the baggage propagated
+	// by the RPCs served on public endpoints, according to
+	// IsPublicEndpointFunc if set, else to IsPublicEndpoint, instead of
+	// BaggageRestrictions, since the baggage sent by public callers can't be
+	// trusted. Use
+	// baggage.AllowlistRestrictionManager{} to drop all of it, or list the
+	// keys to keep. The incoming trace context is handled separately.
+	PublicEndpointBaggage baggage.RestrictionManager
+
+	// BaggageLimits may be set to also install the Jaeger baggage
+	// propagation interceptors, enforcing these limits. It is also copied
+	// to ClientHandler.BaggageLimits.
+	BaggageLimits baggage.Limits
+
+	// ForwardMetadata may be set to also install the metadata propagation
+	// interceptors, copying these incoming keys to the outgoing metadata,
+	// see MetadataPropagateUnaryInterceptor.
+	ForwardMetadata []string
+
+	// ForwardMetadataLimits are enforced on the metadata copied because of
+	// ForwardMetadata, see LimitedMetadataPropagateUnaryInterceptor.
+	ForwardMetadataLimits baggage.Limits
+
+	// BaggageRedactor may be set to redact the matching values of the
+	// metadata copied by the propagation interceptors, see
+	// RedactUnaryInterceptor.
+	BaggageRedactor *baggage.Redactor
+
+	// PropagationAllowlist may be set to only let the propagation
+	// interceptors copy these keys from incoming to outgoing metadata, see
+	// AllowlistUnaryInterceptor.
+	PropagationAllowlist propag.Allowlist
+
+	// TraceIDKey is copied to ServerHandler.TraceIDKey. The TraceID
+	// interceptors are installed when it is set.
+	TraceIDKey string
+
+	// LogFields may be set to true to also install the LogFields
+	// interceptors, setting the trace_id, span_id and sampled grpc_ctxtags
+	// tags of each RPC.
+	LogFields bool
+
+	// TraceIDInHeader is copied to ServerHandler.TraceIDInHeader.
+	TraceIDInHeader bool
+}
+
+// ServerHandler returns a ServerHandler configured from c.
+func (c Config) ServerHandler() *ServerHandler {
+	return &ServerHandler{
+		IsPublicEndpoint:                c.IsPublicEndpoint,
+		IsPublicEndpointFunc:            c.IsPublicEndpointFunc,
+		OnUntrustedParent:               c.OnUntrustedParent,
+		UntrustedParentFunc:             c.UntrustedParentFunc,
+		StartOptions:                    c.ServerStartOptions,
+		Jaeger:                          c.Jaeger,
+		PropagateJaeger:                 c.PropagateJaeger,
+		PropagateRequestID:              c.PropagateRequestID,
+		TraceIDKey:                      c.TraceIDKey,
+		TraceIDInHeader:                 c.TraceIDInHeader,
+		AttributeTags:                   c.AttributeTags,
+		HonorJaegerDebugID:              c.HonorJaegerDebugID,
+		ForceTraceKey:                   c.ForceTraceKey,
+		SamplingPriorityKey:             c.SamplingPriorityKey,
+		RespectUpstreamSamplingDecision: c.RespectUpstreamSamplingDecision,
+		Propagators:                     c.Propagators,
+		DecodeBase64Binary:              c.DecodeBase64Binary,
+		OnConflictingParents:            c.OnConflictingParents,
+		PreferredFormat:                 c.PreferredFormat,
+		OnExtractionFailure:             c.OnExtractionFailure,
+		LogInvalidSpanContexts:          c.LogInvalidSpanContexts,
+		Signer:                          c.Signer,
+		OnSignatureFailure:              c.OnSignatureFailure,
+		SampledParentsPerPeer:           c.SampledParentsPerPeer,
+		Principal:                       c.Principal,
+		PrincipalTag:                    c.PrincipalTag,
+		TLSAttributes:                   c.TLSAttributes,
+		MetadataAttributes:              c.MetadataAttributes,
+		MetadataAttributeMaxLength:      c.MetadataAttributeMaxLength,
+		RedactMetadataAttribute:         c.RedactMetadataAttribute,
+		Tenants:                         c.Tenants,
+		PeerAddress:                     c.PeerAddress,
+		PeerAddressHashKey:              c.PeerAddressHashKey,
+		ClassifyContextErrors:           c.ClassifyContextErrors,
+		DefaultSampler:                  c.DefaultSampler,
+		MethodSamplers:                  c.ServerMethodSamplers,
+		FormatSpanName:                  c.FormatSpanName,
+		OnSpanStart:                     c.OnSpanStart,
+		OnSamplingDecision:              c.OnSamplingDecision,
+		ErrorCodes:                      c.ServerErrorCodes,
+		DisableMessageEvents:            c.DisableMessageEvents,
+		MessageEventLimit:               c.MessageEventLimit,
+		MessageGapThreshold:             c.MessageGapThreshold,
+		SpanLimits:                      c.SpanLimits,
+		TransportAttributes:             c.TransportAttributes,
+		PayloadAnnotations:              c.PayloadAnnotations,
+		FormatStatus:                    c.FormatStatus,
+		RedactStatusMessage:             c.RedactStatusMessage,
+		StatusMessageMaxLength:          c.StatusMessageMaxLength,
+		MessageSpans:                    c.MessageSpans,
+		ConnectionSpans:                 c.ConnectionSpans,
+		DisableTracing:                  c.DisableTracing,
+		DisableStats:                    c.DisableStats,
+		MaxTagsSize:                     c.MaxTagsSize,
+		BaggageTags:                     c.BaggageTags,
+		Filter:                          c.Filter,
+		TracingFlags:                    c.TracingFlags,
+		Decisions:                       c.Decisions,
+		Logger:                          c.Logger,
+	}
+}
+
+// ClientHandler returns a ClientHandler configured from c.
+func (c Config) ClientHandler() *ClientHandler {
+	return &ClientHandler{
+		StartOptions:           c.ClientStartOptions,
+		InjectTraceContext:     c.InjectTraceContext,
+		InjectB3:               c.InjectB3,
+		InjectB3Single:         c.InjectB3Single,
+		InjectJaeger:           c.InjectJaeger,
+		InjectXRay:             c.InjectXRay,
+		InjectCloudTrace:       c.InjectCloudTrace,
+		SamplingPriorityKey:    c.SamplingPriorityKey,
+		InjectW3CBaggage:       c.InjectW3CBaggage,
+		BaggageRestrictions:    c.BaggageRestrictions,
+		BaggageLimits:          c.BaggageLimits,
+		TraceID64:              c.TraceID64,
+		BaggageTags:            c.BaggageTags,
+		Jaeger:                 c.Jaeger,
+		Propagators:            c.Propagators,
+		ClassifyContextErrors:  c.ClassifyContextErrors,
+		DefaultSampler:         c.DefaultSampler,
+		MethodSamplers:         c.ClientMethodSamplers,
+		FormatSpanName:         c.FormatSpanName,
+		OnSpanStart:            c.OnSpanStart,
+		ErrorCodes:             c.ClientErrorCodes,
+		DisableMessageEvents:   c.DisableMessageEvents,
+		MessageEventLimit:      c.MessageEventLimit,
+		MessageGapThreshold:    c.MessageGapThreshold,
+		SpanLimits:             c.SpanLimits,
+		TransportAttributes:    c.TransportAttributes,
+		PayloadAnnotations:     c.PayloadAnnotations,
+		FormatStatus:           c.FormatStatus,
+		RedactStatusMessage:    c.RedactStatusMessage,
+		StatusMessageMaxLength: c.StatusMessageMaxLength,
+		MessageSpans:           c.MessageSpans,
+		ConnectionSpans:        c.ConnectionSpans,
+		DisableTracing:         c.DisableTracing,
+		SkipUnsampledRoots:     c.SkipUnsampledRoots,
+		DisableStats:           c.DisableStats,
+		MaxTagsSize:            c.MaxTagsSize,
+		Signer:                 c.Signer,
+		Filter:                 c.Filter,
+		TracingFlags:           c.TracingFlags,
+		Logger:                 c.Logger,
+	}
+}
+
+// UnaryServerInterceptors returns the unary server interceptors required by c,
+// in the order they should be chained.
+func (c Config) UnaryServerInterceptors() []grpc.UnaryServerInterceptor {
+	var interceptors []grpc.UnaryServerInterceptor
+	if c.OnExtractionFailure == RejectRPC {
+		interceptors = append(interceptors, EnforceUnaryInterceptor())
+	}
+	if c.propagatesBaggage() {
+		interceptors = append(interceptors, baggagePropagateUnaryInterceptor(c.baggageRestrictions(), c.BaggageLimits))
+	}
+	if len(c.ForwardMetadata) > 0 {
+		interceptors = append(interceptors, LimitedMetadataPropagateUnaryInterceptor(c.ForwardMetadataLimits, c.ForwardMetadata...))
+	}
+	if c.BaggageRedactor != nil {
+		interceptors = append(interceptors, RedactUnaryInterceptor(c.BaggageRedactor))
+	}
+	if c.PropagationAllowlist != nil {
+		interceptors = append(interceptors, AllowlistUnaryInterceptor(c.PropagationAllowlist))
+	}
+	if c.LogFields {
+		interceptors = append(interceptors, LogFieldsUnaryInterceptor())
+	}
+	if c.TraceIDKey != "" {
+		interceptors = append(interceptors, TraceIDUnaryInterceptor())
+	}
+	return interceptors
+}
+
+// StreamServerInterceptors returns the stream server interceptors required by
+// c, in the order they should be chained.
+func (c Config) StreamServerInterceptors() []grpc.StreamServerInterceptor {
+	var interceptors []grpc.StreamServerInterceptor
+	if c.OnExtractionFailure == RejectRPC {
+		interceptors = append(interceptors, EnforceStreamInterceptor())
+	}
+	if c.propagatesBaggage() {
+		interceptors = append(interceptors, baggagePropagateStreamInterceptor(c.baggageRestrictions(), c.BaggageLimits))
+	}
+	if len(c.ForwardMetadata) > 0 {
+		interceptors = append(interceptors, LimitedMetadataPropagateStreamInterceptor(c.ForwardMetadataLimits, c.ForwardMetadata...))
+	}
+	if c.BaggageRedactor != nil {
+		interceptors = append(interceptors, RedactStreamInterceptor(c.BaggageRedactor))
+	}
+	if c.PropagationAllowlist != nil {
+		interceptors = append(interceptors, AllowlistStreamInterceptor(c.PropagationAllowlist))
+	}
+	if c.LogFields {
+		interceptors = append(interceptors, LogFieldsStreamInterceptor())
+	}
+	if c.TraceIDKey != "" {
+		interceptors = append(interceptors, TraceIDStreamInterceptor())
+	}
+	return interceptors
+}
+
+func (c Config) propagatesBaggage() bool {
+	return c.BaggageRestrictions != nil || c.restrictsPublicBaggage() || c.BaggageLimits != baggage.Limits{}
+}
+
+// restrictsPublicBaggage reports whether some RPCs may be served on public
+// endpoints, with the PublicEndpointBaggage restrictions.
+func (c Config) restrictsPublicBaggage() bool {
+	return c.PublicEndpointBaggage != nil && (c.IsPublicEndpoint || c.IsPublicEndpointFunc != nil)
+}
+
+// baggageRestrictions returns the restrictions of the baggage propagated by
+// each RPC: PublicEndpointBaggage for the RPCs the ServerHandler built from c
+// serves on public endpoints, else BaggageRestrictions.
+func (c Config) baggageRestrictions() restrictionsFunc {
+	if !c.restrictsPublicBaggage() {
+		return fixedRestrictions(c.BaggageRestrictions)
+	}
+	h := &ServerHandler{IsPublicEndpoint: c.IsPublicEndpoint, IsPublicEndpointFunc: c.IsPublicEndpointFunc}
+	return func(ctx context.Context, method string) baggage.RestrictionManager {
+		if h.isPublicEndpoint(ctx, &stats.RPCTagInfo{FullMethodName: method}) {
+			return c.PublicEndpointBaggage
+		}
+		return c.BaggageRestrictions
+	}
+}
+
+// ServerOptions returns the grpc.ServerOption installing a ServerHandler and
+// the server interceptor chains built from c.
+func (c Config) ServerOptions() []grpc.ServerOption {
+	return c.ServerOptionsFor(c.ServerHandler())
+}
+
+// ServerOptionsFor returns the grpc.ServerOption installing h, typically built
+// with c.ServerHandler, and the server interceptor chains built from c. The
+// interceptors are chained after the ones already installed on the server.
+func (c Config) ServerOptionsFor(h *ServerHandler) []grpc.ServerOption {
+	opts := []grpc.ServerOption{grpc.StatsHandler(h)}
+	if unary := c.UnaryServerInterceptors(); len(unary) > 0 {
+		opts = append(opts, grpc.ChainUnaryInterceptor(unary...))
+	}
+	if stream := c.StreamServerInterceptors(); len(stream) > 0 {
+		opts = append(opts, grpc.ChainStreamInterceptor(stream...))
+	}
+	return opts
+}
+
+// DialOptions returns the grpc.DialOption installing a ClientHandler built
+// from c, the Attempts interceptors if c.CountAttempts is set, the Target
+// interceptors if c.RecordTarget is set, and TracedDialer if c.DialSpans is
+// set.
+func (c Config) DialOptions() []grpc.DialOption {
+	return c.DialOptionsFor(c.ClientHandler())
+}
+
+// DialOptionsFor is DialOptions installing h, typically built with
+// c.ClientHandler, instead of a new ClientHandler.
+func (c Config) DialOptionsFor(h *ClientHandler) []grpc.DialOption {
+	opts := []grpc.DialOption{grpc.WithStatsHandler(h)}
+	if c.CountAttempts {
+		opts = append(opts,
+			grpc.WithChainUnaryInterceptor(AttemptsUnaryClientInterceptor()),
+			grpc.WithChainStreamInterceptor(AttemptsStreamClientInterceptor()))
+	}
+	if c.RecordTarget {
+		opts = append(opts,
+			grpc.WithChainUnaryInterceptor(TargetUnaryClientInterceptor()),
+			grpc.WithChainStreamInterceptor(TargetStreamClientInterceptor()))
+	}
+	if c.DialSpans {
+		opts = append(opts, grpc.WithContextDialer(tcpTracedDialer(c.DefaultSampler)))
+	}
+	return opts
+}
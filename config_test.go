@@ -0,0 +1,130 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"go.opencensus.io/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestJaegerPropagationOptIn(t *testing.T) {
+	if (Config{}).ServerHandler().PropagateJaeger {
+		t.Error("the zero Config propagates uber-trace-id")
+	}
+	if NewServerHandler().PropagateJaeger {
+		t.Error("NewServerHandler() propagates uber-trace-id")
+	}
+	if !(Config{PropagateJaeger: true}).ServerHandler().PropagateJaeger {
+		t.Error("Config.PropagateJaeger isn't copied to the ServerHandler")
+	}
+}
+
+func TestServerOptionsChainInterceptors(t *testing.T) {
+	var called bool
+	own := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		called = true
+		return handler(ctx, req)
+	}
+	lis := bufconn.Listen(1 << 16)
+	opts := ServerOption(WithConfig(Config{DefaultSampler: trace.AlwaysSample(), TraceIDKey: DefaultTraceIDKey}))
+	srv := grpc.NewServer(append(opts, grpc.UnaryInterceptor(own))...)
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	conn, err := grpc.Dial("bufnet", append(DialOption(),
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithInsecure())...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	var trailer metadata.MD
+	if _, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{}, grpc.Trailer(&trailer)); err != nil {
+		t.Fatal(err)
+	}
+	if len(trailer[DefaultTraceIDKey]) != 1 {
+		t.Errorf("trailer %v, want the trace ID of the Config interceptors", trailer)
+	}
+	if !called {
+		t.Error("the interceptor of the service wasn't called")
+	}
+}
+
+func TestOptions(t *testing.T) {
+	own := func(fullMethod string) bool { return fullMethod == "/svc/Ignored" }
+	h := NewServerHandler(
+		WithConfig(Config{IsPublicEndpoint: true}),
+		WithSampler(trace.NeverSample()),
+		WithFilter(own),
+		WithoutHealthAndReflection(),
+	)
+	if !h.IsPublicEndpoint {
+		t.Error("WithConfig wasn't applied")
+	}
+	if h.StartOptions.Sampler == nil {
+		t.Error("WithSampler wasn't applied")
+	}
+	for _, m := range []string{"/svc/Ignored", "/grpc.health.v1.Health/Check"} {
+		if !filtered(h.Filter, m) {
+			t.Errorf("%s isn't filtered", m)
+		}
+	}
+	if filtered(h.Filter, "/svc/Method") {
+		t.Error("/svc/Method is filtered")
+	}
+	if c := NewClientHandler(WithSampler(trace.NeverSample())); c.StartOptions.Sampler == nil {
+		t.Error("WithSampler wasn't applied to the ClientHandler")
+	}
+}
+
+func TestDisableOptions(t *testing.T) {
+	h := NewServerHandler(WithTracingDisabled())
+	if !h.DisableTracing || h.DisableStats {
+		t.Errorf("WithTracingDisabled(): DisableTracing %v, DisableStats %v", h.DisableTracing, h.DisableStats)
+	}
+	c := NewClientHandler(WithStatsDisabled())
+	if c.DisableTracing || !c.DisableStats {
+		t.Errorf("WithStatsDisabled(): DisableTracing %v, DisableStats %v", c.DisableTracing, c.DisableStats)
+	}
+}
+
+func TestWithMessageEvents(t *testing.T) {
+	if !NewServerHandler(WithMessageEvents(false)).DisableMessageEvents {
+		t.Error("WithMessageEvents(false) keeps the message events")
+	}
+	if NewClientHandler(WithMessageEvents(true)).DisableMessageEvents {
+		t.Error("WithMessageEvents(true) disables the message events")
+	}
+}
+
+func TestWithDefaultSampler(t *testing.T) {
+	never := trace.NeverSample()
+	h := NewServerHandler(WithDefaultSampler(never))
+	if h.DefaultSampler == nil || h.sampler("/svc/Method") == nil {
+		t.Error("WithDefaultSampler wasn't applied to the ServerHandler")
+	}
+	if c := NewClientHandler(WithDefaultSampler(never)); c.sampler("/svc/Method") == nil {
+		t.Error("WithDefaultSampler wasn't applied to the ClientHandler")
+	}
+}
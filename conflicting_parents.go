@@ -0,0 +1,102 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	propag "github.com/akhenakh/ocgrpc_propagation/propagation"
+	"go.opencensus.io/trace"
+)
+
+// ConflictingParentPolicy controls which incoming trace context the
+// ServerHandler continues when an RPC carries several formats with different
+// trace IDs, such as grpc-trace-bin and uber-trace-id written by different
+// tracers along the way.
+type ConflictingParentPolicy int
+
+const (
+	// PreferFirstFormat continues the trace context of the first format of
+	// the Propagators carrying one, or of ServerHandler.PreferredFormat.
+	// The others are ignored.
+	PreferFirstFormat ConflictingParentPolicy = iota
+
+	// PreferSampledParent continues the first sampled trace context, or the
+	// first one if none is sampled, so a trace sampled upstream isn't lost
+	// because another tracer didn't sample it.
+	PreferSampledParent
+
+	// LinkConflictingParents continues the first trace context, as
+	// PreferFirstFormat does, and links the span to the trace contexts of
+	// the other traces.
+	LinkConflictingParents
+)
+
+// candidateParent is a valid incoming trace context and its format.
+type candidateParent struct {
+	sc     trace.SpanContext
+	format string
+}
+
+// extractionOrder returns the propagators tried by s, with the ones of
+// s.PreferredFormat first.
+func (s *ServerHandler) extractionOrder() []propag.Propagator {
+	ps := extractors(s.propagators())
+	if s.PreferredFormat == "" {
+		return ps
+	}
+	ordered := make([]propag.Propagator, 0, len(ps))
+	for _, p := range ps {
+		if propag.NameOf(p) == s.PreferredFormat {
+			ordered = append(ordered, p)
+		}
+	}
+	for _, p := range ps {
+		if propag.NameOf(p) != s.PreferredFormat {
+			ordered = append(ordered, p)
+		}
+	}
+	return ordered
+}
+
+// chooseParent returns the candidate continued according to
+// s.OnConflictingParents, and the trace contexts of the candidates of other
+// traces.
+func (s *ServerHandler) chooseParent(candidates []candidateParent) (parent candidateParent, conflicting []trace.SpanContext) {
+	parent = candidates[0]
+	if s.OnConflictingParents == PreferSampledParent && !parent.sc.IsSampled() {
+		for _, c := range candidates[1:] {
+			if c.sc.IsSampled() {
+				parent = c
+				break
+			}
+		}
+	}
+	for _, c := range candidates {
+		if c.sc.TraceID != parent.sc.TraceID {
+			conflicting = append(conflicting, c.sc)
+		}
+	}
+	return parent, conflicting
+}
+
+// linkConflictingParents links span to the trace contexts of conflicting
+// when s.OnConflictingParents is LinkConflictingParents.
+func (s *ServerHandler) linkConflictingParents(span *trace.Span, conflicting []trace.SpanContext) {
+	if s.OnConflictingParents != LinkConflictingParents {
+		return
+	}
+	for _, sc := range conflicting {
+		span.AddLink(trace.Link{TraceID: sc.TraceID, SpanID: sc.SpanID, Type: trace.LinkTypeChild})
+	}
+}
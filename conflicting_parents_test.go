@@ -0,0 +1,40 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"testing"
+
+	propag "github.com/akhenakh/ocgrpc_propagation/propagation"
+)
+
+func TestExtractionOrderB3Formats(t *testing.T) {
+	for _, tc := range []struct {
+		preferred string
+		want      propag.Propagator
+	}{
+		{"b3", propag.B3Propagator{}},
+		{"b3single", propag.B3SinglePropagator{}},
+	} {
+		s := &ServerHandler{PreferredFormat: tc.preferred}
+		order := s.extractionOrder()
+		if got := order[0]; got != tc.want {
+			t.Errorf("PreferredFormat %q: first propagator = %#v; want %#v", tc.preferred, got, tc.want)
+		}
+		if n := len(order); n != len(extractors(s.propagators())) {
+			t.Errorf("PreferredFormat %q: %d propagators; want all of them", tc.preferred, n)
+		}
+	}
+}
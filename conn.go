@@ -0,0 +1,72 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"context"
+	"sync"
+
+	"go.opencensus.io/trace"
+	"google.golang.org/grpc/codes"
+)
+
+type connSpansKey struct{}
+
+// connSpans tracks the spans of the RPCs of a server connection that haven't
+// ended yet, so they can be ended if the connection dies before gRPC reports
+// the end of the RPCs.
+type connSpans struct {
+	mu    sync.Mutex
+	spans map[*trace.Span]struct{}
+}
+
+func newConnSpansContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, connSpansKey{}, &connSpans{spans: make(map[*trace.Span]struct{})})
+}
+
+func connSpansFromContext(ctx context.Context) *connSpans {
+	c, _ := ctx.Value(connSpansKey{}).(*connSpans)
+	return c
+}
+
+func (c *connSpans) add(span *trace.Span) {
+	c.mu.Lock()
+	c.spans[span] = struct{}{}
+	c.mu.Unlock()
+}
+
+// remove stops tracking span. It returns false if span was not tracked
+// anymore, because it was already ended by endAll.
+func (c *connSpans) remove(span *trace.Span) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.spans[span]; !ok {
+		return false
+	}
+	delete(c.spans, span)
+	return true
+}
+
+// endAll ends the spans still tracked with a "connection aborted" status.
+func (c *connSpans) endAll() {
+	c.mu.Lock()
+	spans := c.spans
+	c.spans = make(map[*trace.Span]struct{})
+	c.mu.Unlock()
+	for span := range spans {
+		span.SetStatus(trace.Status{Code: int32(codes.Unavailable), Message: "connection aborted"})
+		span.End()
+	}
+}
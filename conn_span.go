@@ -0,0 +1,54 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"context"
+
+	"go.opencensus.io/trace"
+	"google.golang.org/grpc/stats"
+)
+
+// connSpanName is the name of the spans of connections created when the
+// ConnectionSpans of a handler is set.
+const connSpanName = "grpc.connection"
+
+type connSpanKey struct{}
+
+// startConnSpan starts the span of a connection, with attrs, ended by
+// handleConnSpan. The span is kept under its own context key rather than with
+// trace.NewContext, since the contexts of server RPCs derive from the context
+// of their connection and must not become its children.
+func startConnSpan(ctx context.Context, sampler trace.Sampler, attrs []trace.Attribute) context.Context {
+	_, span := trace.StartSpan(context.Background(), connSpanName, trace.WithSampler(sampler))
+	span.AddAttributes(attrs...)
+	return context.WithValue(ctx, connSpanKey{}, span)
+}
+
+// handleConnSpan annotates the span of the connection of ctx when it begins,
+// and ends it when the connection ends.
+func handleConnSpan(ctx context.Context, cs stats.ConnStats) {
+	span, ok := ctx.Value(connSpanKey{}).(*trace.Span)
+	if !ok {
+		return
+	}
+	switch cs.(type) {
+	case *stats.ConnBegin:
+		span.Annotate(nil, "Connection begin")
+	case *stats.ConnEnd:
+		span.Annotate(nil, "Connection end")
+		span.End()
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/akhenakh/ocgrpc_propagation/propagationtest"
+	"go.opencensus.io/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// forwardingHealthServer answers health checks by forwarding them to next,
+// with the context of the server RPC.
+type forwardingHealthServer struct {
+	healthpb.UnimplementedHealthServer
+	next healthpb.HealthClient
+}
+
+func (s forwardingHealthServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	return s.next.Check(ctx, req)
+}
+
+// serveHealth serves hs over an in-memory listener and returns a client
+// connection to it, both instrumented with c.
+func serveHealth(t *testing.T, c Config, hs healthpb.HealthServer) *grpc.ClientConn {
+	t.Helper()
+	lis := bufconn.Listen(1 << 16)
+	srv := grpc.NewServer(c.ServerOptions()...)
+	healthpb.RegisterHealthServer(srv, hs)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.Dial("bufnet", append(c.DialOptions(),
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithInsecure())...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestNestedClientSpanEnded(t *testing.T) {
+	e := propagationtest.Install(t)
+	c := Config{DefaultSampler: trace.AlwaysSample()}
+	downstream := serveHealth(t, c, health.NewServer())
+	front := serveHealth(t, c, forwardingHealthServer{next: healthpb.NewHealthClient(downstream)})
+
+	if _, err := healthpb.NewHealthClient(front).Check(context.Background(), &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	// The servers end their spans after sending their response.
+	for deadline := time.Now().Add(5 * time.Second); len(e.Spans()) < 4 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+
+	kinds := map[int]int{}
+	for _, s := range e.Spans() {
+		kinds[s.SpanKind]++
+	}
+	if kinds[trace.SpanKindClient] != 2 || kinds[trace.SpanKindServer] != 2 {
+		t.Errorf("exported %d client and %d server spans, want 2 of each", kinds[trace.SpanKindClient], kinds[trace.SpanKindServer])
+	}
+}
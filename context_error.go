@@ -0,0 +1,100 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"context"
+	"time"
+
+	"go.opencensus.io/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/stats"
+)
+
+// Values of the grpc.context_error attribute.
+const (
+	contextErrorCanceled           = "canceled"
+	contextErrorLocalDeadline      = "local_deadline_exceeded"
+	contextErrorPropagatedDeadline = "propagated_deadline_exceeded"
+	contextErrorAttribute          = "grpc.context_error"
+)
+
+// deadlineRemainingAttribute is the server span attribute recording the time
+// left before the deadline propagated by the caller when the RPC starts.
+const deadlineRemainingAttribute = "grpc.deadline_ms_remaining"
+
+// addDeadlineAttribute records the time left before the deadline of ctx on
+// span, if ctx has a deadline.
+func addDeadlineAttribute(ctx context.Context, span *trace.Span) {
+	if d, ok := ctx.Deadline(); ok {
+		span.AddAttributes(trace.Int64Attribute(deadlineRemainingAttribute, time.Until(d).Milliseconds()))
+	}
+}
+
+// annotateDeadlineExceeded annotates span when the RPC ending with rs, whose
+// status is st, exceeded its deadline, with how late it ended if ctx has a
+// deadline.
+func annotateDeadlineExceeded(ctx context.Context, span *trace.Span, rs *stats.End, st trace.Status) {
+	if codes.Code(st.Code) != codes.DeadlineExceeded {
+		return
+	}
+	var attrs []trace.Attribute
+	if deadline, ok := ctx.Deadline(); ok && !rs.EndTime.IsZero() {
+		attrs = append(attrs, trace.Int64Attribute("overrun_ms", rs.EndTime.Sub(deadline).Milliseconds()))
+	}
+	span.Annotate(attrs, "Deadline exceeded")
+}
+
+type incomingDeadlineKey struct{}
+
+// withIncomingDeadline remembers the deadline of an inbound RPC, so client
+// RPCs made with a context derived from it can tell a propagated deadline
+// from a local one.
+func withIncomingDeadline(ctx context.Context) context.Context {
+	if d, ok := ctx.Deadline(); ok {
+		return context.WithValue(ctx, incomingDeadlineKey{}, d)
+	}
+	return ctx
+}
+
+// classifyContextError returns the status of the RPC ending with rs, whose
+// status is st, set to Canceled or DeadlineExceeded depending on whether the
+// deadline of ctx had expired, when the RPC ended with either code. The cause
+// is recorded as an attribute of span.
+func classifyContextError(ctx context.Context, span *trace.Span, rs *stats.End, st trace.Status) trace.Status {
+	if c := codes.Code(st.Code); c != codes.Canceled && c != codes.DeadlineExceeded {
+		return st
+	}
+
+	deadline, ok := ctx.Deadline()
+	endTime := rs.EndTime
+	if endTime.IsZero() {
+		endTime = time.Now()
+	}
+	if !ok || endTime.Before(deadline) {
+		span.AddAttributes(trace.StringAttribute(contextErrorAttribute, contextErrorCanceled))
+		return trace.Status{Code: int32(codes.Canceled), Message: st.Message}
+	}
+
+	cause := contextErrorLocalDeadline
+	if !rs.Client {
+		// The deadline of a server RPC is always set by the caller.
+		cause = contextErrorPropagatedDeadline
+	} else if incoming, ok := ctx.Value(incomingDeadlineKey{}).(time.Time); ok && incoming.Equal(deadline) {
+		cause = contextErrorPropagatedDeadline
+	}
+	span.AddAttributes(trace.StringAttribute(contextErrorAttribute, cause))
+	return trace.Status{Code: int32(codes.DeadlineExceeded), Message: st.Message}
+}
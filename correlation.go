@@ -0,0 +1,108 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"context"
+
+	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	grpc_ctxtags "github.com/grpc-ecosystem/go-grpc-middleware/tags"
+	"go.opencensus.io/trace"
+	"google.golang.org/grpc"
+)
+
+// Log fields set by the LogFields interceptors.
+const (
+	TraceIDLogField = "trace_id"
+	SpanIDLogField  = "span_id"
+	SampledLogField = "sampled"
+
+	// RequestIDLogField is set when ServerHandler.PropagateRequestID is.
+	RequestIDLogField = "request_id"
+)
+
+// TraceIDFromContext returns the hexadecimal trace ID of the current span of
+// ctx, such as the span started by a handler of this package, for log
+// correlation. It is empty when ctx has no span or its span isn't sampled,
+// since the trace won't be found in the tracing backend.
+func TraceIDFromContext(ctx context.Context) string {
+	sc, ok := sampledSpanContext(ctx)
+	if !ok {
+		return ""
+	}
+	return sc.TraceID.String()
+}
+
+// SpanIDFromContext returns the hexadecimal span ID of the current span of
+// ctx, see TraceIDFromContext.
+func SpanIDFromContext(ctx context.Context) string {
+	sc, ok := sampledSpanContext(ctx)
+	if !ok {
+		return ""
+	}
+	return sc.SpanID.String()
+}
+
+func sampledSpanContext(ctx context.Context) (trace.SpanContext, bool) {
+	span := trace.FromContext(ctx)
+	if span == nil {
+		return trace.SpanContext{}, false
+	}
+	sc := span.SpanContext()
+	return sc, sc.IsSampled()
+}
+
+// LogFieldsUnaryInterceptor sets the trace_id, span_id and sampled
+// grpc_ctxtags tags of the span started by the ServerHandler, and its
+// request_id tag if any, so the zap and logrus logging interceptors of
+// go-grpc-middleware, chained after it, add them to every log line and logs
+// and traces are cross-linked. Tags are created in the context if
+// grpc_ctxtags isn't installed.
+func LogFieldsUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(setLogFields(ctx), req)
+	}
+}
+
+// LogFieldsStreamInterceptor sets the trace_id, span_id and sampled
+// grpc_ctxtags tags of the span started by the ServerHandler, see
+// LogFieldsUnaryInterceptor.
+func LogFieldsStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		wrapped := grpc_middleware.WrapServerStream(stream)
+		wrapped.WrappedContext = setLogFields(stream.Context())
+		return handler(srv, wrapped)
+	}
+}
+
+func setLogFields(ctx context.Context) context.Context {
+	span := trace.FromContext(ctx)
+	if span == nil {
+		return ctx
+	}
+	tags := grpc_ctxtags.Extract(ctx)
+	if tags == grpc_ctxtags.NoopTags {
+		tags = grpc_ctxtags.NewTags()
+		ctx = grpc_ctxtags.SetInContext(ctx, tags)
+	}
+	sc := span.SpanContext()
+	tags.Set(TraceIDLogField, sc.TraceID.String()).
+		Set(SpanIDLogField, sc.SpanID.String()).
+		Set(SampledLogField, sc.IsSampled())
+	if id := RequestIDFromContext(ctx); id != "" {
+		tags.Set(RequestIDLogField, id)
+	}
+	return ctx
+}
@@ -0,0 +1,66 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"context"
+	"strings"
+
+	propag "github.com/akhenakh/ocgrpc_propagation/propagation"
+	"go.opencensus.io/trace"
+	"google.golang.org/grpc/metadata"
+)
+
+// jaegerDebugIDAttribute is the server span attribute recording the
+// jaeger-debug-id of the RPC, under which the Jaeger UI finds the trace.
+const jaegerDebugIDAttribute = "jaeger-debug-id"
+
+// jaegerDebugFlagAttribute is the server span attribute set when the Jaeger
+// debug flag of the remote parent forced the sampling of the span.
+const jaegerDebugFlagAttribute = "jaeger.debug"
+
+// JaegerFlagsFromContext returns the Jaeger flags of the current span of ctx,
+// such as the flags received by the ServerHandler, carried over to the spans
+// of the trace, so policy code can react to the debug or firehose flags.
+func JaegerFlagsFromContext(ctx context.Context) propag.JaegerFlags {
+	return propag.JaegerFlagsOf(trace.FromContext(ctx).SpanContext())
+}
+
+// jaegerDebugFlag reports whether parent has the Jaeger debug flag and s
+// honors it.
+func (s *ServerHandler) jaegerDebugFlag(parent trace.SpanContext) bool {
+	return s.HonorJaegerDebugID && propag.JaegerFlagsOf(parent)&propag.JaegerDebugFlag != 0
+}
+
+// jaegerDebugID returns the jaeger-debug-id of md when s honors it, or an
+// empty string.
+func (s *ServerHandler) jaegerDebugID(md metadata.MD) string {
+	if !s.HonorJaegerDebugID {
+		return ""
+	}
+	if vs := propag.Lookup(md, propag.JaegerDebugIDKey); len(vs) > 0 {
+		return strings.TrimSpace(vs[0])
+	}
+	return ""
+}
+
+// debugSampler returns the sampler forcing the sampling of the span of an RPC
+// with the jaeger-debug-id debugID, or sampler if debugID is empty.
+func debugSampler(debugID string, sampler trace.Sampler) trace.Sampler {
+	if debugID == "" {
+		return sampler
+	}
+	return trace.AlwaysSample()
+}
@@ -0,0 +1,105 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.opencensus.io/trace"
+)
+
+// Decision is the propagation decision taken by a ServerHandler for an RPC.
+type Decision struct {
+	Time   time.Time `json:"time"`
+	Method string    `json:"method"`
+
+	// Format is the format the remote parent was read from, empty if none
+	// could be.
+	Format string `json:"format,omitempty"`
+
+	// Errors tell why the trace contexts present couldn't be used.
+	Errors []string `json:"errors,omitempty"`
+
+	// Linked is true when the remote parent was only linked to the span.
+	Linked bool `json:"linked,omitempty"`
+
+	TraceID string `json:"trace_id"`
+	SpanID  string `json:"span_id"`
+	Sampled bool   `json:"sampled"`
+}
+
+// DecisionLog keeps the last propagation decisions of a ServerHandler in a
+// ring buffer, see ServerHandler.Decisions. It is an http.Handler serving
+// them as JSON, oldest first, meant to be installed on a debug server.
+type DecisionLog struct {
+	mu        sync.Mutex
+	decisions []Decision
+	next      int
+	full      bool
+}
+
+// NewDecisionLog returns a DecisionLog keeping the last n decisions.
+func NewDecisionLog(n int) *DecisionLog {
+	if n < 1 {
+		n = 1
+	}
+	return &DecisionLog{decisions: make([]Decision, n)}
+}
+
+func (l *DecisionLog) add(method, format string, errs []error, linked bool, sc trace.SpanContext) {
+	d := Decision{
+		Time:    time.Now(),
+		Method:  method,
+		Format:  format,
+		Linked:  linked,
+		TraceID: sc.TraceID.String(),
+		SpanID:  sc.SpanID.String(),
+		Sampled: sc.IsSampled(),
+	}
+	for _, err := range errs {
+		d.Errors = append(d.Errors, err.Error())
+	}
+
+	l.mu.Lock()
+	l.decisions[l.next] = d
+	l.next = (l.next + 1) % len(l.decisions)
+	if l.next == 0 {
+		l.full = true
+	}
+	l.mu.Unlock()
+}
+
+// Decisions returns the decisions kept, oldest first.
+func (l *DecisionLog) Decisions() []Decision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.full {
+		return append([]Decision(nil), l.decisions[:l.next]...)
+	}
+	return append(append([]Decision(nil), l.decisions[l.next:]...), l.decisions[:l.next]...)
+}
+
+// ServeHTTP serves the decisions kept as a JSON array, oldest first.
+func (l *DecisionLog) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(l.Decisions()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
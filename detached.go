@@ -0,0 +1,43 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"context"
+
+	"github.com/akhenakh/ocgrpc_propagation/baggage"
+	"go.opencensus.io/trace"
+)
+
+// StartDetachedSpan starts a span for work outliving the RPC of ctx, such as
+// a goroutine started by a handler: a child span would have a parent ending
+// before it, and the context of the RPC is canceled when it returns.
+//
+// The span is the root of a new trace, linked to the span of ctx. The
+// returned context isn't derived from ctx, so it isn't canceled with the RPC,
+// but carries its baggage forward. o configures the span, for example with
+// trace.WithSampler.
+func StartDetachedSpan(ctx context.Context, name string, o ...trace.StartOption) (context.Context, *trace.Span) {
+	detached := context.Background()
+	for _, item := range baggage.Items(ctx) {
+		detached = baggage.Set(detached, item.Key, item.Value)
+	}
+	detached, span := trace.StartSpan(detached, name, o...)
+	if parent := trace.FromContext(ctx); parent != nil {
+		sc := parent.SpanContext()
+		span.AddLink(trace.Link{TraceID: sc.TraceID, SpanID: sc.SpanID, Type: trace.LinkTypeChild})
+	}
+	return detached, span
+}
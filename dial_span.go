@@ -0,0 +1,134 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	"go.opencensus.io/trace"
+)
+
+// dialSpanName is the name of the spans of the connection attempts traced by
+// TracedDialer.
+const dialSpanName = "grpc.dial"
+
+// peerNameAttribute records the host name dialed by TracedDialer.
+const peerNameAttribute = "net.peer.name"
+
+// transportWaitAttribute records, on client spans, the time between the
+// start of an RPC and the sending of its header, mostly spent waiting for
+// name resolution and a connection: a slow first call of a cold client shows
+// a long wait.
+const transportWaitAttribute = "grpc.transport_wait_ms"
+
+// TracedDialer returns a dialer for grpc.WithContextDialer tracing each
+// connection attempt in a grpc.dial span sampled by sampler, nil meaning the
+// global default sampler, so cold starts and reconnect storms show in traces
+// rather than as slow first calls. Connections are established by dial; if
+// nil, TCP connections are established to the addresses the host name of
+// the target resolves to, and the name resolution is annotated on the span.
+//
+// The time gRPC resolvers spend before dialing isn't traced: the dialer only
+// sees resolved addresses, except for the passthrough resolver, which hands
+// it the host name of the target. Connections to Unix sockets must not use
+// it when dial is nil, see Config.DialSpans.
+func TracedDialer(dial func(ctx context.Context, addr string) (net.Conn, error), sampler trace.Sampler) func(context.Context, string) (net.Conn, error) {
+	if dial == nil {
+		dial = resolvingDial
+	}
+	return func(ctx context.Context, addr string) (net.Conn, error) {
+		// gRPC dials in the background: the span is the root of its own
+		// trace.
+		ctx, span := trace.StartSpan(trace.NewContext(ctx, nil), dialSpanName,
+			trace.WithSampler(sampler),
+			trace.WithSpanKind(trace.SpanKindClient))
+		defer span.End()
+		host := addr
+		if h, _, err := net.SplitHostPort(addr); err == nil {
+			host = h
+		}
+		span.AddAttributes(trace.StringAttribute(peerNameAttribute, host))
+		conn, err := dial(ctx, addr)
+		if err != nil {
+			span.SetStatus(trace.Status{Code: trace.StatusCodeUnavailable, Message: err.Error()})
+			return nil, err
+		}
+		span.AddAttributes(transportAttributes(conn.LocalAddr(), conn.RemoteAddr(), true)...)
+		return conn, nil
+	}
+}
+
+// tcpTracedDialer is the dialer of Config.DialSpans: it traces the TCP
+// connections as TracedDialer(nil, sampler) does, and connects to Unix
+// sockets untraced, as gRPC does without a dialer.
+func tcpTracedDialer(sampler trace.Sampler) func(context.Context, string) (net.Conn, error) {
+	traced := TracedDialer(nil, sampler)
+	return func(ctx context.Context, addr string) (net.Conn, error) {
+		if path, ok := unixSocket(addr); ok {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", path)
+		}
+		return traced(ctx, addr)
+	}
+}
+
+// unixSocket returns the path of the Unix socket of addr, as passed by gRPC
+// to the dialers of unix:path, unix://absolute-path and unix-abstract:name
+// targets.
+func unixSocket(addr string) (path string, ok bool) {
+	switch {
+	case strings.HasPrefix(addr, "unix://"):
+		return strings.TrimPrefix(addr, "unix://"), true
+	case strings.HasPrefix(addr, "unix:"):
+		return strings.TrimPrefix(addr, "unix:"), true
+	case strings.HasPrefix(addr, "\x00"), strings.HasPrefix(addr, "@"):
+		// Abstract sockets, which Go names with a leading @.
+		return "@" + addr[1:], true
+	}
+	return "", false
+}
+
+// resolvingDial resolves the host of addr, annotating the span of ctx with
+// the time it took, and connects to the first of its addresses accepting a
+// TCP connection.
+func resolvingDial(ctx context.Context, addr string) (net.Conn, error) {
+	var d net.Dialer
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || net.ParseIP(host) != nil {
+		return d.DialContext(ctx, "tcp", addr)
+	}
+	span := trace.FromContext(ctx)
+	start := time.Now()
+	ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		span.Annotate([]trace.Attribute{trace.StringAttribute("error", err.Error())}, "Name resolution failed")
+		return nil, err
+	}
+	span.Annotate([]trace.Attribute{
+		trace.Int64Attribute("addresses", int64(len(ips))),
+		trace.Int64Attribute("duration_ms", time.Since(start).Milliseconds()),
+	}, "Name resolved")
+	err = &net.AddrError{Err: "no addresses", Addr: host}
+	for _, ip := range ips {
+		var conn net.Conn
+		if conn, err = d.DialContext(ctx, "tcp", net.JoinHostPort(ip.String(), port)); err == nil {
+			return conn, nil
+		}
+	}
+	return nil, err
+}
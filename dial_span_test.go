@@ -0,0 +1,78 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/akhenakh/ocgrpc_propagation/propagationtest"
+	"go.opencensus.io/trace"
+)
+
+func TestUnixSocket(t *testing.T) {
+	for _, tc := range []struct {
+		addr string
+		path string
+		ok   bool
+	}{
+		{"unix:///tmp/grpc.sock", "/tmp/grpc.sock", true},
+		{"unix:grpc.sock", "grpc.sock", true},
+		{"\x00abstract", "@abstract", true},
+		{"@abstract", "@abstract", true},
+		{"localhost:50051", "", false},
+		{"10.0.0.1:50051", "", false},
+	} {
+		if path, ok := unixSocket(tc.addr); path != tc.path || ok != tc.ok {
+			t.Errorf("unixSocket(%q) = %q, %v; want %q, %v", tc.addr, path, ok, tc.path, tc.ok)
+		}
+	}
+}
+
+func TestDialSpansSkipUnixSockets(t *testing.T) {
+	e := propagationtest.Install(t)
+	path := filepath.Join(t.TempDir(), "grpc.sock")
+	unix, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unix.Close()
+	tcp, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tcp.Close()
+
+	dial := tcpTracedDialer(trace.AlwaysSample())
+	conn, err := dial(context.Background(), "unix://"+path)
+	if err != nil {
+		t.Fatalf("dialing the Unix socket: %v", err)
+	}
+	conn.Close()
+	if spans := e.Spans(); len(spans) != 0 {
+		t.Errorf("got %d spans for a Unix socket, want 0", len(spans))
+	}
+
+	conn, err = dial(context.Background(), tcp.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+	if s := onlySpan(t, e); s.Name != dialSpanName {
+		t.Errorf("got span %q, want %q", s.Name, dialSpanName)
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"context"
+	"sync"
+
+	"google.golang.org/grpc/stats"
+)
+
+// The claim of the handler instrumenting an RPC is stored in its context under
+// these keys, so a second handler of the same kind installed on the same
+// server or connection, or the same handler installed twice, doesn't start
+// duplicate nested spans or record stats twice.
+type (
+	serverOwnerKey struct{}
+	clientOwnerKey struct{}
+)
+
+// duplicateDetector claims RPCs for a handler.
+type duplicateDetector struct {
+	warnOnce sync.Once
+}
+
+// claim returns ctx marked as instrumented by owner under key. ok is false if
+// another handler, or owner installed a second time, already instruments the
+// RPC of ctx, in which case a warning is logged once.
+func (d *duplicateDetector) claim(ctx context.Context, key, owner interface{}, name string, l Logger) (context.Context, bool) {
+	if c, ok := ctx.Value(key).(*rpcClaim); ok && c.retag(owner) {
+		d.warnOnce.Do(func() {
+			warningf(l, "opencensus: RPC already instrumented by a %s, check that a single %[1]s is installed once", name)
+		})
+		return ctx, false
+	}
+	return context.WithValue(ctx, key, &rpcClaim{owner: owner, installs: 1}), true
+}
+
+// handles reports whether owner handles rs for the RPC of ctx: it must have
+// claimed the RPC, and when it is installed several times, each event is only
+// handled by the first of its deliveries.
+func handles(ctx context.Context, key, owner interface{}, rs stats.RPCStats) bool {
+	c, ok := ctx.Value(key).(*rpcClaim)
+	return ok && c.owner == owner && c.deliver(rs)
+}
+
+// rpcClaim is the claim of an RPC by the handler owner.
+//
+// gRPC tags an RPC by calling the TagRPC of its stats handlers in turn, each
+// with the context returned by the previous one, and then delivers every event
+// to each of them in the same order. Events of a given kind are delivered by
+// a single goroutine at a time, so the deliveries of an event to the copies of
+// a handler installed several times are consecutive.
+type rpcClaim struct {
+	owner interface{}
+
+	mu        sync.Mutex
+	installs  int  // number of times owner is installed
+	sealed    bool // set once the RPC is tagged by all the handlers
+	delivered [eventKinds]int
+}
+
+// retag records that the RPC is tagged again with its context, and reports
+// whether it is a duplicate tagging by owner or by another handler. Once the
+// RPC was tagged, its context only reaches TagRPC again when it is used to make
+// a new RPC, which must be claimed anew.
+func (c *rpcClaim) retag(owner interface{}) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.sealed {
+		return false
+	}
+	if owner == c.owner {
+		c.installs++
+	}
+	return true
+}
+
+// deliver records the delivery of rs to the owner of c and reports whether it
+// is the first of its deliveries.
+func (c *rpcClaim) deliver(rs stats.RPCStats) bool {
+	k := eventKind(rs)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if k != beginEvent && k != inHeaderEvent {
+		// Begin and, on servers, InHeader are delivered to each handler
+		// right after its TagRPC; the other events follow them all.
+		c.sealed = true
+	}
+	n := c.delivered[k]
+	c.delivered[k]++
+	return n%c.installs == 0
+}
+
+// Kinds of events counted by rpcClaim.
+const (
+	beginEvent = iota
+	inHeaderEvent
+	inPayloadEvent
+	inTrailerEvent
+	outHeaderEvent
+	outPayloadEvent
+	outTrailerEvent
+	endEvent
+	otherEvent
+	eventKinds
+)
+
+func eventKind(rs stats.RPCStats) int {
+	switch rs.(type) {
+	case *stats.Begin:
+		return beginEvent
+	case *stats.InHeader:
+		return inHeaderEvent
+	case *stats.InPayload:
+		return inPayloadEvent
+	case *stats.InTrailer:
+		return inTrailerEvent
+	case *stats.OutHeader:
+		return outHeaderEvent
+	case *stats.OutPayload:
+		return outPayloadEvent
+	case *stats.OutTrailer:
+		return outTrailerEvent
+	case *stats.End:
+		return endEvent
+	}
+	return otherEvent
+}
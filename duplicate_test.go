@@ -0,0 +1,107 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/akhenakh/ocgrpc_propagation/propagationtest"
+	"go.opencensus.io/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// checkWith runs a health check through a server and a client installing the
+// given stats handlers, and returns the spans exported once both spans of the
+// RPC ended.
+func checkWith(t *testing.T, server []stats.Handler, client []stats.Handler) []*trace.SpanData {
+	t.Helper()
+	e := propagationtest.Install(t)
+	var sopts []grpc.ServerOption
+	for _, h := range server {
+		sopts = append(sopts, grpc.StatsHandler(h))
+	}
+	lis := bufconn.Listen(1 << 16)
+	srv := grpc.NewServer(sopts...)
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	dopts := []grpc.DialOption{
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithInsecure(),
+	}
+	for _, h := range client {
+		dopts = append(dopts, grpc.WithStatsHandler(h))
+	}
+	conn, err := grpc.Dial("bufnet", dopts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	// The server ends its span after sending its response.
+	for deadline := time.Now().Add(5 * time.Second); len(e.Spans()) < 2 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	srv.Stop()
+	return e.Spans()
+}
+
+func TestDuplicateHandlers(t *testing.T) {
+	sampled := trace.StartOptions{Sampler: trace.AlwaysSample()}
+	s := &ServerHandler{StartOptions: sampled, Logger: discardLogger{}}
+	c := &ClientHandler{StartOptions: sampled, Logger: discardLogger{}}
+	for _, tc := range []struct {
+		name           string
+		server, client []stats.Handler
+	}{
+		{"once", []stats.Handler{s}, []stats.Handler{c}},
+		{"twice", []stats.Handler{s, s}, []stats.Handler{c, c}},
+		{"three times", []stats.Handler{s, s, s}, []stats.Handler{c, c, c}},
+		{"two handlers", []stats.Handler{s, &ServerHandler{StartOptions: sampled, Logger: discardLogger{}}},
+			[]stats.Handler{c, &ClientHandler{StartOptions: sampled, Logger: discardLogger{}}}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			spans := checkWith(t, tc.server, tc.client)
+			if len(spans) != 2 {
+				t.Fatalf("exported %d spans, want a client and a server span: %v", len(spans), spans)
+			}
+			client, server := spans[0], spans[1]
+			if client.SpanKind != trace.SpanKindClient {
+				client, server = server, client
+			}
+			if client.SpanKind != trace.SpanKindClient || server.SpanKind != trace.SpanKindServer {
+				t.Fatalf("span kinds %d and %d, want a client and a server span", client.SpanKind, server.SpanKind)
+			}
+			if server.ParentSpanID != client.SpanID {
+				t.Errorf("server span parent %v, want the client span %v", server.ParentSpanID, client.SpanID)
+			}
+			for _, span := range spans {
+				if len(span.MessageEvents) != 2 {
+					t.Errorf("span %q has %d message events, want 2", span.Name, len(span.MessageEvents))
+				}
+			}
+		})
+	}
+}
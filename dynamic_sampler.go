@@ -0,0 +1,198 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"go.opencensus.io/trace"
+)
+
+// DynamicSamplingConfig is the configuration of a DynamicSampler, also its
+// JSON representation on the admin endpoint.
+type DynamicSamplingConfig struct {
+	// Probability of sampling the spans of the operations without their own
+	// probability in Operations.
+	Probability float64 `json:"probability"`
+
+	// Operations maps span names, see FormatSpanName, to their sampling
+	// probability.
+	Operations map[string]float64 `json:"operations,omitempty"`
+}
+
+func (c DynamicSamplingConfig) validate() error {
+	if !validProbability(c.Probability) {
+		return fmt.Errorf("sampling probability %v out of [0, 1]", c.Probability)
+	}
+	for name, p := range c.Operations {
+		if !validProbability(p) {
+			return fmt.Errorf("sampling probability %v of %q out of [0, 1]", p, name)
+		}
+	}
+	return nil
+}
+
+func validProbability(p float64) bool {
+	return !math.IsNaN(p) && p >= 0 && p <= 1
+}
+
+// DynamicSampler samples spans with probabilities that can be changed while
+// the service runs, so operators can raise sampling during an incident
+// without restarting services, through its methods or its admin endpoint.
+// Use its Sampler as the DefaultSampler of the handlers.
+//
+// Spans whose parent is sampled are always sampled. The zero value samples
+// only them, until configured.
+type DynamicSampler struct {
+	// mu serializes updates, current is swapped atomically so sampling
+	// never waits for them.
+	mu      sync.Mutex
+	current atomic.Value // *dynamicSampling
+}
+
+// dynamicSampling is an immutable configuration of a DynamicSampler and the
+// samplers built from it.
+type dynamicSampling struct {
+	config  DynamicSamplingConfig
+	sampler trace.Sampler
+	ops     map[string]trace.Sampler
+}
+
+func newDynamicSampling(c DynamicSamplingConfig) *dynamicSampling {
+	s := &dynamicSampling{
+		config:  DynamicSamplingConfig{Probability: c.Probability},
+		sampler: trace.ProbabilitySampler(c.Probability),
+	}
+	if len(c.Operations) > 0 {
+		s.config.Operations = make(map[string]float64, len(c.Operations))
+		s.ops = make(map[string]trace.Sampler, len(c.Operations))
+		for name, p := range c.Operations {
+			s.config.Operations[name] = p
+			s.ops[name] = trace.ProbabilitySampler(p)
+		}
+	}
+	return s
+}
+
+// NewDynamicSampler returns a DynamicSampler configured by c. It returns an
+// error if a probability of c is out of [0, 1].
+func NewDynamicSampler(c DynamicSamplingConfig) (*DynamicSampler, error) {
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	d := &DynamicSampler{}
+	d.current.Store(newDynamicSampling(c))
+	return d, nil
+}
+
+// unconfiguredSampling is the configuration of the zero DynamicSampler.
+var unconfiguredSampling = newDynamicSampling(DynamicSamplingConfig{})
+
+func (d *DynamicSampler) load() *dynamicSampling {
+	if s, ok := d.current.Load().(*dynamicSampling); ok {
+		return s
+	}
+	return unconfiguredSampling
+}
+
+// Sampler returns the trace.Sampler applying the current configuration of d.
+func (d *DynamicSampler) Sampler() trace.Sampler {
+	return func(p trace.SamplingParameters) trace.SamplingDecision {
+		s := d.load()
+		if sampler, ok := s.ops[p.Name]; ok {
+			return sampler(p)
+		}
+		return s.sampler(p)
+	}
+}
+
+// Config returns the current configuration of d.
+func (d *DynamicSampler) Config() DynamicSamplingConfig {
+	c := d.load().config
+	if c.Operations != nil {
+		ops := make(map[string]float64, len(c.Operations))
+		for name, p := range c.Operations {
+			ops[name] = p
+		}
+		c.Operations = ops
+	}
+	return c
+}
+
+// Set replaces the configuration of d with c. It returns an error, leaving
+// the configuration unchanged, if a probability of c is out of [0, 1].
+func (d *DynamicSampler) Set(c DynamicSamplingConfig) error {
+	if err := c.validate(); err != nil {
+		return err
+	}
+	d.mu.Lock()
+	d.current.Store(newDynamicSampling(c))
+	d.mu.Unlock()
+	return nil
+}
+
+// SetProbability sets the probability of the operations without their own.
+func (d *DynamicSampler) SetProbability(p float64) error {
+	return d.update(func(c *DynamicSamplingConfig) { c.Probability = p })
+}
+
+// SetPerMethod sets the probability of the operations of ops, keyed by span
+// name, replacing the previous ones. A nil ops samples every operation with
+// the default probability.
+func (d *DynamicSampler) SetPerMethod(ops map[string]float64) error {
+	return d.update(func(c *DynamicSamplingConfig) { c.Operations = ops })
+}
+
+func (d *DynamicSampler) update(f func(c *DynamicSamplingConfig)) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	c := d.load().config
+	f(&c)
+	if err := c.validate(); err != nil {
+		return err
+	}
+	d.current.Store(newDynamicSampling(c))
+	return nil
+}
+
+// ServeHTTP is the admin endpoint of d: GET returns its configuration as a
+// JSON DynamicSamplingConfig, PUT replaces it. It must only be served to
+// operators, on an admin port.
+func (d *DynamicSampler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var c DynamicSamplingConfig
+		if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := d.Set(c); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(d.Config())
+}
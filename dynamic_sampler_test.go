@@ -0,0 +1,92 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"math"
+	"sync"
+	"testing"
+
+	"go.opencensus.io/trace"
+)
+
+func TestDynamicSamplerZeroValue(t *testing.T) {
+	var d DynamicSampler
+	sampler := d.Sampler()
+	if sampler(trace.SamplingParameters{Name: "op"}).Sample {
+		t.Error("zero DynamicSampler sampled a root span")
+	}
+	parent := trace.SpanContext{TraceOptions: 1}
+	if !sampler(trace.SamplingParameters{ParentContext: parent, Name: "op"}).Sample {
+		t.Error("zero DynamicSampler didn't sample a span with a sampled parent")
+	}
+	if c := d.Config(); c.Probability != 0 || c.Operations != nil {
+		t.Errorf("Config() = %+v, want zero", c)
+	}
+	if err := d.SetProbability(1); err != nil {
+		t.Fatal(err)
+	}
+	if !sampler(trace.SamplingParameters{Name: "op"}).Sample {
+		t.Error("DynamicSampler didn't sample with probability 1")
+	}
+}
+
+func TestDynamicSamplerRejectsNaN(t *testing.T) {
+	if _, err := NewDynamicSampler(DynamicSamplingConfig{Probability: math.NaN()}); err == nil {
+		t.Error("NewDynamicSampler accepted a NaN probability")
+	}
+	d, err := NewDynamicSampler(DynamicSamplingConfig{Probability: 0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SetProbability(math.NaN()); err == nil {
+		t.Error("SetProbability accepted NaN")
+	}
+	if err := d.SetPerMethod(map[string]float64{"op": math.NaN()}); err == nil {
+		t.Error("SetPerMethod accepted NaN")
+	}
+	if c := d.Config(); c.Probability != 0.5 || c.Operations != nil {
+		t.Errorf("Config() = %+v after rejected updates", c)
+	}
+}
+
+func TestDynamicSamplerConcurrentUpdates(t *testing.T) {
+	var d DynamicSampler
+	sampler := d.Sampler()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if err := d.SetPerMethod(map[string]float64{"op": float64(j % 2)}); err != nil {
+					t.Error(err)
+				}
+				if err := d.SetProbability(float64(i % 2)); err != nil {
+					t.Error(err)
+				}
+				d.Config()
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				sampler(trace.SamplingParameters{Name: "op"})
+				sampler(trace.SamplingParameters{Name: "other"})
+			}
+		}()
+	}
+	wg.Wait()
+}
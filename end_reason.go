@@ -0,0 +1,75 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/status"
+)
+
+// endReasonAttribute is the span attribute recording why the RPC ended, one
+// of the endReason values.
+const endReasonAttribute = "grpc.end_reason"
+
+// Values of the grpc.end_reason attribute and of the KeyClientEndReason and
+// KeyServerEndReason tags.
+const (
+	endReasonOK               = "ok"
+	endReasonCanceled         = "canceled"
+	endReasonDeadlineExceeded = "deadline_exceeded"
+	endReasonTransportError   = "transport_error"
+	endReasonApplicationError = "application_error"
+)
+
+// endReason tells why the RPC ending with rs, whose context is ctx, ended,
+// telling a caller giving up from a failure of the server or the network
+// better than its status code:
+//   - canceled when the RPC was canceled before its deadline, by the
+//     application of the client or, on servers, by the remote client;
+//   - deadline_exceeded when the deadline of the RPC expired;
+//   - transport_error when a client RPC failed without receiving the
+//     trailer of the server, or a server RPC failed without a status;
+//   - application_error when the server returned an error status.
+//
+// trailerReceived reports whether a client RPC received the trailer of the
+// server.
+func endReason(ctx context.Context, rs *stats.End, trailerReceived bool) string {
+	if rs.Error == nil {
+		return endReasonOK
+	}
+	s, ok := status.FromError(rs.Error)
+	switch s.Code() {
+	case codes.DeadlineExceeded:
+		return endReasonDeadlineExceeded
+	case codes.Canceled:
+		// gRPC may report an expired deadline as a cancellation.
+		endTime := rs.EndTime
+		if endTime.IsZero() {
+			endTime = time.Now()
+		}
+		if deadline, ok := ctx.Deadline(); ok && !endTime.Before(deadline) {
+			return endReasonDeadlineExceeded
+		}
+		return endReasonCanceled
+	}
+	if !ok || (rs.Client && !trailerReceived) {
+		return endReasonTransportError
+	}
+	return endReasonApplicationError
+}
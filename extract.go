@@ -0,0 +1,84 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"context"
+	"strconv"
+
+	propag "github.com/akhenakh/ocgrpc_propagation/propagation"
+	"go.opencensus.io/trace"
+	"google.golang.org/grpc/metadata"
+)
+
+// SpanContextFromIncomingContext returns the span context carried by the
+// incoming metadata of ctx, read with the propagators of s as TagRPC does, so
+// background workers and custom transports share the extraction logic of the
+// handler. ok is false when no valid span context is found, or when s.Signer
+// is set and the signature of the span context is invalid. Unlike TagRPC, it
+// records no stats.
+func (s *ServerHandler) SpanContextFromIncomingContext(ctx context.Context) (sc trace.SpanContext, ok bool) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, p := range extractors(s.propagators()) {
+		if sc, ok = p.Extract(md); ok {
+			break
+		}
+	}
+	if !ok {
+		return trace.SpanContext{}, false
+	}
+	if s.Signer != nil {
+		sig := propag.Lookup(md, propag.SignatureKey)
+		if len(sig) == 0 || !s.Signer.Verify(sc, []byte(sig[0])) {
+			return trace.SpanContext{}, false
+		}
+	}
+	return sc, true
+}
+
+// InjectSpanContext returns a copy of ctx whose outgoing metadata carries sc in
+// the formats written by c, signed if c.Signer is set, as TagRPC does, so
+// message producers and custom transports share the injection logic of the
+// handler.
+func (c *ClientHandler) InjectSpanContext(ctx context.Context, sc trace.SpanContext) context.Context {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	md = md.Copy()
+	c.injectMetadata(ctx, sc, md)
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+// injectMetadata writes sc to md in the formats written by c, signed if
+// c.Signer is set, with the sampling priority of the trace of ctx if
+// c.SamplingPriorityKey is set.
+func (c *ClientHandler) injectMetadata(ctx context.Context, sc trace.SpanContext, md metadata.MD) {
+	text := sc
+	if c.TraceID64 {
+		text.TraceID = trace.TraceID{}
+		copy(text.TraceID[8:], sc.TraceID[8:])
+	}
+	for _, p := range injectors(c.propagators()) {
+		if _, binary := p.(propag.BinaryPropagator); binary {
+			p.Inject(sc, md)
+		} else {
+			p.Inject(text, md)
+		}
+	}
+	if c.Signer != nil {
+		md.Set(propag.SignatureKey, string(c.Signer.Sign(sc)))
+	}
+	if c.SamplingPriorityKey != "" {
+		md.Set(c.SamplingPriorityKey, strconv.Itoa(outgoingSamplingPriority(ctx, sc)))
+	}
+}
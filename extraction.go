@@ -0,0 +1,89 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.opencensus.io/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ExtractionFailurePolicy controls what the ServerHandler does when tracing
+// metadata is present on an inbound RPC but can't be used.
+type ExtractionFailurePolicy int
+
+const (
+	// StartNewTrace starts a new trace, as if no tracing metadata was
+	// present.
+	StartNewTrace ExtractionFailurePolicy = iota
+
+	// AnnotateNewTrace starts a new trace and adds an annotation listing
+	// the formats that couldn't be used to its root span.
+	AnnotateNewTrace
+
+	// RejectRPC fails the RPC with codes.InvalidArgument. It requires the
+	// EnforceUnaryInterceptor and EnforceStreamInterceptor to be installed,
+	// as a stats.Handler can't fail an RPC itself.
+	RejectRPC
+)
+
+type rejectionKey struct{}
+
+func (s *ServerHandler) handleExtractionFailure(ctx context.Context, span *trace.Span, failed []string) context.Context {
+	switch s.OnExtractionFailure {
+	case AnnotateNewTrace:
+		span.Annotate([]trace.Attribute{
+			trace.StringAttribute("formats", strings.Join(failed, ",")),
+		}, "Invalid incoming trace context")
+	case RejectRPC:
+		err := status.Error(codes.InvalidArgument, fmt.Sprintf("invalid trace context in %s metadata", strings.Join(failed, ", ")))
+		ctx = context.WithValue(ctx, rejectionKey{}, err)
+	}
+	return ctx
+}
+
+// rejection returns the error the ServerHandler decided to fail the RPC of ctx
+// with, if any.
+func rejection(ctx context.Context) error {
+	err, _ := ctx.Value(rejectionKey{}).(error)
+	return err
+}
+
+// EnforceUnaryInterceptor fails the RPCs the ServerHandler decided to reject,
+// see RejectRPC.
+func EnforceUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := rejection(ctx); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// EnforceStreamInterceptor fails the RPCs the ServerHandler decided to reject,
+// see RejectRPC.
+func EnforceStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := rejection(stream.Context()); err != nil {
+			return err
+		}
+		return handler(srv, stream)
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import "strings"
+
+// FilterFunc reports whether the RPCs of the full method name fullMethod, such
+// as "/grpc.health.v1.Health/Check", are ignored by a handler: they get no
+// span and no stats.
+type FilterFunc func(fullMethod string) bool
+
+// FilterMethods returns a FilterFunc ignoring the RPCs of the full method
+// names methods.
+func FilterMethods(methods ...string) FilterFunc {
+	ignored := make(map[string]bool, len(methods))
+	for _, m := range methods {
+		ignored[m] = true
+	}
+	return func(fullMethod string) bool {
+		return ignored[fullMethod]
+	}
+}
+
+// FilterHealthAndReflection is a FilterFunc ignoring the RPCs of the gRPC
+// health checking service, grpc.health.v1.Health, and of every version of the
+// server reflection service, such as grpc.reflection.v1alpha.ServerReflection.
+func FilterHealthAndReflection(fullMethod string) bool {
+	return strings.HasPrefix(fullMethod, "/grpc.health.v1.Health/") ||
+		strings.HasPrefix(fullMethod, "/grpc.reflection.")
+}
+
+// anyFilter returns a FilterFunc ignoring the RPCs ignored by f or g, which
+// may be nil.
+func anyFilter(f, g FilterFunc) FilterFunc {
+	if f == nil {
+		return g
+	}
+	if g == nil {
+		return f
+	}
+	return func(fullMethod string) bool {
+		return f(fullMethod) || g(fullMethod)
+	}
+}
+
+// filtered reports whether the RPC of fullMethod is ignored according to f.
+func filtered(f FilterFunc, fullMethod string) bool {
+	return f != nil && f(fullMethod)
+}
@@ -0,0 +1,47 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import "context"
+
+// FeatureFlags is the interface of a feature-flag system consulted before
+// tracing each RPC, so tracing of a method can be turned off at runtime.
+type FeatureFlags interface {
+	// Enabled reports whether RPCs of the full method name method, such
+	// as "/pkg.Service/Method", are traced.
+	Enabled(method string) bool
+}
+
+// untracedKey marks the context of RPCs whose tracing is turned off, for the
+// client or server side.
+type untracedKey struct {
+	client bool
+}
+
+// tagTracing reports whether the RPC of method is traced, unless tracing is
+// disabled, according to flags. If it isn't, the returned context is marked
+// as such.
+func tagTracing(ctx context.Context, disabled bool, flags FeatureFlags, method string, client bool) (context.Context, bool) {
+	if !disabled && (flags == nil || flags.Enabled(method)) {
+		return ctx, true
+	}
+	return context.WithValue(ctx, untracedKey{client: client}, true), false
+}
+
+// traced reports whether the RPC of ctx is traced on the client or server
+// side.
+func traced(ctx context.Context, client bool) bool {
+	return ctx.Value(untracedKey{client: client}) == nil
+}
@@ -0,0 +1,47 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"strings"
+
+	propag "github.com/akhenakh/ocgrpc_propagation/propagation"
+	"google.golang.org/grpc/metadata"
+)
+
+// DefaultForceTraceKey is the incoming metadata key suggested for
+// ServerHandler.ForceTraceKey.
+const DefaultForceTraceKey = "x-force-trace"
+
+// forcedAttribute is the server span attribute marking the spans sampled
+// because of ServerHandler.ForceTraceKey.
+const forcedAttribute = "forced"
+
+// forceTrace reports whether md asks to sample the span of its RPC, with a
+// s.ForceTraceKey value other than "0" or "false".
+func (s *ServerHandler) forceTrace(md metadata.MD) bool {
+	if s.ForceTraceKey == "" {
+		return false
+	}
+	vs := propag.Lookup(md, s.ForceTraceKey)
+	if len(vs) == 0 {
+		return false
+	}
+	switch strings.ToLower(strings.TrimSpace(vs[0])) {
+	case "", "0", "false":
+		return false
+	}
+	return true
+}
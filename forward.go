@@ -0,0 +1,123 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/akhenakh/ocgrpc_propagation/baggage"
+	propag "github.com/akhenakh/ocgrpc_propagation/propagation"
+	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	ocstats "go.opencensus.io/stats"
+	"go.opencensus.io/tag"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// MetadataPropagateUnaryInterceptor copies the incoming metadata matching keys
+// to the outgoing metadata of the context passed to the handler, so request
+// IDs, tenant IDs or baggage reach the RPCs it makes. Keys ending in "*"
+// match every key starting with the rest of the key, such as "uberctx-*".
+func MetadataPropagateUnaryInterceptor(keys ...string) grpc.UnaryServerInterceptor {
+	return LimitedMetadataPropagateUnaryInterceptor(baggage.Limits{}, keys...)
+}
+
+// MetadataPropagateStreamInterceptor copies the incoming metadata matching
+// keys to the outgoing metadata of the context passed to the handler, see
+// MetadataPropagateUnaryInterceptor.
+func MetadataPropagateStreamInterceptor(keys ...string) grpc.StreamServerInterceptor {
+	return LimitedMetadataPropagateStreamInterceptor(baggage.Limits{}, keys...)
+}
+
+// LimitedMetadataPropagateUnaryInterceptor is like
+// MetadataPropagateUnaryInterceptor but enforces limits on the copied
+// metadata, so untrusted callers can't inflate every downstream request. Each
+// value counts as an item, keys are considered in sorted order. Limited
+// values are recorded by ServerForwardedMetadataLimitedItems.
+func LimitedMetadataPropagateUnaryInterceptor(limits baggage.Limits, keys ...string) grpc.UnaryServerInterceptor {
+	l := propag.Allowlist(keys)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(forwardMetadata(ctx, info.FullMethod, l, limits), req)
+	}
+}
+
+// LimitedMetadataPropagateStreamInterceptor is like
+// MetadataPropagateStreamInterceptor but enforces limits on the copied
+// metadata, see LimitedMetadataPropagateUnaryInterceptor.
+func LimitedMetadataPropagateStreamInterceptor(limits baggage.Limits, keys ...string) grpc.StreamServerInterceptor {
+	l := propag.Allowlist(keys)
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		wrapped := grpc_middleware.WrapServerStream(stream)
+		wrapped.WrappedContext = forwardMetadata(stream.Context(), info.FullMethod, l, limits)
+		return handler(srv, wrapped)
+	}
+}
+
+// forwardMetadata returns ctx with the incoming metadata allowed by l copied
+// to the outgoing metadata within limits, replacing the values already there.
+// Pseudo-headers such as :authority are never copied.
+func forwardMetadata(ctx context.Context, method string, l propag.Allowlist, limits baggage.Limits) context.Context {
+	in, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+	var items []baggage.Item
+	for k, vs := range in {
+		if strings.HasPrefix(k, ":") || !l.Allows(k) {
+			continue
+		}
+		for _, v := range vs {
+			items = append(items, baggage.Item{Key: k, Value: v})
+		}
+	}
+	if len(items) == 0 {
+		return ctx
+	}
+	if limits != (baggage.Limits{}) {
+		// Sort the keys so the limits are applied deterministically, keeping
+		// the order of the values of each key.
+		sort.SliceStable(items, func(i, j int) bool { return items[i].Key < items[j].Key })
+		var truncated, dropped int
+		items, truncated, dropped = limits.Apply(items)
+		recordForwardedMetadataLimited(ctx, method, "truncated", truncated)
+		recordForwardedMetadataLimited(ctx, method, "dropped", dropped)
+		if len(items) == 0 {
+			return ctx
+		}
+	}
+	md, _ := metadata.FromOutgoingContext(ctx)
+	md = md.Copy()
+	for _, it := range items {
+		delete(md, it.Key)
+	}
+	for _, it := range items {
+		md.Append(it.Key, it.Value)
+	}
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+func recordForwardedMetadataLimited(ctx context.Context, method, action string, n int) {
+	if n == 0 {
+		return
+	}
+	ocstats.RecordWithTags(ctx,
+		[]tag.Mutator{
+			tag.Upsert(KeyServerMethod, methodName(method)),
+			tag.Upsert(KeyBaggageLimitAction, action),
+		},
+		ServerForwardedMetadataLimitedItems.M(int64(n)))
+}
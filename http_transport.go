@@ -0,0 +1,79 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"net/http"
+	"strings"
+
+	"go.opencensus.io/trace"
+	"google.golang.org/grpc/metadata"
+)
+
+// Transport is an http.RoundTripper continuing the trace of the context of
+// each request, such as the context of an RPC handled by a ServerHandler, in
+// the outgoing HTTP call: the SpanContext of the span of the context is
+// written to the request headers, like ClientHandler writes it to the
+// metadata of RPCs.
+//
+// Transport doesn't start spans: wrap it in an ochttp.Transport to trace the
+// calls themselves, so the span of each call is the one written.
+type Transport struct {
+	// Base is the RoundTripper making the requests. http.DefaultTransport
+	// is used if Base is nil.
+	Base http.RoundTripper
+
+	// Handler configures the formats written: its Propagators, or the
+	// formats enabled by its Inject fields, with its Jaeger, TraceID64 and
+	// Signer settings. Binary formats, which HTTP servers don't read, are
+	// not written. When Handler is nil, the uber-trace-id and traceparent
+	// headers are written.
+	Handler *ClientHandler
+}
+
+// defaultTransportHandler is the ClientHandler of Transports with no Handler.
+var defaultTransportHandler = &ClientHandler{InjectJaeger: true, InjectTraceContext: true}
+
+// RoundTrip implements http.RoundTripper. Requests whose context has no span
+// are sent unmodified.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	span := trace.FromContext(req.Context())
+	if span == nil {
+		return base.RoundTrip(req)
+	}
+	h := t.Handler
+	if h == nil {
+		h = defaultTransportHandler
+	}
+	md := metadata.MD{}
+	h.injectMetadata(req.Context(), span.SpanContext(), md)
+
+	// A RoundTripper must not modify the request it is given.
+	req = req.Clone(req.Context())
+	for k, vs := range md {
+		if strings.HasSuffix(k, "-bin") {
+			continue
+		}
+		req.Header.Del(k)
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	return base.RoundTrip(req)
+}
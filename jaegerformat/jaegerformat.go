@@ -0,0 +1,54 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package jaegerformat contains an HTTP format for the Jaeger uber-trace-id
+// header, so ochttp servers and clients read and write the same values as the
+// ocgrpc handlers.
+package jaegerformat
+
+import (
+	"net/http"
+
+	propag "github.com/akhenakh/ocgrpc_propagation/propagation"
+	"go.opencensus.io/trace"
+	"go.opencensus.io/trace/propagation"
+)
+
+var _ propagation.HTTPFormat = (*HTTPFormat)(nil)
+
+// HTTPFormat implements propagation.HTTPFormat with the Jaeger uber-trace-id
+// header. It may be set as the Propagation of an ochttp.Handler or
+// ochttp.Transport.
+type HTTPFormat struct {
+	// Options controls how trace IDs are read and written.
+	Options propag.JaegerOptions
+}
+
+// SpanContextFromRequest extracts a span context from the uber-trace-id header
+// of req.
+func (f *HTTPFormat) SpanContextFromRequest(req *http.Request) (sc trace.SpanContext, ok bool) {
+	jv := req.Header.Get(propag.JaegerKey)
+	if jv == "" {
+		return trace.SpanContext{}, false
+	}
+	return f.Options.Parse(jv)
+}
+
+// SpanContextToRequest sets the uber-trace-id header of req to sc. The header
+// is left unset when sc can't be written with f.Options.
+func (f *HTTPFormat) SpanContextToRequest(sc trace.SpanContext, req *http.Request) {
+	if jv, ok := f.Options.Format(sc); ok {
+		req.Header.Set(propag.JaegerKey, jv)
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import "go.opencensus.io/trace"
+
+// Attributes recording the number of messages of an RPC, added to its span
+// when a MessageEventLimit drops message events.
+const (
+	sentMessagesAttribute     = "grpc.sent_messages"
+	receivedMessagesAttribute = "grpc.received_messages"
+)
+
+// MessageEventLimit caps the message events added to the span of an RPC, so
+// that the span of a long-lived stream doesn't exceed the limits of exporters
+// nor grow without bound in memory. Sent and received messages are limited
+// separately. The zero value keeps every message event.
+type MessageEventLimit struct {
+	// First is the number of messages with a message event, whatever Every.
+	// Zero disables the limit.
+	First int64
+
+	// Every may be set to keep the message event of one in Every messages
+	// after the First ones. If zero, every message after the First ones is
+	// dropped.
+	Every int64
+}
+
+func (l MessageEventLimit) enabled() bool {
+	return l.First > 0
+}
+
+// keep reports whether the message event of message id, counting from 1, is
+// added to the span.
+func (l MessageEventLimit) keep(id int64) bool {
+	if !l.enabled() || id <= l.First {
+		return true
+	}
+	return l.Every > 0 && (id-l.First)%l.Every == 0
+}
+
+// addMessageCounts records the number of messages sent and received by the
+// RPC, since its span doesn't have an event for each of them.
+func (l MessageEventLimit) addMessageCounts(span *trace.Span, d *rpcTraceData) {
+	if !l.enabled() || d == nil {
+		return
+	}
+	sent, received := d.messageCounts()
+	span.AddAttributes(
+		trace.Int64Attribute(sentMessagesAttribute, sent),
+		trace.Int64Attribute(receivedMessagesAttribute, received))
+}
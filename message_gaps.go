@@ -0,0 +1,79 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"time"
+
+	"go.opencensus.io/trace"
+)
+
+// Attributes recording the longest gaps between consecutive messages of an
+// RPC, in milliseconds, when the MessageGapThreshold of a handler is set.
+const (
+	maxSendGapAttribute    = "grpc.max_send_gap_ms"
+	maxReceiveGapAttribute = "grpc.max_receive_gap_ms"
+)
+
+// messageGap returns the time between at and the previous message of the
+// direction whose last message time is *last, and updates *last and *max.
+// The gap of the first message is zero.
+func messageGap(at time.Time, last *time.Time, max *time.Duration) time.Duration {
+	if at.IsZero() {
+		at = time.Now()
+	}
+	var gap time.Duration
+	if !last.IsZero() {
+		gap = at.Sub(*last)
+	}
+	*last = at
+	if gap > *max {
+		*max = gap
+	}
+	return gap
+}
+
+// sendGap records the time a message was sent at and annotates span when it
+// follows the previous sent message by threshold or more: sends blocked by
+// flow control show up as such gaps.
+func (d *rpcTraceData) sendGap(span *trace.Span, at time.Time, threshold time.Duration) {
+	d.mu.Lock()
+	gap := messageGap(at, &d.lastSent, &d.maxSendGap)
+	d.mu.Unlock()
+	if gap >= threshold {
+		span.Annotate([]trace.Attribute{trace.Int64Attribute("gap_ms", gap.Milliseconds())}, "Send gap")
+	}
+}
+
+// receiveGap records the time a message was received at and annotates span
+// when it follows the previous received message by threshold or more: slow
+// peers and messages waiting for the handler show up as such gaps.
+func (d *rpcTraceData) receiveGap(span *trace.Span, at time.Time, threshold time.Duration) {
+	d.mu.Lock()
+	gap := messageGap(at, &d.lastReceived, &d.maxReceiveGap)
+	d.mu.Unlock()
+	if gap >= threshold {
+		span.Annotate([]trace.Attribute{trace.Int64Attribute("gap_ms", gap.Milliseconds())}, "Receive gap")
+	}
+}
+
+// addMaxGaps records the longest gaps between the messages of the RPC.
+func (d *rpcTraceData) addMaxGaps(span *trace.Span) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	span.AddAttributes(
+		trace.Int64Attribute(maxSendGapAttribute, d.maxSendGap.Milliseconds()),
+		trace.Int64Attribute(maxReceiveGapAttribute, d.maxReceiveGap.Milliseconds()))
+}
@@ -0,0 +1,39 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import "sync/atomic"
+
+// messageIDs are the sequence numbers of the messages sent and received by an
+// RPC, kept in its rpcTraceData and used as the message IDs of the events of
+// its span. Each direction has its own sequence, starting at 1.
+type messageIDs struct {
+	sent, received int64
+}
+
+// nextSent returns the ID of the next message sent.
+func (ids *messageIDs) nextSent() int64 {
+	return atomic.AddInt64(&ids.sent, 1)
+}
+
+// nextReceived returns the ID of the next message received.
+func (ids *messageIDs) nextReceived() int64 {
+	return atomic.AddInt64(&ids.received, 1)
+}
+
+// counts returns the number of messages sent and received so far.
+func (ids *messageIDs) counts() (sent, received int64) {
+	return atomic.LoadInt64(&ids.sent), atomic.LoadInt64(&ids.received)
+}
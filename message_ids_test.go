@@ -0,0 +1,78 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/akhenakh/ocgrpc_propagation/propagationtest"
+	"go.opencensus.io/trace"
+	"google.golang.org/grpc/stats"
+)
+
+func TestMessageIDsPerDirection(t *testing.T) {
+	var ids messageIDs
+	for want := int64(1); want <= 3; want++ {
+		if got := ids.nextSent(); got != want {
+			t.Errorf("nextSent() = %d, want %d", got, want)
+		}
+	}
+	if got := ids.nextReceived(); got != 1 {
+		t.Errorf("nextReceived() = %d, want 1: each direction has its own sequence", got)
+	}
+	if sent, received := ids.counts(); sent != 3 || received != 1 {
+		t.Errorf("counts() = %d, %d; want 3, 1", sent, received)
+	}
+}
+
+func TestMessageIDsPerRPC(t *testing.T) {
+	e := propagationtest.Install(t)
+	first, second := startTracedRPC(t), startTracedRPC(t)
+	for _, ctx := range []context.Context{first, first, second} {
+		traceHandleRPC(ctx, &stats.OutPayload{Length: 10, SentTime: time.Now()}, traceOptions{})
+	}
+	traceHandleRPC(second, &stats.InPayload{Length: 10, RecvTime: time.Now()}, traceOptions{})
+	for _, ctx := range []context.Context{first, second} {
+		traceHandleRPC(ctx, &stats.End{EndTime: time.Now()}, traceOptions{})
+	}
+
+	want := map[trace.SpanID][]trace.MessageEvent{
+		trace.FromContext(first).SpanContext().SpanID: {
+			{EventType: trace.MessageEventTypeSent, MessageID: 1},
+			{EventType: trace.MessageEventTypeSent, MessageID: 2},
+		},
+		trace.FromContext(second).SpanContext().SpanID: {
+			{EventType: trace.MessageEventTypeSent, MessageID: 1},
+			{EventType: trace.MessageEventTypeRecv, MessageID: 1},
+		},
+	}
+	spans := e.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	for _, s := range spans {
+		w := want[s.SpanID]
+		if len(s.MessageEvents) != len(w) {
+			t.Fatalf("span %v: got %d message events, want %d", s.SpanID, len(s.MessageEvents), len(w))
+		}
+		for i, ev := range s.MessageEvents {
+			if ev.EventType != w[i].EventType || ev.MessageID != w[i].MessageID {
+				t.Errorf("span %v: message event %d is %v %d, want %v %d", s.SpanID, i, ev.EventType, ev.MessageID, w[i].EventType, w[i].MessageID)
+			}
+		}
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"context"
+	"sync"
+
+	"go.opencensus.io/trace"
+)
+
+// messageSpanName is the name of the child spans of streaming RPCs created
+// when the MessageSpans of a handler is set.
+const messageSpanName = "grpc.message"
+
+// Attributes of message spans.
+const (
+	messageReceivedIDAttribute   = "grpc.message.received_id"
+	messageReceivedSizeAttribute = "grpc.message.received_size"
+	messageSentIDAttribute       = "grpc.message.sent_id"
+	messageSentSizeAttribute     = "grpc.message.sent_size"
+)
+
+// messageSpans creates the child spans of the messages of a streaming RPC.
+// Each request message, received by servers and sent by clients, starts a
+// span ended by the next response message, so the span measures the time
+// taken to answer it in lock-step streams. Responses that don't follow a
+// request get their own instant span.
+type messageSpans struct {
+	mu sync.Mutex
+
+	// streaming is set when the RPC starts, as unary RPCs don't get message
+	// spans.
+	streaming bool
+
+	sent, received int64
+	pending        *trace.Span
+}
+
+type messageSpansKey struct{}
+
+func newMessageSpansContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, messageSpansKey{}, &messageSpans{})
+}
+
+func messageSpansFromContext(ctx context.Context) *messageSpans {
+	m, _ := ctx.Value(messageSpansKey{}).(*messageSpans)
+	return m
+}
+
+func (m *messageSpans) begin(streaming bool) {
+	m.mu.Lock()
+	m.streaming = streaming
+	m.mu.Unlock()
+}
+
+// message records a message sent or received by the RPC of ctx, whose span is
+// parent. client tells whether the RPC is a client one.
+func (m *messageSpans) message(ctx context.Context, parent *trace.Span, client, sent bool, size int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var attrs []trace.Attribute
+	if sent {
+		m.sent++
+		attrs = []trace.Attribute{
+			trace.Int64Attribute(messageSentIDAttribute, m.sent),
+			trace.Int64Attribute(messageSentSizeAttribute, size),
+		}
+	} else {
+		m.received++
+		attrs = []trace.Attribute{
+			trace.Int64Attribute(messageReceivedIDAttribute, m.received),
+			trace.Int64Attribute(messageReceivedSizeAttribute, size),
+		}
+	}
+	if !m.streaming || !parent.SpanContext().IsSampled() {
+		return
+	}
+	if sent == client {
+		// A request starts a new message span.
+		if m.pending != nil {
+			m.pending.End()
+		}
+		_, m.pending = trace.StartSpan(ctx, messageSpanName, trace.WithSampler(trace.AlwaysSample()))
+		m.pending.AddAttributes(attrs...)
+		return
+	}
+	span := m.pending
+	m.pending = nil
+	if span == nil {
+		_, span = trace.StartSpan(ctx, messageSpanName, trace.WithSampler(trace.AlwaysSample()))
+	}
+	span.AddAttributes(attrs...)
+	span.End()
+}
+
+// end ends the span of the last request, if it wasn't answered.
+func (m *messageSpans) end() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.pending != nil {
+		m.pending.End()
+		m.pending = nil
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"context"
+	"strings"
+
+	"go.opencensus.io/trace"
+	"google.golang.org/grpc/metadata"
+)
+
+// metadataAttributePrefix prefixes the name of the server span attributes
+// recording the incoming metadata listed in ServerHandler.MetadataAttributes.
+const metadataAttributePrefix = "rpc.grpc.request.metadata."
+
+// DefaultMetadataAttributeMaxLength is the length metadata attribute values
+// are truncated to when ServerHandler.MetadataAttributeMaxLength isn't set.
+const DefaultMetadataAttributeMaxLength = 256
+
+// MetadataAttributeRedactor returns the value of the metadata attribute of
+// key to record instead of value, such as a masked or hashed value. ok is
+// false when the attribute shouldn't be recorded.
+type MetadataAttributeRedactor func(key, value string) (v string, ok bool)
+
+// metadataTagRPC records the incoming metadata listed in s.MetadataAttributes
+// on the span of the RPC of ctx. Multiple values are joined with commas.
+func (s *ServerHandler) metadataTagRPC(ctx context.Context) {
+	if len(s.MetadataAttributes) == 0 {
+		return
+	}
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return
+	}
+	maxLength := s.MetadataAttributeMaxLength
+	if maxLength <= 0 {
+		maxLength = DefaultMetadataAttributeMaxLength
+	}
+	var attrs []trace.Attribute
+	for _, key := range s.MetadataAttributes {
+		key = strings.ToLower(key)
+		if strings.HasSuffix(key, "-bin") {
+			continue
+		}
+		vs := md.Get(key)
+		if len(vs) == 0 {
+			continue
+		}
+		v := strings.Join(vs, ",")
+		if s.RedactMetadataAttribute != nil {
+			if v, ok = s.RedactMetadataAttribute(key, v); !ok {
+				continue
+			}
+		}
+		if len(v) > maxLength {
+			v = v[:maxLength]
+		}
+		attrs = append(attrs, trace.StringAttribute(metadataAttributePrefix+key, v))
+	}
+	trace.FromContext(ctx).AddAttributes(attrs...)
+}
@@ -0,0 +1,66 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc/stats"
+)
+
+// multiHandler fans the calls of gRPC out to several stats.Handlers.
+type multiHandler []stats.Handler
+
+// MultiHandler returns a stats.Handler calling each of handlers in order, so
+// a ServerHandler or ClientHandler can be installed along other handlers,
+// such as one feeding a rate limiter.
+//
+// TagRPC and TagConn call each handler with the context returned by the
+// previous one, and return the context returned by the last one: handlers
+// must derive the context they return from the one they are given. HandleRPC
+// and HandleConn call each handler with that final context.
+func MultiHandler(handlers ...stats.Handler) stats.Handler {
+	return multiHandler(handlers)
+}
+
+// TagRPC implements stats.Handler.
+func (m multiHandler) TagRPC(ctx context.Context, rti *stats.RPCTagInfo) context.Context {
+	for _, h := range m {
+		ctx = h.TagRPC(ctx, rti)
+	}
+	return ctx
+}
+
+// HandleRPC implements stats.Handler.
+func (m multiHandler) HandleRPC(ctx context.Context, rs stats.RPCStats) {
+	for _, h := range m {
+		h.HandleRPC(ctx, rs)
+	}
+}
+
+// TagConn implements stats.Handler.
+func (m multiHandler) TagConn(ctx context.Context, cti *stats.ConnTagInfo) context.Context {
+	for _, h := range m {
+		ctx = h.TagConn(ctx, cti)
+	}
+	return ctx
+}
+
+// HandleConn implements stats.Handler.
+func (m multiHandler) HandleConn(ctx context.Context, cs stats.ConnStats) {
+	for _, h := range m {
+		h.HandleConn(ctx, cs)
+	}
+}
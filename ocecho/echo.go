@@ -0,0 +1,95 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ocecho provides an Echo middleware tracing HTTP requests with the
+// header formats understood by the ocgrpc handlers.
+package ocecho
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/akhenakh/ocgrpc_propagation/propagation"
+	"github.com/labstack/echo/v4"
+	"go.opencensus.io/plugin/ochttp"
+	"go.opencensus.io/trace"
+)
+
+// Options configures the middleware returned by Middleware.
+type Options struct {
+	// IsPublicEndpoint has the same meaning as ocgrpc.ServerHandler.IsPublicEndpoint.
+	IsPublicEndpoint bool
+
+	// StartOptions to use for spans started around requests.
+	//
+	// StartOptions.SpanKind will always be set to trace.SpanKindServer
+	// for spans started by this middleware.
+	StartOptions trace.StartOptions
+
+	// Propagators are the formats the trace headers are read in, in order,
+	// such as the Propagators of the ocgrpc.ServerHandler of the service.
+	// If nil, those of propagation.FromHTTPHeader are used.
+	Propagators []propagation.Propagator
+}
+
+// Middleware returns an echo.MiddlewareFunc starting a server span around each
+// request. The span is named after the matched route, or
+// propagation.UnmatchedRouteName when none matched, and the incoming Jaeger
+// header is forwarded to the gRPC calls made while handling the request. On
+// public endpoints, the span is forwarded instead of the untrusted header.
+// The error returned by the next handler is returned as is, for the outer
+// middlewares and the HTTP error handler of Echo; the span records the status
+// it maps to.
+func Middleware(o Options) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			name := c.Path()
+			if name == "" {
+				name = propagation.UnmatchedRouteName
+			}
+			ctx, span := propagation.StartHTTPServerSpanWith(req, name, o.IsPublicEndpoint, o.StartOptions, o.Propagators)
+			defer span.End()
+			span.AddAttributes(
+				trace.StringAttribute(ochttp.MethodAttribute, req.Method),
+				trace.StringAttribute(ochttp.PathAttribute, req.URL.Path))
+
+			if o.IsPublicEndpoint {
+				ctx = propagation.ForwardSpanContext(ctx, span.SpanContext())
+			} else {
+				ctx = propagation.ForwardHTTPHeader(ctx, req.Header)
+			}
+			c.SetRequest(req.WithContext(ctx))
+			err := next(c)
+
+			code := statusCode(c, err)
+			span.AddAttributes(trace.Int64Attribute(ochttp.StatusCodeAttribute, int64(code)))
+			span.SetStatus(ochttp.TraceStatus(code, http.StatusText(code)))
+			return err
+		}
+	}
+}
+
+// statusCode returns the status of the response to c, or, if err wasn't
+// written yet, the status the HTTP error handler of Echo writes for it.
+func statusCode(c echo.Context, err error) int {
+	if err == nil || c.Response().Committed {
+		return c.Response().Status
+	}
+	var he *echo.HTTPError
+	if errors.As(err, &he) {
+		return he.Code
+	}
+	return http.StatusInternalServerError
+}
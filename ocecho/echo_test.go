@@ -0,0 +1,96 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocecho
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/akhenakh/ocgrpc_propagation/propagation"
+	"github.com/akhenakh/ocgrpc_propagation/propagationtest"
+	"github.com/labstack/echo/v4"
+	"go.opencensus.io/plugin/ochttp"
+	"go.opencensus.io/trace"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestMiddlewareReturnsErrors(t *testing.T) {
+	e := propagationtest.Install(t)
+	for _, tc := range []struct {
+		err  error
+		code int
+	}{
+		{nil, http.StatusOK},
+		{echo.NewHTTPError(http.StatusNotFound), http.StatusNotFound},
+		{errors.New("failed"), http.StatusInternalServerError},
+	} {
+		e.Reset()
+		var returned error
+		outer := func(next echo.HandlerFunc) echo.HandlerFunc {
+			return func(c echo.Context) error {
+				returned = next(c)
+				return returned
+			}
+		}
+		ec := echo.New()
+		ec.Use(outer, Middleware(Options{StartOptions: trace.StartOptions{Sampler: trace.AlwaysSample()}}))
+		ec.GET("/", func(c echo.Context) error {
+			if tc.err != nil {
+				return tc.err
+			}
+			return c.NoContent(http.StatusOK)
+		})
+		rec := httptest.NewRecorder()
+		ec.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		if returned != tc.err {
+			t.Errorf("error %v returned to the outer middleware, want %v", returned, tc.err)
+		}
+		if rec.Code != tc.code {
+			t.Errorf("response status %d, want %d", rec.Code, tc.code)
+		}
+		spans := e.Spans()
+		if len(spans) != 1 || spans[0].Attributes[ochttp.StatusCodeAttribute] != int64(tc.code) {
+			t.Errorf("got spans %v, want one with status %d", spans, tc.code)
+		}
+	}
+}
+
+func TestMiddlewarePublicEndpointForwarding(t *testing.T) {
+	propagationtest.Install(t)
+	incoming := trace.SpanContext{TraceID: trace.TraceID{15: 1}, SpanID: trace.SpanID{7: 1}, TraceOptions: 1}
+	jv, _ := propagation.JaegerOptions{}.Format(incoming)
+
+	var forwarded []string
+	var span trace.SpanContext
+	ec := echo.New()
+	ec.Use(Middleware(Options{IsPublicEndpoint: true}))
+	ec.GET("/", func(c echo.Context) error {
+		md, _ := metadata.FromOutgoingContext(c.Request().Context())
+		forwarded = md.Get(propagation.JaegerKey)
+		span = trace.FromContext(c.Request().Context()).SpanContext()
+		return nil
+	})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(propagation.JaegerKey, jv)
+	ec.ServeHTTP(httptest.NewRecorder(), req)
+
+	want, _ := propagation.JaegerOptions{}.Format(span)
+	if len(forwarded) != 1 || forwarded[0] != want {
+		t.Errorf("forwarded %q, want the request span %q", forwarded, want)
+	}
+}
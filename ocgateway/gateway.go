@@ -0,0 +1,76 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ocgateway carries HTTP trace headers across the grpc-gateway hop,
+// so traces started by HTTP clients continue in the gRPC backends.
+package ocgateway
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/akhenakh/ocgrpc_propagation/propagation"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/metadata"
+)
+
+// TraceHeaders are the HTTP headers copied to gRPC metadata by Annotator, in
+// addition to the Jaeger baggage headers.
+var TraceHeaders = []string{
+	propagation.JaegerKey,
+	propagation.JaegerDebugIDKey,
+	propagation.TraceparentKey,
+	propagation.TracestateKey,
+	propagation.BaggageKey,
+	propagation.B3Key,
+	propagation.B3TraceIDKey,
+	propagation.B3SpanIDKey,
+	propagation.B3ParentSpanIDKey,
+	propagation.B3SampledKey,
+	propagation.B3FlagsKey,
+	propagation.XRayKey,
+	propagation.CloudTraceKey,
+	propagation.GRPCWebKey,
+	propagation.OTSpanContextKey,
+}
+
+// Annotator returns the trace headers of r as gRPC metadata, under the keys
+// the ocgrpc ServerHandler extracts. It has the signature expected by
+// runtime.WithMetadata.
+//
+// When the gateway traces its own calls with an ocgrpc ClientHandler, the
+// handler overwrites the copied trace context with the span of the gateway,
+// which is a child of the incoming one if the gateway is traced by ochttp or
+// an HTTP middleware.
+func Annotator(_ context.Context, r *http.Request) metadata.MD {
+	md := metadata.MD{}
+	for _, k := range TraceHeaders {
+		if vs := r.Header.Values(k); len(vs) > 0 {
+			md.Set(k, vs...)
+		}
+	}
+	for k, vs := range r.Header {
+		if k = strings.ToLower(k); strings.HasPrefix(k, propagation.JaegerBaggagePrefix) {
+			md.Set(k, vs...)
+		}
+	}
+	return md
+}
+
+// ServeMuxOption returns a runtime.ServeMuxOption installing Annotator on a
+// grpc-gateway runtime.ServeMux.
+func ServeMuxOption() runtime.ServeMuxOption {
+	return runtime.WithMetadata(Annotator)
+}
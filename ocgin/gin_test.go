@@ -0,0 +1,97 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/akhenakh/ocgrpc_propagation/propagation"
+	"github.com/akhenakh/ocgrpc_propagation/propagationtest"
+	"github.com/gin-gonic/gin"
+	"go.opencensus.io/trace"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestMiddlewareSpanNames(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	e := propagationtest.Install(t)
+	r := gin.New()
+	r.Use(Middleware(Options{StartOptions: trace.StartOptions{Sampler: trace.AlwaysSample()}}))
+	r.GET("/users/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for _, tc := range []struct {
+		path string
+		name string
+	}{
+		{"/users/42", "/users/:id"},
+		{"/wp-admin/setup.php", propagation.UnmatchedRouteName},
+	} {
+		e.Reset()
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tc.path, nil))
+		spans := e.Spans()
+		if len(spans) != 1 || spans[0].Name != tc.name {
+			t.Errorf("GET %s: got spans %v, want one named %q", tc.path, spans, tc.name)
+		}
+	}
+}
+
+func TestMiddlewarePropagators(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	e := propagationtest.Install(t)
+	parent := trace.SpanContext{TraceID: trace.TraceID{15: 1}, SpanID: trace.SpanID{7: 1}, TraceOptions: 1}
+	r := gin.New()
+	r.Use(Middleware(Options{Propagators: []propagation.Propagator{propagation.B3SinglePropagator{}}}))
+	r.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(propagation.B3Key, propagation.B3Single(parent))
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	spans := e.Spans()
+	if len(spans) != 1 || spans[0].TraceID != parent.TraceID || spans[0].ParentSpanID != parent.SpanID {
+		t.Errorf("got spans %v, want one child of %v", spans, parent)
+	}
+}
+
+func TestMiddlewareForwarding(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	propagationtest.Install(t)
+	incoming := trace.SpanContext{TraceID: trace.TraceID{15: 1}, SpanID: trace.SpanID{7: 1}, TraceOptions: 1}
+	jv, _ := propagation.JaegerOptions{}.Format(incoming)
+
+	for _, public := range []bool{false, true} {
+		var forwarded []string
+		var span trace.SpanContext
+		r := gin.New()
+		r.Use(Middleware(Options{IsPublicEndpoint: public}))
+		r.GET("/", func(c *gin.Context) {
+			md, _ := metadata.FromOutgoingContext(c.Request.Context())
+			forwarded = md.Get(propagation.JaegerKey)
+			span = trace.FromContext(c.Request.Context()).SpanContext()
+		})
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(propagation.JaegerKey, jv)
+		r.ServeHTTP(httptest.NewRecorder(), req)
+
+		want := jv
+		if public {
+			want, _ = propagation.JaegerOptions{}.Format(span)
+		}
+		if len(forwarded) != 1 || forwarded[0] != want {
+			t.Errorf("IsPublicEndpoint %v: forwarded %q, want %q", public, forwarded, want)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ocgrpcfx provides an uber/fx module wiring the ocgrpc handlers and
+// interceptors from an ocgrpc.Config.
+//
+// The application is expected to supply the configuration:
+//
+//	fx.New(
+//	  fx.Supply(ocgrpc.Config{IsPublicEndpoint: true}),
+//	  ocgrpcfx.Module,
+//	)
+//
+// The resulting grpc.ServerOption and grpc.DialOption values are provided in
+// the "grpc.server_options" and "grpc.dial_options" value groups.
+package ocgrpcfx
+
+import (
+	ocgrpc "github.com/akhenakh/ocgrpc_propagation"
+	"go.uber.org/fx"
+	"google.golang.org/grpc"
+)
+
+// Module provides *ocgrpc.ServerHandler, *ocgrpc.ClientHandler and the
+// grpc.ServerOption and grpc.DialOption groups built from an ocgrpc.Config.
+var Module = fx.Module("ocgrpc",
+	fx.Provide(
+		NewServerHandler,
+		NewClientHandler,
+		NewServerOptions,
+		NewDialOptions,
+	),
+)
+
+// ServerOptionsResult adds the server options built from the configuration to
+// the "grpc.server_options" value group.
+type ServerOptionsResult struct {
+	fx.Out
+
+	Options []grpc.ServerOption `group:"grpc.server_options,flatten"`
+}
+
+// DialOptionsResult adds the dial options built from the configuration to the
+// "grpc.dial_options" value group.
+type DialOptionsResult struct {
+	fx.Out
+
+	Options []grpc.DialOption `group:"grpc.dial_options,flatten"`
+}
+
+// NewServerHandler returns the ServerHandler configured by cfg.
+func NewServerHandler(cfg ocgrpc.Config) *ocgrpc.ServerHandler {
+	return cfg.ServerHandler()
+}
+
+// NewClientHandler returns the ClientHandler configured by cfg.
+func NewClientHandler(cfg ocgrpc.Config) *ocgrpc.ClientHandler {
+	return cfg.ClientHandler()
+}
+
+// NewServerOptions returns the server options configured by cfg, installing
+// the ServerHandler h provided by the module.
+func NewServerOptions(cfg ocgrpc.Config, h *ocgrpc.ServerHandler) ServerOptionsResult {
+	return ServerOptionsResult{Options: cfg.ServerOptionsFor(h)}
+}
+
+// NewDialOptions returns the dial options configured by cfg, installing the
+// ClientHandler h provided by the module.
+func NewDialOptions(cfg ocgrpc.Config, h *ocgrpc.ClientHandler) DialOptionsResult {
+	return DialOptionsResult{Options: cfg.DialOptionsFor(h)}
+}
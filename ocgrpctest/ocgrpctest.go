@@ -0,0 +1,131 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ocgrpctest helps writing integration tests of the trace propagation
+// of services instrumented with the ocgrpc package: it runs a gRPC server and
+// a client wired with the handlers in memory, records the spans they export,
+// and checks how the spans relate.
+package ocgrpctest
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	ocgrpc "github.com/akhenakh/ocgrpc_propagation"
+	"github.com/akhenakh/ocgrpc_propagation/propagationtest"
+	"go.opencensus.io/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// bufSize is the size of the in-memory connection buffers.
+const bufSize = 1 << 20
+
+// Pair is a gRPC server and a client connection to it, over an in-memory
+// listener, instrumented with the handlers and interceptors of an
+// ocgrpc.Config.
+type Pair struct {
+	// Server is the gRPC server, already serving.
+	Server *grpc.Server
+
+	// Conn is the client connection to Server.
+	Conn *grpc.ClientConn
+
+	// Spans records the spans exported during the test.
+	Spans *propagationtest.Exporter
+}
+
+// NewPair starts a server and connects a client to it, both instrumented with
+// cfg. register registers the services of the server; if nil, the server only
+// serves the health checking service, see Pair.HealthClient. Unless
+// cfg.DefaultSampler is set, every span is sampled so it is recorded. The
+// server and connection are closed when t ends.
+func NewPair(t testing.TB, cfg ocgrpc.Config, register func(*grpc.Server)) *Pair {
+	t.Helper()
+	if cfg.DefaultSampler == nil {
+		cfg.DefaultSampler = trace.AlwaysSample()
+	}
+	p := &Pair{Spans: propagationtest.Install(t)}
+
+	lis := bufconn.Listen(bufSize)
+	p.Server = grpc.NewServer(cfg.ServerOptions()...)
+	if register == nil {
+		register = func(s *grpc.Server) { healthpb.RegisterHealthServer(s, health.NewServer()) }
+	}
+	register(p.Server)
+	go p.Server.Serve(lis)
+	t.Cleanup(p.Server.Stop)
+
+	opts := append(cfg.DialOptions(),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}))
+	conn, err := grpc.Dial("bufconn", opts...)
+	if err != nil {
+		t.Fatalf("ocgrpctest: dialing the in-memory server: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	p.Conn = conn
+	return p
+}
+
+// HealthClient returns a client of the health checking service served when
+// NewPair is given no register function, the simplest RPC to exercise the
+// handlers with.
+func (p *Pair) HealthClient() healthpb.HealthClient {
+	return healthpb.NewHealthClient(p.Conn)
+}
+
+// SpansNamed returns the recorded spans named name, such as
+// "grpc.health.v1.Health.Check" for both the client and server spans of a
+// health check.
+func (p *Pair) SpansNamed(name string) []*trace.SpanData {
+	var spans []*trace.SpanData
+	for _, s := range p.Spans.Spans() {
+		if s.Name == name {
+			spans = append(spans, s)
+		}
+	}
+	return spans
+}
+
+// RequireSameTrace stops the test t unless spans, of which there must be at
+// least one, all belong to the same trace, as the client and server spans of
+// a propagated RPC do.
+func RequireSameTrace(t testing.TB, spans ...*trace.SpanData) {
+	t.Helper()
+	if len(spans) == 0 {
+		t.Fatal("ocgrpctest: no spans")
+	}
+	for _, s := range spans[1:] {
+		if s.TraceID != spans[0].TraceID {
+			t.Fatalf("span %q is in trace %v, span %q in trace %v", s.Name, s.TraceID, spans[0].Name, spans[0].TraceID)
+		}
+	}
+}
+
+// RequireChildOf stops the test t unless child is a child of parent, as the
+// server span of an RPC is of its client span.
+func RequireChildOf(t testing.TB, parent, child *trace.SpanData) {
+	t.Helper()
+	RequireSameTrace(t, parent, child)
+	if child.ParentSpanID != parent.SpanID {
+		t.Fatalf("span %q has parent %v, want %q (%v)", child.Name, child.ParentSpanID, parent.Name, parent.SpanID)
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpctest_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	ocgrpc "github.com/akhenakh/ocgrpc_propagation"
+	"github.com/akhenakh/ocgrpc_propagation/ocgrpctest"
+	propag "github.com/akhenakh/ocgrpc_propagation/propagation"
+	"go.opencensus.io/trace"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const checkSpanName = "grpc.health.v1.Health.Check"
+
+// check runs a health check and returns its client and server spans. The
+// server span ends once the response is sent, so it is waited for.
+func check(ctx context.Context, t *testing.T, p *ocgrpctest.Pair) (client, server *trace.SpanData) {
+	t.Helper()
+	if _, err := p.HealthClient().Check(ctx, &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatalf("Check() failed: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		for _, s := range p.SpansNamed(checkSpanName) {
+			switch s.SpanKind {
+			case trace.SpanKindClient:
+				client = s
+			case trace.SpanKindServer:
+				server = s
+			}
+		}
+		if client != nil && server != nil {
+			return client, server
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("spans %q = %v, want a client and a server span", checkSpanName, p.SpansNamed(checkSpanName))
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestPair(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		cfg  ocgrpc.Config
+	}{
+		{"default", ocgrpc.Config{}},
+		{"jaeger", ocgrpc.Config{PropagateJaeger: true}},
+		{"w3c", ocgrpc.Config{Propagators: []propag.Propagator{propag.TraceContextPropagator{}}}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			p := ocgrpctest.NewPair(t, tc.cfg, nil)
+			client, server := check(context.Background(), t, p)
+			ocgrpctest.RequireChildOf(t, client, server)
+		})
+	}
+}
+
+func TestPairContinuesTheCallerTrace(t *testing.T) {
+	p := ocgrpctest.NewPair(t, ocgrpc.Config{}, nil)
+	ctx, span := trace.StartSpan(context.Background(), "caller", trace.WithSampler(trace.AlwaysSample()))
+	client, server := check(ctx, t, p)
+	span.End()
+
+	caller := p.SpansNamed("caller")
+	if len(caller) != 1 {
+		t.Fatalf("spans %q = %v, want 1", "caller", caller)
+	}
+	ocgrpctest.RequireChildOf(t, caller[0], client)
+	ocgrpctest.RequireSameTrace(t, caller[0], client, server)
+}
@@ -0,0 +1,70 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ocgrpcwire provides google/wire provider sets building the ocgrpc
+// handlers and interceptors from an ocgrpc.Config.
+//
+// The injector is expected to provide the configuration:
+//
+//	wire.Build(ocgrpcwire.ProviderSet, loadConfig, newServer)
+package ocgrpcwire
+
+import (
+	ocgrpc "github.com/akhenakh/ocgrpc_propagation"
+	"github.com/google/wire"
+	"google.golang.org/grpc"
+)
+
+// ProviderSet provides *ocgrpc.ServerHandler, *ocgrpc.ClientHandler,
+// []grpc.ServerOption and []grpc.DialOption from an ocgrpc.Config.
+var ProviderSet = wire.NewSet(
+	ServerSet,
+	ClientSet,
+)
+
+// ServerSet provides *ocgrpc.ServerHandler and []grpc.ServerOption from an
+// ocgrpc.Config.
+var ServerSet = wire.NewSet(
+	ProvideServerHandler,
+	ProvideServerOptions,
+)
+
+// ClientSet provides *ocgrpc.ClientHandler and []grpc.DialOption from an
+// ocgrpc.Config.
+var ClientSet = wire.NewSet(
+	ProvideClientHandler,
+	ProvideDialOptions,
+)
+
+// ProvideServerHandler returns the ServerHandler configured by cfg.
+func ProvideServerHandler(cfg ocgrpc.Config) *ocgrpc.ServerHandler {
+	return cfg.ServerHandler()
+}
+
+// ProvideClientHandler returns the ClientHandler configured by cfg.
+func ProvideClientHandler(cfg ocgrpc.Config) *ocgrpc.ClientHandler {
+	return cfg.ClientHandler()
+}
+
+// ProvideServerOptions returns the server options configured by cfg,
+// installing the provided ServerHandler h.
+func ProvideServerOptions(cfg ocgrpc.Config, h *ocgrpc.ServerHandler) []grpc.ServerOption {
+	return cfg.ServerOptionsFor(h)
+}
+
+// ProvideDialOptions returns the dial options configured by cfg, installing
+// the provided ClientHandler h.
+func ProvideDialOptions(cfg ocgrpc.Config, h *ocgrpc.ClientHandler) []grpc.DialOption {
+	return cfg.DialOptionsFor(h)
+}
@@ -0,0 +1,78 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ockafka carries trace contexts in the headers of the Kafka messages
+// of github.com/segmentio/kafka-go, so traces continue from the gRPC handlers
+// publishing events to their consumers.
+package ockafka
+
+import (
+	"context"
+
+	"github.com/akhenakh/ocgrpc_propagation/propagation"
+	"github.com/segmentio/kafka-go"
+	"go.opencensus.io/trace"
+)
+
+// Headers is a propagation.Carrier over the headers of a Kafka message:
+//
+//	propagation.Inject(sc, (*ockafka.Headers)(&msg.Headers))
+type Headers []kafka.Header
+
+// Get implements propagation.Carrier. It returns the value of the first
+// header named key.
+func (h *Headers) Get(key string) string {
+	for _, kh := range *h {
+		if kh.Key == key {
+			return string(kh.Value)
+		}
+	}
+	return ""
+}
+
+// Set implements propagation.Carrier. The first header named key is replaced,
+// and the others removed, so messages that are published again don't carry
+// stale trace contexts.
+func (h *Headers) Set(key, value string) {
+	hs := (*h)[:0]
+	set := false
+	for _, kh := range *h {
+		if kh.Key != key {
+			hs = append(hs, kh)
+		} else if !set {
+			hs = append(hs, kafka.Header{Key: key, Value: []byte(value)})
+			set = true
+		}
+	}
+	if !set {
+		hs = append(hs, kafka.Header{Key: key, Value: []byte(value)})
+	}
+	*h = hs
+}
+
+// Inject writes the SpanContext of the span of ctx to the headers of m, see
+// propagation.Inject. m is left untouched if ctx has no span.
+func Inject(ctx context.Context, m *kafka.Message) {
+	span := trace.FromContext(ctx)
+	if span == nil {
+		return
+	}
+	propagation.Inject(span.SpanContext(), (*Headers)(&m.Headers))
+}
+
+// Extract returns the SpanContext carried by the headers of m, to be used as
+// the remote parent of the span processing it.
+func Extract(m *kafka.Message) (sc trace.SpanContext, ok bool) {
+	return propagation.Extract((*Headers)(&m.Headers))
+}
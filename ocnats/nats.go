@@ -0,0 +1,49 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ocnats carries trace contexts in the headers of the NATS messages
+// of github.com/nats-io/nats.go, so traces continue from the gRPC handlers
+// publishing events to their subscribers.
+package ocnats
+
+import (
+	"context"
+
+	"github.com/akhenakh/ocgrpc_propagation/propagation"
+	"github.com/nats-io/nats.go"
+	"go.opencensus.io/trace"
+)
+
+// Inject writes the SpanContext of the span of ctx to the headers of m, see
+// propagation.Inject. The headers are allocated if m has none; m is left
+// untouched if ctx has no span.
+func Inject(ctx context.Context, m *nats.Msg) {
+	span := trace.FromContext(ctx)
+	if span == nil {
+		return
+	}
+	if m.Header == nil {
+		m.Header = nats.Header{}
+	}
+	propagation.Inject(span.SpanContext(), m.Header)
+}
+
+// Extract returns the SpanContext carried by the headers of m, to be used as
+// the remote parent of the span processing it.
+func Extract(m *nats.Msg) (sc trace.SpanContext, ok bool) {
+	if m.Header == nil {
+		return trace.SpanContext{}, false
+	}
+	return propagation.Extract(m.Header)
+}
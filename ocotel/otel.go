@@ -0,0 +1,115 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ocotel bridges the ocgrpc handlers and OpenTelemetry, so fleets
+// mixing services instrumented with this package and with OpenTelemetry
+// produce single traces. Propagator lets the handlers read and write trace
+// contexts with any OpenTelemetry TextMapPropagator, and FromOTel and ToOTel
+// convert span contexts.
+package ocotel
+
+import (
+	"context"
+
+	propag "github.com/akhenakh/ocgrpc_propagation/propagation"
+	octrace "go.opencensus.io/trace"
+	otelpropagation "go.opentelemetry.io/otel/propagation"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/metadata"
+)
+
+// ToOTel returns the OpenTelemetry span context of sc, marked as remote.
+func ToOTel(sc octrace.SpanContext) oteltrace.SpanContext {
+	config := oteltrace.SpanContextConfig{
+		TraceID: oteltrace.TraceID(sc.TraceID),
+		SpanID:  oteltrace.SpanID(sc.SpanID),
+		Remote:  true,
+	}
+	if sc.IsSampled() {
+		config.TraceFlags = oteltrace.FlagsSampled
+	}
+	if h := propag.Tracestate(sc.Tracestate); h != "" {
+		if ts, err := oteltrace.ParseTraceState(h); err == nil {
+			config.TraceState = ts
+		}
+	}
+	return oteltrace.NewSpanContext(config)
+}
+
+// FromOTel returns the OpenCensus span context of sc.
+func FromOTel(sc oteltrace.SpanContext) octrace.SpanContext {
+	oc := octrace.SpanContext{
+		TraceID: octrace.TraceID(sc.TraceID()),
+		SpanID:  octrace.SpanID(sc.SpanID()),
+	}
+	if sc.IsSampled() {
+		oc.TraceOptions = 1
+	}
+	if h := sc.TraceState().String(); h != "" {
+		oc.Tracestate = propag.FromTracestate([]string{h})
+	}
+	return oc
+}
+
+// Propagator is a propagation.Propagator reading and writing trace contexts
+// with an OpenTelemetry TextMapPropagator, such as
+// propagation.TraceContext{} or the propagators of the OpenTelemetry
+// contrib repository, to be set in the Propagators of the handlers.
+type Propagator struct {
+	TextMapPropagator otelpropagation.TextMapPropagator
+}
+
+// Name returns "otel".
+func (Propagator) Name() string { return "otel" }
+
+// Extract implements propagation.Propagator.
+func (p Propagator) Extract(md metadata.MD) (sc octrace.SpanContext, ok bool) {
+	ctx := p.TextMapPropagator.Extract(context.Background(), metadataCarrier(md))
+	otelSC := oteltrace.SpanContextFromContext(ctx)
+	if !otelSC.IsValid() {
+		return sc, false
+	}
+	return FromOTel(otelSC), true
+}
+
+// Inject implements propagation.Propagator.
+func (p Propagator) Inject(sc octrace.SpanContext, md metadata.MD) {
+	ctx := oteltrace.ContextWithRemoteSpanContext(context.Background(), ToOTel(sc))
+	p.TextMapPropagator.Inject(ctx, metadataCarrier(md))
+}
+
+// metadataCarrier adapts gRPC metadata to an OpenTelemetry TextMapCarrier.
+type metadataCarrier metadata.MD
+
+// Get returns the first value of key.
+func (c metadataCarrier) Get(key string) string {
+	if vs := propag.Lookup(metadata.MD(c), key); len(vs) > 0 {
+		return vs[0]
+	}
+	return ""
+}
+
+// Set sets the value of key.
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+// Keys returns the keys of the metadata.
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
@@ -0,0 +1,147 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	propag "github.com/akhenakh/ocgrpc_propagation/propagation"
+	"go.opencensus.io/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/stats"
+)
+
+// Option configures the handlers returned by NewServerHandler and
+// NewClientHandler, by setting the fields of the Config they are built from.
+type Option func(c *Config)
+
+// NewServerHandler returns a ServerHandler configured by opts. Its settings
+// are fixed when it is built, so they needn't be set through the fields of a
+// handler that may already be registered.
+func NewServerHandler(opts ...Option) *ServerHandler {
+	return newConfig(opts).ServerHandler()
+}
+
+// NewClientHandler returns a ClientHandler configured by opts, see
+// NewServerHandler.
+func NewClientHandler(opts ...Option) *ClientHandler {
+	return newConfig(opts).ClientHandler()
+}
+
+// ServerOption returns the grpc.ServerOptions installing a ServerHandler
+// configured by opts along the server interceptors its settings require, see
+// Config.ServerOptions, so the wiring of a server is a single line.
+func ServerOption(opts ...Option) []grpc.ServerOption {
+	return newConfig(opts).ServerOptions()
+}
+
+// DialOption returns the grpc.DialOptions installing a ClientHandler
+// configured by opts along the client interceptors its settings require, see
+// Config.DialOptions.
+func DialOption(opts ...Option) []grpc.DialOption {
+	return newConfig(opts).DialOptions()
+}
+
+func newConfig(opts []Option) Config {
+	var c Config
+	for _, o := range opts {
+		o(&c)
+	}
+	return c
+}
+
+// WithConfig sets every field of the Config the handlers are built from, for
+// the settings without a dedicated Option. It should come first, since it
+// overrides the Options before it.
+func WithConfig(config Config) Option {
+	return func(c *Config) { *c = config }
+}
+
+// WithSampler sets the Sampler of the StartOptions of the handlers, which
+// takes precedence over their DefaultSampler.
+func WithSampler(sampler trace.Sampler) Option {
+	return func(c *Config) {
+		c.ServerStartOptions.Sampler = sampler
+		c.ClientStartOptions.Sampler = sampler
+	}
+}
+
+// WithDefaultSampler sets the DefaultSampler of the handlers, used when no
+// sampler is set by WithSampler. Handlers built without either log a warning
+// the first time the global default sampler applies.
+func WithDefaultSampler(sampler trace.Sampler) Option {
+	return func(c *Config) { c.DefaultSampler = sampler }
+}
+
+// WithPropagators sets the Propagators of the handlers.
+func WithPropagators(propagators ...propag.Propagator) Option {
+	return func(c *Config) { c.Propagators = propagators }
+}
+
+// WithPublicEndpoint sets ServerHandler.IsPublicEndpoint.
+func WithPublicEndpoint(public bool) Option {
+	return func(c *Config) { c.IsPublicEndpoint = public }
+}
+
+// WithNameFormatter sets the FormatSpanName of the handlers.
+func WithNameFormatter(format func(rti *stats.RPCTagInfo) string) Option {
+	return func(c *Config) { c.FormatSpanName = format }
+}
+
+// WithFilter sets the Filter of the handlers.
+func WithFilter(filter FilterFunc) Option {
+	return func(c *Config) { c.Filter = filter }
+}
+
+// WithoutHealthAndReflection adds FilterHealthAndReflection to the Filter of
+// the handlers, so health checks and reflection RPCs get no span and no
+// stats. It keeps the Filter set by the Options before it, WithFilter after
+// it replaces both.
+func WithoutHealthAndReflection() Option {
+	return func(c *Config) { c.Filter = anyFilter(c.Filter, FilterHealthAndReflection) }
+}
+
+// WithLogger sets the Logger of the handlers.
+func WithLogger(l Logger) Option {
+	return func(c *Config) { c.Logger = l }
+}
+
+// WithSpanStartHook sets the OnSpanStart of the handlers, adding attributes
+// to the span of every RPC.
+func WithSpanStartHook(hook SpanStartHook) Option {
+	return func(c *Config) { c.OnSpanStart = hook }
+}
+
+// WithMessageEvents sets whether the handlers add a message event to their
+// spans for every message sent or received.
+func WithMessageEvents(enabled bool) Option {
+	return func(c *Config) { c.DisableMessageEvents = !enabled }
+}
+
+// WithTracingDisabled sets the DisableTracing of the handlers, so they only
+// record stats.
+func WithTracingDisabled() Option {
+	return func(c *Config) { c.DisableTracing = true }
+}
+
+// WithStatsDisabled sets the DisableStats of the handlers, so they only trace
+// RPCs.
+func WithStatsDisabled() Option {
+	return func(c *Config) { c.DisableStats = true }
+}
+
+// WithMetadataAttributes sets ServerHandler.MetadataAttributes, recording the
+// values of these incoming metadata keys on server spans.
+func WithMetadataAttributes(keys ...string) Option {
+	return func(c *Config) { c.MetadataAttributes = keys }
+}
@@ -0,0 +1,86 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"go.opencensus.io/trace"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// DefaultPayloadMaxLength is the length serialized messages are truncated to
+// when PayloadAnnotations.MaxLength isn't set.
+const DefaultPayloadMaxLength = 1024
+
+// PayloadAnnotations configures the annotation of sampled spans with the
+// messages sent and received by RPCs, to see the actual arguments of a
+// failing RPC. Messages may hold personal data or secrets: it is meant for
+// debugging only.
+type PayloadAnnotations struct {
+	// MaxLength is the length serialized messages are truncated to. It
+	// defaults to DefaultPayloadMaxLength.
+	MaxLength int
+
+	// Filter may be set to only annotate spans with the messages for which
+	// it returns true, such as messages of some types. If nil, every
+	// message is annotated.
+	Filter func(msg interface{}) bool
+}
+
+// annotate annotates span with msg, serialized as JSON if it is a protocol
+// buffer message. Spans that aren't sampled aren't annotated.
+func (p *PayloadAnnotations) annotate(span *trace.Span, msg interface{}, message string) {
+	if p == nil || msg == nil || !span.IsRecordingEvents() {
+		return
+	}
+	if p.Filter != nil && !p.Filter(msg) {
+		return
+	}
+	var payload, typ string
+	if m, ok := msg.(proto.Message); ok {
+		b, err := protojson.Marshal(m)
+		if err != nil {
+			return
+		}
+		payload = string(b)
+		typ = string(m.ProtoReflect().Descriptor().FullName())
+	} else {
+		payload = fmt.Sprintf("%+v", msg)
+		typ = fmt.Sprintf("%T", msg)
+	}
+	maxLength := p.MaxLength
+	if maxLength <= 0 {
+		maxLength = DefaultPayloadMaxLength
+	}
+	span.Annotate([]trace.Attribute{
+		trace.StringAttribute("message_type", typ),
+		trace.StringAttribute("payload", truncateUTF8(payload, maxLength)),
+	}, message)
+}
+
+// truncateUTF8 returns the longest prefix of s no longer than n bytes that
+// doesn't split a rune.
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
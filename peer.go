@@ -0,0 +1,94 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+)
+
+// peerAddressAttribute is the server span attribute recording the address of
+// the caller, according to ServerHandler.PeerAddress.
+const peerAddressAttribute = "net.peer.ip"
+
+// PeerAddressPolicy controls whether and how the IP address of callers is
+// recorded on server spans. Peer addresses are never used as metric tags.
+type PeerAddressPolicy int
+
+const (
+	// OmitPeerAddress doesn't record the peer address.
+	OmitPeerAddress PeerAddressPolicy = iota
+
+	// RecordPeerAddress records the peer address as is.
+	RecordPeerAddress
+
+	// MaskPeerAddress records the network of the peer address: its last
+	// IPv4 byte, or its last 80 IPv6 bits, are zeroed.
+	MaskPeerAddress
+
+	// HashPeerAddress records the HMAC-SHA256 of the peer address keyed
+	// with ServerHandler.PeerAddressHashKey, so the RPCs of a caller can be
+	// correlated without storing its address. Without a secret key, IPv4
+	// addresses are easily recovered from their hashes.
+	HashPeerAddress
+)
+
+// peerAddressOptions holds the ServerHandler settings of the peer address
+// attribute.
+type peerAddressOptions struct {
+	policy  PeerAddressPolicy
+	hashKey []byte
+}
+
+// format returns the value of the peer address attribute for addr. ok is
+// false when nothing should be recorded.
+func (o peerAddressOptions) format(addr net.Addr) (v string, ok bool) {
+	if o.policy == OmitPeerAddress || addr == nil {
+		return "", false
+	}
+	ip := peerIP(addr)
+	if ip == nil {
+		return "", false
+	}
+	switch o.policy {
+	case MaskPeerAddress:
+		if ip4 := ip.To4(); ip4 != nil {
+			return ip4.Mask(net.CIDRMask(24, 32)).String(), true
+		}
+		return ip.Mask(net.CIDRMask(48, 128)).String(), true
+	case HashPeerAddress:
+		mac := hmac.New(sha256.New, o.hashKey)
+		mac.Write([]byte(ip.String()))
+		return hex.EncodeToString(mac.Sum(nil)), true
+	}
+	return ip.String(), true
+}
+
+// peerIP returns the IP of addr, or nil if it isn't an IP address.
+func peerIP(addr net.Addr) net.IP {
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		return a.IP
+	case *net.UDPAddr:
+		return a.IP
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return nil
+	}
+	return net.ParseIP(host)
+}
@@ -0,0 +1,80 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"context"
+
+	"go.opencensus.io/tag"
+	"go.opencensus.io/trace"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// principalAttribute is the server span attribute recording the principal
+// returned by ServerHandler.Principal.
+const principalAttribute = "enduser.id"
+
+// PrincipalFunc returns the authenticated principal of the RPC of ctx, such as
+// the subject of a JWT, the SAN of a client certificate or the ID of an API
+// key. ok is false when the RPC isn't authenticated.
+//
+// It is called when the RPC starts, before any interceptor: ctx carries the
+// incoming metadata and the peer of the RPC.
+type PrincipalFunc func(ctx context.Context) (principal string, ok bool)
+
+// TLSPrincipal is a PrincipalFunc returning the first URI SAN, else the first
+// DNS SAN, else the subject common name of the verified client certificate.
+func TLSPrincipal(ctx context.Context) (string, bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "", false
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 || len(info.State.VerifiedChains[0]) == 0 {
+		return "", false
+	}
+	cert := info.State.VerifiedChains[0][0]
+	switch {
+	case len(cert.URIs) > 0:
+		return cert.URIs[0].String(), true
+	case len(cert.DNSNames) > 0:
+		return cert.DNSNames[0], true
+	case cert.Subject.CommonName != "":
+		return cert.Subject.CommonName, true
+	}
+	return "", false
+}
+
+// principalTagRPC records the principal of the RPC of ctx on its span, and as
+// a tag if s.PrincipalTag is set.
+func (s *ServerHandler) principalTagRPC(ctx context.Context) context.Context {
+	if s.Principal == nil {
+		return ctx
+	}
+	principal, ok := s.Principal(ctx)
+	if !ok {
+		return ctx
+	}
+	trace.FromContext(ctx).AddAttributes(trace.StringAttribute(principalAttribute, principal))
+	if s.PrincipalTag {
+		// Principals that aren't valid tag values are only recorded on the
+		// span.
+		if tctx, err := tag.New(ctx, tag.Upsert(KeyServerPrincipal, principal)); err == nil {
+			ctx = tctx
+		}
+	}
+	return ctx
+}
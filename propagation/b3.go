@@ -0,0 +1,210 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package propagation
+
+import (
+	"encoding/hex"
+	"strings"
+
+	"go.opencensus.io/trace"
+)
+
+// B3 multi-header gRPC metadata keys and HTTP headers.
+const (
+	B3TraceIDKey      = "x-b3-traceid"
+	B3SpanIDKey       = "x-b3-spanid"
+	B3ParentSpanIDKey = "x-b3-parentspanid"
+	B3SampledKey      = "x-b3-sampled"
+	B3FlagsKey        = "x-b3-flags"
+
+	// B3Key is the gRPC metadata key and HTTP header of the B3 single
+	// header format.
+	B3Key = "b3"
+)
+
+// FromB3 parses the values of the B3 multi-header keys. traceID and spanID
+// are required; sampled and flags may be empty.
+func FromB3(traceID, spanID, sampled, flags string) (sc trace.SpanContext, ok bool) {
+	sc, err := ParseB3(traceID, spanID, sampled, flags)
+	return sc, err == nil
+}
+
+// ParseB3 is like FromB3 but reports why the values can't be parsed with a
+// *ParseError.
+//
+// 64-bit trace IDs are stored in the lower 64 bits of the TraceID. The span
+// context is sampled when sampled is "1" or "true", or flags is "1", the
+// debug flag. A missing sampling decision is left to the local sampler.
+func ParseB3(traceID, spanID, sampled, flags string) (sc trace.SpanContext, err error) {
+	if err := decodeB3TraceID(&sc.TraceID, traceID); err != nil {
+		return trace.SpanContext{}, b3Error("trace ID", traceID, err)
+	}
+	if err := decodeB3SpanID(&sc.SpanID, spanID); err != nil {
+		return trace.SpanContext{}, b3Error("span ID", spanID, err)
+	}
+	switch sampled {
+	case "1", "true":
+		sc.TraceOptions = 1
+	case "", "0", "false":
+	default:
+		return trace.SpanContext{}, b3Error("sampled", sampled, ErrBadFlags)
+	}
+	switch flags {
+	case "1":
+		sc.TraceOptions = 1
+	case "", "0":
+	default:
+		return trace.SpanContext{}, b3Error("flags", flags, ErrBadFlags)
+	}
+	return sc, nil
+}
+
+// FromB3Single parses a B3 single header value of the form
+// {trace-id}-{span-id}-{sampling-state}-{parent-span-id}, where the last two
+// fields are optional.
+func FromB3Single(v string) (sc trace.SpanContext, ok bool) {
+	sc, err := ParseB3Single(v)
+	return sc, err == nil
+}
+
+// ParseB3Single is like FromB3Single but reports why v can't be parsed with a
+// *ParseError. Values carrying only a sampling state, such as "0", carry no
+// span context and are reported as malformed.
+func ParseB3Single(v string) (sc trace.SpanContext, err error) {
+	s := strings.TrimSpace(v)
+	// The trace ID has 32 or 16 digits: the dash after a 16-digit trace ID
+	// is followed by the 16 digits of the span ID.
+	traceIDLength := 32
+	if len(s) <= traceIDLength || s[traceIDLength] != '-' {
+		traceIDLength = 16
+	}
+	traceID, s, ok := cutB3Field(s, traceIDLength)
+	if !ok || s == "" {
+		return sc, b3SingleError("", v, ErrMalformed)
+	}
+	spanID, s, ok := cutB3Field(s, 16)
+	if !ok {
+		return sc, b3SingleError("", v, ErrMalformed)
+	}
+	var state, parentID string
+	if s != "" {
+		if state, s, ok = cutB3Field(s, 1); !ok {
+			return sc, b3SingleError("", v, ErrMalformed)
+		}
+	}
+	if s != "" {
+		if parentID, s, ok = cutB3Field(s, 16); !ok || s != "" {
+			return sc, b3SingleError("", v, ErrMalformed)
+		}
+	}
+
+	if err := decodeB3TraceID(&sc.TraceID, traceID); err != nil {
+		return trace.SpanContext{}, b3SingleError("trace ID", traceID, err)
+	}
+	if err := decodeB3SpanID(&sc.SpanID, spanID); err != nil {
+		return trace.SpanContext{}, b3SingleError("span ID", spanID, err)
+	}
+	switch state {
+	case "1", "d":
+		sc.TraceOptions = 1
+	case "0", "":
+	default:
+		return trace.SpanContext{}, b3SingleError("sampling state", state, ErrBadFlags)
+	}
+	if parentID != "" {
+		var parent trace.SpanID
+		if err := decodeB3SpanID(&parent, parentID); err != nil {
+			return trace.SpanContext{}, b3SingleError("parent span ID", parentID, err)
+		}
+	}
+	return sc, nil
+}
+
+// cutB3Field cuts the field of n bytes at the start of s, followed by the
+// end of s or by a dash and the rest of s. ok is false if s doesn't start
+// with such a field.
+func cutB3Field(s string, n int) (field, rest string, ok bool) {
+	switch {
+	case len(s) == n:
+		return s, "", true
+	case len(s) > n+1 && s[n] == '-':
+		return s[:n], s[n+1:], true
+	}
+	return "", "", false
+}
+
+// B3Single formats sc as a B3 single header value, without parent span ID.
+func B3Single(sc trace.SpanContext) string {
+	return sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + B3Sampled(sc)
+}
+
+// b3TraceID formats id on 32 hexadecimal digits, or on 16 digits with only
+// its lower 64 bits if traceID64 is set.
+func b3TraceID(id trace.TraceID, traceID64 bool) string {
+	if traceID64 {
+		return hex.EncodeToString(id[8:])
+	}
+	return id.String()
+}
+
+// B3Sampled returns the value of the B3SampledKey for sc.
+func B3Sampled(sc trace.SpanContext) string {
+	if sc.IsSampled() {
+		return "1"
+	}
+	return "0"
+}
+
+// decodeB3TraceID decodes the 16 or 32 hexadecimal digits s into id.
+func decodeB3TraceID(id *trace.TraceID, s string) error {
+	var b []byte
+	switch len(s) {
+	case 16:
+		b = id[8:]
+	case 32:
+		b = id[:]
+	default:
+		return ErrBadLength
+	}
+	if _, err := hex.Decode(b, []byte(s)); err != nil {
+		return ErrBadHex
+	}
+	if *id == (trace.TraceID{}) {
+		return ErrZeroID
+	}
+	return nil
+}
+
+// decodeB3SpanID decodes the 16 hexadecimal digits s into id.
+func decodeB3SpanID(id *trace.SpanID, s string) error {
+	if len(s) != 16 {
+		return ErrBadLength
+	}
+	if _, err := hex.Decode(id[:], []byte(s)); err != nil {
+		return ErrBadHex
+	}
+	if *id == (trace.SpanID{}) {
+		return ErrZeroID
+	}
+	return nil
+}
+
+func b3Error(field, value string, err error) error {
+	return &ParseError{Format: "b3", Field: field, Value: value, Err: err}
+}
+
+func b3SingleError(field, value string, err error) error {
+	return &ParseError{Format: "b3single", Field: field, Value: value, Err: err}
+}
@@ -0,0 +1,65 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package propagation
+
+import (
+	"errors"
+	"testing"
+
+	"go.opencensus.io/trace"
+)
+
+func TestParseB3Single(t *testing.T) {
+	traceID := trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36}
+	traceID64 := trace.TraceID{8: 0xa3, 9: 0xce, 10: 0x92, 11: 0x9d, 12: 0x0e, 13: 0x0e, 14: 0x47, 15: 0x36}
+	spanID := trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7}
+	for _, tc := range []struct {
+		v       string
+		want    trace.SpanContext
+		wantErr error
+	}{
+		{v: "4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7", want: trace.SpanContext{TraceID: traceID, SpanID: spanID}},
+		{v: "4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-1", want: trace.SpanContext{TraceID: traceID, SpanID: spanID, TraceOptions: 1}},
+		{v: "4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-d", want: trace.SpanContext{TraceID: traceID, SpanID: spanID, TraceOptions: 1}},
+		{v: "4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-0-53995c3f42cd8ad8", want: trace.SpanContext{TraceID: traceID, SpanID: spanID}},
+		{v: "a3ce929d0e0e4736-00f067aa0ba902b7-1", want: trace.SpanContext{TraceID: traceID64, SpanID: spanID, TraceOptions: 1}},
+		{v: " a3ce929d0e0e4736-00f067aa0ba902b7 ", want: trace.SpanContext{TraceID: traceID64, SpanID: spanID}},
+		{v: "", wantErr: ErrMalformed},
+		{v: "0", wantErr: ErrMalformed},
+		{v: "4bf92f3577b34da6a3ce929d0e0e4736", wantErr: ErrMalformed},
+		{v: "4bf92f3577b34da6a3ce929d0e0e4736-", wantErr: ErrMalformed},
+		{v: "4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-", wantErr: ErrMalformed},
+		{v: "4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-1-", wantErr: ErrMalformed},
+		{v: "4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-true", wantErr: ErrMalformed},
+		{v: "4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-1-53995c3f42cd8ad8-1", wantErr: ErrMalformed},
+		{v: "4bf92f3577b34da6a3ce9-00f067aa0ba902b7-1", wantErr: ErrMalformed},
+		{v: "4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-x", wantErr: ErrBadFlags},
+		{v: "4bf92f3577b34da6a3ce929d0e0e473z-00f067aa0ba902b7-1", wantErr: ErrBadHex},
+		{v: "4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-1-53995c3f42cd8adz", wantErr: ErrBadHex},
+		{v: "00000000000000000000000000000000-00f067aa0ba902b7-1", wantErr: ErrZeroID},
+	} {
+		sc, err := ParseB3Single(tc.v)
+		if !errors.Is(err, tc.wantErr) || sc != tc.want {
+			t.Errorf("ParseB3Single(%q) = %v, %v; want %v, %v", tc.v, sc, err, tc.want, tc.wantErr)
+		}
+	}
+}
+
+func BenchmarkParseB3Single(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ParseB3Single("4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-1-53995c3f42cd8ad8")
+	}
+}
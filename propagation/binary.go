@@ -0,0 +1,85 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package propagation
+
+import (
+	"encoding/base64"
+	"strings"
+
+	"go.opencensus.io/trace"
+	"go.opencensus.io/trace/propagation"
+)
+
+// FromBinary parses the OpenCensus binary format carried by the BinaryKey
+// metadata.
+func FromBinary(b []byte) (sc trace.SpanContext, ok bool) {
+	sc, err := ParseBinary(b)
+	return sc, err == nil
+}
+
+// ParseBinary is like FromBinary but reports why b can't be parsed with a
+// *ParseError.
+func ParseBinary(b []byte) (trace.SpanContext, error) {
+	if len(b) == 0 {
+		return trace.SpanContext{}, binaryError("", b, ErrBadLength)
+	}
+	if b[0] != 0 {
+		return trace.SpanContext{}, binaryError("version", b[:1], ErrBadVersion)
+	}
+	sc, ok := propagation.FromBinary(b)
+	switch {
+	case !ok:
+		return trace.SpanContext{}, binaryError("", b, ErrMalformed)
+	case sc.TraceID == trace.TraceID{}:
+		return trace.SpanContext{}, binaryError("trace ID", sc.TraceID[:], ErrZeroID)
+	case sc.SpanID == trace.SpanID{}:
+		return trace.SpanContext{}, binaryError("span ID", sc.SpanID[:], ErrZeroID)
+	}
+	return sc, nil
+}
+
+func binaryError(field string, value []byte, err error) error {
+	return &ParseError{Format: "binary", Field: field, Value: string(value), Err: err}
+}
+
+// base64Encodings are the encodings tried by DecodeBase64, in order.
+var base64Encodings = []*base64.Encoding{
+	base64.StdEncoding,
+	base64.RawStdEncoding,
+	base64.URLEncoding,
+	base64.RawURLEncoding,
+}
+
+// DecodeBase64 decodes s with the first of the standard, raw standard, URL and
+// raw URL base64 encodings accepting it.
+func DecodeBase64(s string) ([]byte, bool) {
+	s = strings.TrimSpace(s)
+	for _, enc := range base64Encodings {
+		if b, err := enc.DecodeString(s); err == nil {
+			return b, true
+		}
+	}
+	return nil, false
+}
+
+// FromBase64Binary parses the OpenCensus binary format encoded as base64 text,
+// as forwarded by proxies re-encoding -bin metadata.
+func FromBase64Binary(s string) (sc trace.SpanContext, ok bool) {
+	b, ok := DecodeBase64(s)
+	if !ok {
+		return trace.SpanContext{}, false
+	}
+	return FromBinary(b)
+}
@@ -0,0 +1,74 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package propagation
+
+import (
+	"go.opencensus.io/trace"
+)
+
+// Carrier is the minimal interface of the headers of a transport, such as a
+// message broker, trace contexts are read from and written to by Extract and
+// Inject. The headers of a NATS message implement it.
+type Carrier interface {
+	// Get returns the value of key, or an empty string.
+	Get(key string) string
+
+	// Set replaces the value of key with value.
+	Set(key, value string)
+}
+
+// MapCarrier is a Carrier over a map of headers, such as the attributes of a
+// Pub/Sub message.
+type MapCarrier map[string]string
+
+// Get implements Carrier.
+func (c MapCarrier) Get(key string) string { return c[key] }
+
+// Set implements Carrier.
+func (c MapCarrier) Set(key, value string) { c[key] = value }
+
+// Inject writes sc to c in the Jaeger and W3C Trace Context formats, under
+// JaegerKey, TraceparentKey and, when sc has a tracestate, TracestateKey, so
+// consumers continue the trace whichever format they read. The Jaeger value
+// holds the full 128-bit trace ID, so both formats carry the same trace.
+func Inject(sc trace.SpanContext, c Carrier) {
+	if jv, ok := (JaegerOptions{}).Format(sc); ok {
+		c.Set(JaegerKey, jv)
+	}
+	c.Set(TraceparentKey, Traceparent(sc))
+	if ts := Tracestate(sc.Tracestate); ts != "" {
+		c.Set(TracestateKey, ts)
+	}
+}
+
+// Extract returns the SpanContext written to c by Inject, or by any producer
+// writing the Jaeger or W3C Trace Context format. The Jaeger format takes
+// precedence, like it does in the gRPC ServerHandler.
+func Extract(c Carrier) (sc trace.SpanContext, ok bool) {
+	if jv := c.Get(JaegerKey); jv != "" {
+		if sc, ok = FromJaeger(jv); ok {
+			return sc, true
+		}
+	}
+	if tp := c.Get(TraceparentKey); tp != "" {
+		if sc, ok = FromTraceparent(tp); ok {
+			if ts := c.Get(TracestateKey); ts != "" {
+				sc.Tracestate = FromTracestate([]string{ts})
+			}
+			return sc, true
+		}
+	}
+	return trace.SpanContext{}, false
+}
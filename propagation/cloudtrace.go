@@ -0,0 +1,122 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package propagation
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"strconv"
+	"strings"
+
+	"go.opencensus.io/trace"
+	"google.golang.org/grpc/metadata"
+)
+
+// CloudTraceKey is the gRPC metadata key and HTTP header of the Google Cloud
+// Trace format, as set by Google Cloud load balancers and Cloud Run.
+const CloudTraceKey = "x-cloud-trace-context"
+
+// FromCloudTrace parses a Google Cloud Trace header of the form
+// {trace-id}/{span-id};o={options}, where the span ID is decimal and the
+// options are optional.
+func FromCloudTrace(v string) (sc trace.SpanContext, ok bool) {
+	sc, err := ParseCloudTrace(v)
+	return sc, err == nil
+}
+
+// ParseCloudTrace is like FromCloudTrace but reports why v can't be parsed
+// with a *ParseError.
+func ParseCloudTrace(v string) (sc trace.SpanContext, err error) {
+	ids, options, hasOptions := strings.Cut(strings.TrimSpace(v), ";")
+	traceID, spanID, ok := strings.Cut(ids, "/")
+	if !ok {
+		return sc, cloudTraceError("", v, ErrMalformed)
+	}
+
+	if len(traceID) != 32 {
+		return sc, cloudTraceError("trace ID", traceID, ErrBadLength)
+	}
+	if _, err := hex.Decode(sc.TraceID[:], []byte(traceID)); err != nil {
+		return trace.SpanContext{}, cloudTraceError("trace ID", traceID, ErrBadHex)
+	}
+	if sc.TraceID == (trace.TraceID{}) {
+		return trace.SpanContext{}, cloudTraceError("trace ID", traceID, ErrZeroID)
+	}
+
+	sid, err := strconv.ParseUint(spanID, 10, 64)
+	switch {
+	case err != nil:
+		return trace.SpanContext{}, cloudTraceError("span ID", spanID, ErrMalformed)
+	case sid == 0:
+		return trace.SpanContext{}, cloudTraceError("span ID", spanID, ErrZeroID)
+	}
+	binary.BigEndian.PutUint64(sc.SpanID[:], sid)
+
+	if hasOptions {
+		o, ok := strings.CutPrefix(options, "o=")
+		if !ok {
+			return trace.SpanContext{}, cloudTraceError("options", options, ErrMalformed)
+		}
+		flags, err := strconv.ParseUint(o, 10, 8)
+		if err != nil {
+			return trace.SpanContext{}, cloudTraceError("options", options, ErrBadFlags)
+		}
+		sc.TraceOptions = trace.TraceOptions(flags & 1)
+	}
+	return sc, nil
+}
+
+// CloudTrace formats sc as a Google Cloud Trace header.
+func CloudTrace(sc trace.SpanContext) string {
+	o := "0"
+	if sc.IsSampled() {
+		o = "1"
+	}
+	return sc.TraceID.String() + "/" + strconv.FormatUint(binary.BigEndian.Uint64(sc.SpanID[:]), 10) + ";o=" + o
+}
+
+func cloudTraceError(field, value string, err error) error {
+	return &ParseError{Format: "cloudtrace", Field: field, Value: value, Err: err}
+}
+
+// CloudTracePropagator is the Propagator of the Google Cloud Trace format,
+// carried by the CloudTraceKey metadata.
+type CloudTracePropagator struct{}
+
+// Name returns "cloudtrace".
+func (CloudTracePropagator) Name() string { return "cloudtrace" }
+
+// Extract implements Propagator.
+func (CloudTracePropagator) Extract(md metadata.MD) (sc trace.SpanContext, ok bool) {
+	vs := Lookup(md, CloudTraceKey)
+	if len(vs) == 0 {
+		return sc, false
+	}
+	return FromCloudTrace(vs[0])
+}
+
+// Validate implements Validator.
+func (CloudTracePropagator) Validate(md metadata.MD) error {
+	if vs := Lookup(md, CloudTraceKey); len(vs) > 0 {
+		_, err := ParseCloudTrace(vs[0])
+		return err
+	}
+	return nil
+}
+
+// Inject implements Propagator.
+func (CloudTracePropagator) Inject(sc trace.SpanContext, md metadata.MD) {
+	md.Set(CloudTraceKey, CloudTrace(sc))
+}
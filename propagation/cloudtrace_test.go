@@ -0,0 +1,78 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package propagation
+
+import (
+	"errors"
+	"testing"
+
+	"go.opencensus.io/trace"
+)
+
+func TestParseCloudTrace(t *testing.T) {
+	traceID := trace.TraceID{0x10, 0x5e, 0x45, 0x8c, 0x2c, 0x16, 0xa1, 0x1b, 0x2d, 0x8b, 0x4b, 0x47, 0x36, 0x6b, 0x7e, 0x23}
+	spanID := trace.SpanID{6: 0x30, 7: 0x39}
+	for _, tc := range []struct {
+		v       string
+		want    trace.SpanContext
+		wantErr error
+	}{
+		{v: "105e458c2c16a11b2d8b4b47366b7e23/12345", want: trace.SpanContext{TraceID: traceID, SpanID: spanID}},
+		{v: "105e458c2c16a11b2d8b4b47366b7e23/12345;o=1", want: trace.SpanContext{TraceID: traceID, SpanID: spanID, TraceOptions: 1}},
+		{v: "105e458c2c16a11b2d8b4b47366b7e23/12345;o=0", want: trace.SpanContext{TraceID: traceID, SpanID: spanID}},
+		{v: "105e458c2c16a11b2d8b4b47366b7e23/12345;o=3", want: trace.SpanContext{TraceID: traceID, SpanID: spanID, TraceOptions: 1}},
+		{v: "105e458c2c16a11b2d8b4b47366b7e23/12345;o=2", want: trace.SpanContext{TraceID: traceID, SpanID: spanID}},
+		{v: " 105e458c2c16a11b2d8b4b47366b7e23/12345;o=1 ", want: trace.SpanContext{TraceID: traceID, SpanID: spanID, TraceOptions: 1}},
+		{v: "105e458c2c16a11b2d8b4b47366b7e23/18446744073709551615", want: trace.SpanContext{TraceID: traceID, SpanID: trace.SpanID{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}}},
+		{v: "", wantErr: ErrMalformed},
+		{v: "105e458c2c16a11b2d8b4b47366b7e23", wantErr: ErrMalformed},
+		{v: "105e458c2c16a11b2d8b4b47366b7e23/", wantErr: ErrMalformed},
+		{v: "105e458c2c16a11b2d8b4b47366b7e23/0x3039", wantErr: ErrMalformed},
+		{v: "105e458c2c16a11b2d8b4b47366b7e23/-1", wantErr: ErrMalformed},
+		{v: "105e458c2c16a11b2d8b4b47366b7e23/18446744073709551616", wantErr: ErrMalformed},
+		{v: "105e458c2c16a11b2d8b4b47366b7e23/12345;x=1", wantErr: ErrMalformed},
+		{v: "105e458c2c16a11b2d8b4b47366b7e2/12345", wantErr: ErrBadLength},
+		{v: "105e458c2c16a11b2d8b4b47366b7e23a/12345", wantErr: ErrBadLength},
+		{v: "105e458c2c16a11b2d8b4b47366b7e2z/12345", wantErr: ErrBadHex},
+		{v: "00000000000000000000000000000000/12345", wantErr: ErrZeroID},
+		{v: "105e458c2c16a11b2d8b4b47366b7e23/0", wantErr: ErrZeroID},
+		{v: "105e458c2c16a11b2d8b4b47366b7e23/12345;o=true", wantErr: ErrBadFlags},
+		{v: "105e458c2c16a11b2d8b4b47366b7e23/12345;o=256", wantErr: ErrBadFlags},
+	} {
+		sc, err := ParseCloudTrace(tc.v)
+		if !errors.Is(err, tc.wantErr) || sc != tc.want {
+			t.Errorf("ParseCloudTrace(%q) = %v, %v; want %v, %v", tc.v, sc, err, tc.want, tc.wantErr)
+		}
+	}
+}
+
+func TestCloudTraceRoundTrip(t *testing.T) {
+	traceID := trace.TraceID{0x10, 0x5e, 0x45, 0x8c, 0x2c, 0x16, 0xa1, 0x1b, 0x2d, 0x8b, 0x4b, 0x47, 0x36, 0x6b, 0x7e, 0x23}
+	for _, tc := range []struct {
+		sc   trace.SpanContext
+		want string
+	}{
+		{trace.SpanContext{TraceID: traceID, SpanID: trace.SpanID{6: 0x30, 7: 0x39}, TraceOptions: 1}, "105e458c2c16a11b2d8b4b47366b7e23/12345;o=1"},
+		{trace.SpanContext{TraceID: traceID, SpanID: trace.SpanID{0: 0x80}}, "105e458c2c16a11b2d8b4b47366b7e23/9223372036854775808;o=0"},
+	} {
+		v := CloudTrace(tc.sc)
+		if v != tc.want {
+			t.Errorf("CloudTrace(%v) = %q, want %q", tc.sc, v, tc.want)
+		}
+		if got, err := ParseCloudTrace(v); err != nil || got != tc.sc {
+			t.Errorf("ParseCloudTrace(CloudTrace(%v)) = %v, %v", tc.sc, got, err)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package propagation
+
+import (
+	"go.opencensus.io/trace"
+	"google.golang.org/grpc/metadata"
+)
+
+// CompositePropagator combines the Propagators of several formats, reading
+// and writing them independently: the order of Extractors sets which format
+// wins when the metadata carries several trace contexts, such as both
+// grpc-trace-bin and uber-trace-id with different IDs, while Injectors sets
+// the formats written on egress.
+//
+// The handlers of the ocgrpc package expand a CompositePropagator set in
+// their Propagators, so each format is still reported on its own.
+type CompositePropagator struct {
+	// Extractors are tried in order, the first valid SpanContext winning.
+	Extractors []Propagator
+
+	// Injectors all write the SpanContext.
+	Injectors []Propagator
+}
+
+// Extract implements Propagator.
+func (c CompositePropagator) Extract(md metadata.MD) (sc trace.SpanContext, ok bool) {
+	for _, p := range c.Extractors {
+		if sc, ok = p.Extract(md); ok {
+			return sc, true
+		}
+	}
+	return trace.SpanContext{}, false
+}
+
+// Validate implements Validator. It returns the first error of the
+// Extractors when none of them found a valid SpanContext.
+func (c CompositePropagator) Validate(md metadata.MD) error {
+	if _, ok := c.Extract(md); ok {
+		return nil
+	}
+	for _, p := range c.Extractors {
+		if v, ok := p.(Validator); ok {
+			if err := v.Validate(md); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Inject implements Propagator.
+func (c CompositePropagator) Inject(sc trace.SpanContext, md metadata.MD) {
+	for _, p := range c.Injectors {
+		p.Inject(sc, md)
+	}
+}
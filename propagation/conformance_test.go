@@ -0,0 +1,74 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package propagation_test
+
+import (
+	"testing"
+
+	propag "github.com/akhenakh/ocgrpc_propagation/propagation"
+	"github.com/akhenakh/ocgrpc_propagation/propagationtest"
+	"go.opencensus.io/trace"
+	"google.golang.org/grpc/metadata"
+)
+
+// The JaegerPropagator truncating trace IDs and the OTSpanContextPropagator
+// write 64-bit trace IDs, so they can't pass the round trip of
+// RunPropagatorTests: see TestShortTraceIDPropagators.
+func TestBuiltinPropagatorsConform(t *testing.T) {
+	for _, p := range []propag.Propagator{
+		propag.BinaryPropagator{},
+		propag.BinaryPropagator{DecodeBase64: true},
+		propag.GRPCWebPropagator{},
+		propag.JaegerPropagator{},
+		propag.TraceContextPropagator{},
+		propag.B3Propagator{},
+		propag.B3SinglePropagator{},
+		propag.XRayPropagator{},
+		propag.CloudTracePropagator{},
+		propag.CompositePropagator{
+			Extractors: []propag.Propagator{propag.TraceContextPropagator{}, propag.BinaryPropagator{}},
+			Injectors:  []propag.Propagator{propag.TraceContextPropagator{}, propag.BinaryPropagator{}},
+		},
+	} {
+		t.Run(propag.NameOf(p), func(t *testing.T) {
+			propagationtest.RunPropagatorTests(t, p)
+		})
+	}
+}
+
+func TestShortTraceIDPropagators(t *testing.T) {
+	sc := trace.SpanContext{
+		TraceID:      trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:       trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		TraceOptions: 1,
+	}
+	want := sc
+	copy(want.TraceID[:8], make([]byte, 8))
+	for _, p := range []propag.Propagator{
+		propag.JaegerPropagator{Options: propag.JaegerOptions{LongTraceIDs: propag.TruncateLongTraceIDs}},
+		propag.OTSpanContextPropagator{},
+	} {
+		t.Run(propag.NameOf(p), func(t *testing.T) {
+			md := metadata.MD{}
+			p.Inject(sc, md)
+			if got, ok := p.Extract(md); !ok || got != want {
+				t.Errorf("Extract(Inject(%v)) = %v, %v; want %v, true", sc, got, ok, want)
+			}
+			if got, ok := p.Extract(metadata.MD{}); ok {
+				t.Errorf("Extract(empty metadata) = %v, want none", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package propagation
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Reasons a trace context can't be parsed, wrapped by ParseError.
+var (
+	// ErrMalformed reports a value without the structure of its format.
+	ErrMalformed = errors.New("malformed")
+
+	// ErrBadLength reports a value or ID of the wrong length.
+	ErrBadLength = errors.New("bad length")
+
+	// ErrBadHex reports an ID that isn't hexadecimal.
+	ErrBadHex = errors.New("bad hex")
+
+	// ErrBadFlags reports unparsable trace flags or sampling state.
+	ErrBadFlags = errors.New("bad flags")
+
+	// ErrBadVersion reports an unsupported format version.
+	ErrBadVersion = errors.New("bad version")
+
+	// ErrZeroID reports a trace or span ID of all zeros.
+	ErrZeroID = errors.New("zero ID")
+
+	// ErrShortTraceID reports a 64-bit trace ID rejected by
+	// RejectShortTraceIDs.
+	ErrShortTraceID = errors.New("short trace ID")
+)
+
+// ParseError describes why a trace context value couldn't be parsed. Use
+// errors.Is to test its reason.
+type ParseError struct {
+	// Format is the name of the format, such as "jaeger" or "binary".
+	Format string
+
+	// Field is the part of the value at fault, such as "trace ID", or
+	// empty when the whole value is.
+	Field string
+
+	// Value is the value, or part, at fault.
+	Value string
+
+	// Err is the reason, one of the Err variables of this package.
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	field := e.Field
+	if field == "" {
+		field = "value"
+	}
+	return fmt.Sprintf("propagation: invalid %s %s %q: %v", e.Format, field, e.Value, e.Err)
+}
+
+// Unwrap returns the reason of e.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,86 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package propagation
+
+import (
+	"encoding/base64"
+
+	"go.opencensus.io/trace"
+	"go.opencensus.io/trace/propagation"
+	"google.golang.org/grpc/metadata"
+)
+
+// GRPCWebKey is the gRPC metadata key and HTTP header carrying the OpenCensus
+// binary format as base64 text. grpc-web clients can't send binary headers,
+// so browsers set this ASCII header instead of BinaryKey.
+const GRPCWebKey = "grpc-trace-web"
+
+// FromGRPCWeb parses the base64 text of the OpenCensus binary format carried
+// by the GRPCWebKey metadata. The standard and URL base64 alphabets are
+// accepted, with or without padding.
+func FromGRPCWeb(v string) (sc trace.SpanContext, ok bool) {
+	sc, err := ParseGRPCWeb(v)
+	return sc, err == nil
+}
+
+// ParseGRPCWeb is like FromGRPCWeb but reports why v can't be parsed with a
+// *ParseError.
+func ParseGRPCWeb(v string) (trace.SpanContext, error) {
+	b, ok := DecodeBase64(v)
+	if !ok {
+		return trace.SpanContext{}, &ParseError{Format: "grpcweb", Value: v, Err: ErrMalformed}
+	}
+	sc, err := ParseBinary(b)
+	if err != nil {
+		err.(*ParseError).Format = "grpcweb"
+	}
+	return sc, err
+}
+
+// GRPCWeb formats sc as the standard base64 text of the OpenCensus binary
+// format.
+func GRPCWeb(sc trace.SpanContext) string {
+	return base64.StdEncoding.EncodeToString(propagation.Binary(sc))
+}
+
+// GRPCWebPropagator is the Propagator of the base64 text binary format,
+// carried by the GRPCWebKey metadata.
+type GRPCWebPropagator struct{}
+
+// Name returns "grpcweb".
+func (GRPCWebPropagator) Name() string { return "grpcweb" }
+
+// Extract implements Propagator.
+func (GRPCWebPropagator) Extract(md metadata.MD) (sc trace.SpanContext, ok bool) {
+	vs := Lookup(md, GRPCWebKey)
+	if len(vs) == 0 {
+		return sc, false
+	}
+	return FromGRPCWeb(vs[0])
+}
+
+// Validate implements Validator.
+func (GRPCWebPropagator) Validate(md metadata.MD) error {
+	if vs := Lookup(md, GRPCWebKey); len(vs) > 0 {
+		_, err := ParseGRPCWeb(vs[0])
+		return err
+	}
+	return nil
+}
+
+// Inject implements Propagator.
+func (GRPCWebPropagator) Inject(sc trace.SpanContext, md metadata.MD) {
+	md.Set(GRPCWebKey, GRPCWeb(sc))
+}
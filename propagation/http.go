@@ -0,0 +1,118 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package propagation
+
+import (
+	"context"
+	"net/http"
+
+	"go.opencensus.io/trace"
+	"google.golang.org/grpc/metadata"
+)
+
+// httpPropagators are the Propagators of FromHTTPHeader: those of the default
+// gRPC ServerHandler, in the same order, without the binary formats HTTP
+// clients don't send.
+var httpPropagators = []Propagator{
+	JaegerPropagator{},
+	TraceContextPropagator{},
+	B3SinglePropagator{},
+	B3Propagator{},
+	XRayPropagator{},
+	CloudTracePropagator{},
+	OTSpanContextPropagator{},
+}
+
+// UnmatchedRouteName names the spans of the HTTP requests matching no route in
+// the middlewares naming spans after routes, rather than their path, which
+// would give every probed URL a span name of its own.
+const UnmatchedRouteName = "unmatched route"
+
+// FromHTTPHeader returns the SpanContext carried by the trace headers of h, in
+// the text formats read by default by the gRPC ServerHandler, in the same
+// order. Use ExtractHTTPHeader for other formats or another order, such as
+// those of a ServerHandler with Propagators.
+func FromHTTPHeader(h http.Header) (sc trace.SpanContext, ok bool) {
+	return ExtractHTTPHeader(h, httpPropagators)
+}
+
+// ExtractHTTPHeader returns the SpanContext carried by the headers of h for
+// the first of ps finding a valid one.
+func ExtractHTTPHeader(h http.Header, ps []Propagator) (sc trace.SpanContext, ok bool) {
+	md := make(metadata.MD, len(h))
+	for k, vs := range h {
+		md[CanonicalKey(k)] = vs
+	}
+	for _, p := range ps {
+		if sc, ok = p.Extract(md); ok {
+			return sc, true
+		}
+	}
+	return trace.SpanContext{}, false
+}
+
+// StartHTTPServerSpan starts a server span named name around the HTTP request
+// r. It follows the rules of the gRPC ServerHandler: the SpanContext found in
+// the headers of r by FromHTTPHeader becomes the parent of the new span, or a
+// link to it when isPublicEndpoint is true.
+func StartHTTPServerSpan(r *http.Request, name string, isPublicEndpoint bool, o trace.StartOptions) (context.Context, *trace.Span) {
+	return StartHTTPServerSpanWith(r, name, isPublicEndpoint, o, nil)
+}
+
+// StartHTTPServerSpanWith is StartHTTPServerSpan reading the headers of r with
+// ps, such as the Propagators of a ServerHandler, or with those of
+// FromHTTPHeader if ps is nil.
+func StartHTTPServerSpanWith(r *http.Request, name string, isPublicEndpoint bool, o trace.StartOptions, ps []Propagator) (context.Context, *trace.Span) {
+	if ps == nil {
+		ps = httpPropagators
+	}
+	ctx := r.Context()
+	parent, haveParent := ExtractHTTPHeader(r.Header, ps)
+	if haveParent && !isPublicEndpoint {
+		return trace.StartSpanWithRemoteParent(ctx, name, parent,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithSampler(o.Sampler),
+		)
+	}
+
+	ctx, span := trace.StartSpan(ctx, name,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithSampler(o.Sampler))
+	if haveParent {
+		span.AddLink(trace.Link{TraceID: parent.TraceID, SpanID: parent.SpanID, Type: trace.LinkTypeChild})
+	}
+	return ctx, span
+}
+
+// ForwardHTTPHeader copies the Jaeger header of h, unmodified, to the outgoing
+// gRPC metadata of ctx, like the Jaeger propagation interceptors of the ocgrpc
+// package do for incoming gRPC metadata. Public endpoints must not forward the
+// headers of their untrusted callers: see ForwardSpanContext.
+func ForwardHTTPHeader(ctx context.Context, h http.Header) context.Context {
+	if jv := h.Get(JaegerKey); jv != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, JaegerKey, jv)
+	}
+	return ctx
+}
+
+// ForwardSpanContext writes sc, such as the span of an HTTP request served on
+// a public endpoint, to the Jaeger metadata of the outgoing gRPC metadata of
+// ctx, so the gRPC calls made by the request join its trace.
+func ForwardSpanContext(ctx context.Context, sc trace.SpanContext) context.Context {
+	if jv, ok := (JaegerOptions{}).Format(sc); ok {
+		ctx = metadata.AppendToOutgoingContext(ctx, JaegerKey, jv)
+	}
+	return ctx
+}
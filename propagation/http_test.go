@@ -0,0 +1,57 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package propagation
+
+import (
+	"net/http"
+	"testing"
+
+	"go.opencensus.io/trace"
+)
+
+func TestFromHTTPHeader(t *testing.T) {
+	jaeger := trace.SpanContext{TraceID: trace.TraceID{15: 1}, SpanID: trace.SpanID{7: 1}, TraceOptions: 1}
+	w3c := trace.SpanContext{TraceID: trace.TraceID{15: 2}, SpanID: trace.SpanID{7: 2}, TraceOptions: 1}
+
+	h := http.Header{}
+	h.Set(TraceparentKey, Traceparent(w3c))
+	h.Set(TracestateKey, "vendor=value")
+	sc, ok := FromHTTPHeader(h)
+	if !ok || sc.TraceID != w3c.TraceID || sc.SpanID != w3c.SpanID {
+		t.Errorf("FromHTTPHeader() = %v, %v; want %v", sc, ok, w3c)
+	}
+	if sc.Tracestate == nil || len(sc.Tracestate.Entries()) != 1 {
+		t.Errorf("the tracestate wasn't read: %v", sc.Tracestate)
+	}
+
+	// The Jaeger format takes precedence, as in the ServerHandler.
+	jv, _ := JaegerOptions{}.Format(jaeger)
+	h.Set(JaegerKey, jv)
+	if sc, ok := FromHTTPHeader(h); !ok || sc != jaeger {
+		t.Errorf("FromHTTPHeader() = %v, %v; want %v", sc, ok, jaeger)
+	}
+	if sc, ok := ExtractHTTPHeader(h, []Propagator{TraceContextPropagator{}, JaegerPropagator{}}); !ok || sc.TraceID != w3c.TraceID {
+		t.Errorf("ExtractHTTPHeader() = %v, %v; want %v", sc, ok, w3c)
+	}
+
+	// Invalid values are skipped.
+	h.Set(JaegerKey, "garbage")
+	if sc, ok := FromHTTPHeader(h); !ok || sc.TraceID != w3c.TraceID {
+		t.Errorf("FromHTTPHeader() = %v, %v; want %v", sc, ok, w3c)
+	}
+	if _, ok := FromHTTPHeader(http.Header{}); ok {
+		t.Error("FromHTTPHeader() found a span context in empty headers")
+	}
+}
@@ -0,0 +1,319 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package propagation contains the trace context header formats shared by the
+// gRPC handlers of the ocgrpc package and the HTTP middlewares built on top of
+// it, so HTTP edges and gRPC backends read and write exactly the same bytes.
+package propagation
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"go.opencensus.io/trace"
+)
+
+// Header and metadata keys carrying trace contexts.
+const (
+	// BinaryKey is the gRPC metadata key of the OpenCensus binary format.
+	BinaryKey = "grpc-trace-bin"
+
+	// JaegerKey is the gRPC metadata key and HTTP header of the Jaeger format.
+	JaegerKey = "uber-trace-id"
+
+	// JaegerDebugIDKey is the gRPC metadata key and HTTP header used by
+	// Jaeger clients to force the sampling of a trace.
+	JaegerDebugIDKey = "jaeger-debug-id"
+
+	// SignatureKey is the gRPC metadata key of the signature of the trace
+	// context, see ocgrpc.Signer.
+	SignatureKey = "trace-context-sig-bin"
+
+	// RequestIDKey is the gRPC metadata key and HTTP header of the request
+	// ID set by Envoy and other proxies, correlating the logs of a request.
+	RequestIDKey = "x-request-id"
+
+	// JaegerBaggagePrefix prefixes the gRPC metadata keys and HTTP headers
+	// carrying Jaeger baggage items.
+	JaegerBaggagePrefix = "uberctx-"
+)
+
+// IsValid reports whether sc has non-zero trace and span IDs. Span contexts
+// failing this check must be treated as absent.
+func IsValid(sc trace.SpanContext) bool {
+	return sc.TraceID != trace.TraceID{} && sc.SpanID != trace.SpanID{}
+}
+
+// JaegerFlags is the bit field of the flags of the Jaeger format. The flags of
+// Jaeger trace contexts are all kept in the TraceOptions of their
+// SpanContext, whose lowest bit is the sampled flag: OpenCensus carries them
+// over to child spans, so they are propagated downstream and written back to
+// uber-trace-id as received. Other formats only write the sampled flag.
+type JaegerFlags uint8
+
+// Flags of the Jaeger format.
+const (
+	// JaegerSampledFlag is set when the trace is sampled.
+	JaegerSampledFlag JaegerFlags = 1
+
+	// JaegerDebugFlag is set when the trace must be sampled whatever the
+	// samplers, see ocgrpc.ServerHandler.HonorJaegerDebugID.
+	JaegerDebugFlag JaegerFlags = 2
+
+	// JaegerFirehoseFlag is set when the spans of the trace must be
+	// written to the firehose storage of Jaeger only.
+	JaegerFirehoseFlag JaegerFlags = 8
+)
+
+// JaegerFlagsOf returns the Jaeger flags of sc.
+func JaegerFlagsOf(sc trace.SpanContext) JaegerFlags {
+	return JaegerFlags(sc.TraceOptions)
+}
+
+// ShortTraceIDPolicy controls how Jaeger trace IDs of 64 bits or less are
+// widened to the 128-bit TraceID of OpenCensus.
+type ShortTraceIDPolicy int
+
+const (
+	// PadShortTraceIDs stores short trace IDs in the lower 64 bits of the
+	// TraceID, leaving the high bits zero.
+	PadShortTraceIDs ShortTraceIDPolicy = iota
+
+	// RejectShortTraceIDs treats trace contexts with a short trace ID as
+	// absent.
+	RejectShortTraceIDs
+)
+
+// LongTraceIDPolicy controls how 128-bit trace IDs are written to formats or
+// collectors only supporting 64-bit trace IDs.
+type LongTraceIDPolicy int
+
+const (
+	// WriteLongTraceIDs writes the full 128-bit TraceID to the formats
+	// supporting it, such as Jaeger, and truncates it elsewhere.
+	WriteLongTraceIDs LongTraceIDPolicy = iota
+
+	// TruncateLongTraceIDs keeps the lower 64 bits of the TraceID, as legacy
+	// Jaeger and Zipkin collectors do.
+	TruncateLongTraceIDs
+
+	// SkipLongTraceIDs doesn't write trace contexts whose TraceID doesn't
+	// fit in 64 bits.
+	SkipLongTraceIDs
+)
+
+// TraceID64 returns the 64-bit trace ID to write for id according to p. ok is
+// false when id must not be written.
+func (p LongTraceIDPolicy) TraceID64(id trace.TraceID) (low [8]byte, ok bool) {
+	if p == SkipLongTraceIDs && binary.BigEndian.Uint64(id[:8]) != 0 {
+		return low, false
+	}
+	copy(low[:], id[8:])
+	return low, true
+}
+
+// JaegerOptions configures how the Jaeger format is read and written.
+type JaegerOptions struct {
+	// ShortTraceIDs controls how trace IDs of 64 bits or less are read.
+	ShortTraceIDs ShortTraceIDPolicy
+
+	// LongTraceIDs controls how 128-bit trace IDs are written. They are
+	// written whole by default; TruncateLongTraceIDs or SkipLongTraceIDs
+	// suit legacy collectors only supporting 64-bit trace IDs.
+	LongTraceIDs LongTraceIDPolicy
+
+	// Strict may be set to true to make Parse validate the whole value, as
+	// ParseStrict does.
+	Strict bool
+}
+
+// FromJaeger parses a Jaeger trace context of the form
+// {trace-id}:{span-id}:{parent-span-id}:{flags} with the default
+// JaegerOptions.
+func FromJaeger(jv string) (sc trace.SpanContext, ok bool) {
+	return JaegerOptions{}.Parse(jv)
+}
+
+// Parse parses a Jaeger trace context of the form
+// {trace-id}:{span-id}:{parent-span-id}:{flags}.
+//
+// URL-encoded values, such as 4bf9...%3A00f0...%3A0%3A1, are decoded first.
+// Trace IDs of 64 bits or less are handled according to o.ShortTraceIDs. ok is
+// false when the trace or span ID is zero or doesn't fit. Unparsable flags
+// and parent span IDs are ignored unless o.Strict is set.
+func (o JaegerOptions) Parse(jv string) (sc trace.SpanContext, ok bool) {
+	sc, err := o.parse(jv, o.Strict)
+	return sc, err == nil
+}
+
+// ParseJaegerHeader is like FromJaeger but reports why jv can't be parsed
+// with a *ParseError.
+func ParseJaegerHeader(jv string) (trace.SpanContext, error) {
+	return JaegerOptions{}.ParseStrict(jv)
+}
+
+// ParseStrict is like Parse but also rejects unparsable flags and parent span
+// IDs, and reports why jv can't be parsed with a *ParseError.
+func (o JaegerOptions) ParseStrict(jv string) (trace.SpanContext, error) {
+	return o.parse(jv, true)
+}
+
+func (o JaegerOptions) parse(jv string, strict bool) (sc trace.SpanContext, err error) {
+	// Some Jaeger clients and HTTP gateways URL-encode the colons.
+	if strings.Contains(jv, "%") {
+		if u, err := url.QueryUnescape(jv); err == nil {
+			jv = u
+		}
+	}
+	var parts [4]string
+	if !splitExactly(jv, ':', parts[:]) {
+		return sc, jaegerError("", jv, ErrMalformed)
+	}
+
+	// Shorter IDs are left-padded with zeros.
+	n, ok := decodeHexID(sc.TraceID[:], parts[0])
+	switch {
+	case !ok:
+		return sc, jaegerError("trace ID", parts[0], ErrBadHex)
+	case n > 16:
+		return sc, jaegerError("trace ID", parts[0], ErrBadLength)
+	case n <= 8 && o.ShortTraceIDs == RejectShortTraceIDs:
+		return sc, jaegerError("trace ID", parts[0], ErrShortTraceID)
+	}
+
+	n, ok = decodeHexID(sc.SpanID[:], parts[1])
+	switch {
+	case !ok:
+		return trace.SpanContext{}, jaegerError("span ID", parts[1], ErrBadHex)
+	case n > 8:
+		return trace.SpanContext{}, jaegerError("span ID", parts[1], ErrBadLength)
+	}
+
+	if strict {
+		var parent trace.SpanID
+		n, ok = decodeHexID(parent[:], parts[2])
+		switch {
+		case !ok:
+			return trace.SpanContext{}, jaegerError("parent span ID", parts[2], ErrBadHex)
+		case n > 8:
+			return trace.SpanContext{}, jaegerError("parent span ID", parts[2], ErrBadLength)
+		}
+	}
+
+	// The flags are a hexadecimal bit field, see JaegerFlags. They are all
+	// kept, so they are written back as received.
+	flags, err := strconv.ParseUint(parts[3], 16, 8)
+	if err != nil {
+		if strict {
+			return trace.SpanContext{}, jaegerError("flags", parts[3], ErrBadFlags)
+		}
+		flags = 0
+	}
+	sc.TraceOptions = trace.TraceOptions(flags)
+
+	switch {
+	case sc.TraceID == trace.TraceID{}:
+		return trace.SpanContext{}, jaegerError("trace ID", parts[0], ErrZeroID)
+	case sc.SpanID == trace.SpanID{}:
+		return trace.SpanContext{}, jaegerError("span ID", parts[1], ErrZeroID)
+	}
+	return sc, nil
+}
+
+// Format formats sc as a Jaeger trace context with no parent span ID. The
+// trace ID is written on 128 bits if o.LongTraceIDs is WriteLongTraceIDs and
+// its high bits aren't zero, or o.ShortTraceIDs is RejectShortTraceIDs so
+// that o can parse it back, else on 64 bits; ok is false when sc must not be
+// written.
+func (o JaegerOptions) Format(sc trace.SpanContext) (jv string, ok bool) {
+	traceID := sc.TraceID[:]
+	long := binary.BigEndian.Uint64(sc.TraceID[:8]) != 0 || o.ShortTraceIDs == RejectShortTraceIDs
+	if o.LongTraceIDs != WriteLongTraceIDs || !long {
+		low, ok := o.LongTraceIDs.TraceID64(sc.TraceID)
+		if !ok {
+			return "", false
+		}
+		traceID = low[:]
+	}
+	// The value is formatted in a fixed buffer, as it is written for every
+	// RPC: only the returned string is allocated.
+	var buf [len(trace.TraceID{})*2 + len(trace.SpanID{})*2 + 6]byte
+	n := hex.Encode(buf[:], traceID)
+	buf[n] = ':'
+	n++
+	n += hex.Encode(buf[n:], sc.SpanID[:])
+	n += copy(buf[n:], ":0:")
+	return string(strconv.AppendUint(buf[:n], uint64(JaegerFlagsOf(sc)), 16)), true
+}
+
+func jaegerError(field, value string, err error) error {
+	return &ParseError{Format: "jaeger", Field: field, Value: value, Err: err}
+}
+
+// decodeHexID decodes the hexadecimal ID h into the end of dst, left-padded
+// with zeros, and returns its length in bytes, counting an odd leading digit
+// as a byte. ok is false if h isn't hexadecimal. Nothing is written when h is
+// longer than dst, which callers detect from n.
+func decodeHexID(dst []byte, h string) (n int, ok bool) {
+	for i := 0; i < len(h); i++ {
+		if _, ok := fromHexChar(h[i]); !ok {
+			return 0, false
+		}
+	}
+	n = (len(h) + 1) / 2
+	if n > len(dst) {
+		return n, true
+	}
+	for i, j := len(h), len(dst)-1; i > 0; i, j = i-2, j-1 {
+		lo, _ := fromHexChar(h[i-1])
+		var hi byte
+		if i > 1 {
+			hi, _ = fromHexChar(h[i-2])
+		}
+		dst[j] = hi<<4 | lo
+	}
+	return n, true
+}
+
+func fromHexChar(c byte) (byte, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0', true
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10, true
+	case 'A' <= c && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
+
+// splitExactly splits s around sep into parts, without allocating. ok is false
+// if s doesn't have exactly len(parts) parts.
+func splitExactly(s string, sep byte, parts []string) (ok bool) {
+	for i := range parts[:len(parts)-1] {
+		j := strings.IndexByte(s, sep)
+		if j < 0 {
+			return false
+		}
+		parts[i], s = s[:j], s[j+1:]
+	}
+	if strings.IndexByte(s, sep) >= 0 {
+		return false
+	}
+	parts[len(parts)-1] = s
+	return true
+}
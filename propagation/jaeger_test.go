@@ -0,0 +1,134 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package propagation
+
+import (
+	"testing"
+
+	"go.opencensus.io/trace"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestJaegerRoundTrip(t *testing.T) {
+	strict := JaegerOptions{ShortTraceIDs: RejectShortTraceIDs}
+	traceID := trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36}
+	traceID64 := trace.TraceID{8: 0xa3, 9: 0xce, 10: 0x92, 11: 0x9d, 12: 0x0e, 13: 0x0e, 14: 0x47, 15: 0x36}
+	spanID := trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7}
+	for _, tc := range []struct {
+		name    string
+		options JaegerOptions
+		value   string
+		want    trace.SpanContext
+		format  string // empty when the SpanContext mustn't be written
+	}{
+		{
+			name:   "128-bit",
+			value:  "4bf92f3577b34da6a3ce929d0e0e4736:00f067aa0ba902b7:0:1",
+			want:   trace.SpanContext{TraceID: traceID, SpanID: spanID, TraceOptions: 1},
+			format: "4bf92f3577b34da6a3ce929d0e0e4736:00f067aa0ba902b7:0:1",
+		},
+		{
+			name:    "128-bit truncated",
+			options: JaegerOptions{LongTraceIDs: TruncateLongTraceIDs},
+			value:   "4bf92f3577b34da6a3ce929d0e0e4736:00f067aa0ba902b7:0:1",
+			want:    trace.SpanContext{TraceID: traceID, SpanID: spanID, TraceOptions: 1},
+			format:  "a3ce929d0e0e4736:00f067aa0ba902b7:0:1",
+		},
+		{
+			name:    "128-bit skipped",
+			options: JaegerOptions{LongTraceIDs: SkipLongTraceIDs},
+			value:   "4bf92f3577b34da6a3ce929d0e0e4736:00f067aa0ba902b7:0:1",
+			want:    trace.SpanContext{TraceID: traceID, SpanID: spanID, TraceOptions: 1},
+		},
+		{
+			name:   "odd-length high half",
+			value:  "bf92f3577b34da6a3ce929d0e0e4736:00f067aa0ba902b7:0:1",
+			want:   trace.SpanContext{TraceID: trace.TraceID{0x0b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36}, SpanID: spanID, TraceOptions: 1},
+			format: "0bf92f3577b34da6a3ce929d0e0e4736:00f067aa0ba902b7:0:1",
+		},
+		{
+			name:   "one-digit high half",
+			value:  "1a3ce929d0e0e4736:00f067aa0ba902b7:0:1",
+			want:   trace.SpanContext{TraceID: trace.TraceID{7: 1, 8: 0xa3, 9: 0xce, 10: 0x92, 11: 0x9d, 12: 0x0e, 13: 0x0e, 14: 0x47, 15: 0x36}, SpanID: spanID, TraceOptions: 1},
+			format: "0000000000000001a3ce929d0e0e4736:00f067aa0ba902b7:0:1",
+		},
+		{
+			name:   "zero-padded high half",
+			value:  "0000000000000000a3ce929d0e0e4736:00f067aa0ba902b7:0:1",
+			want:   trace.SpanContext{TraceID: traceID64, SpanID: spanID, TraceOptions: 1},
+			format: "a3ce929d0e0e4736:00f067aa0ba902b7:0:1",
+		},
+		{
+			name:    "zero-padded high half, short trace IDs rejected",
+			options: strict,
+			value:   "0000000000000000a3ce929d0e0e4736:00f067aa0ba902b7:0:1",
+			want:    trace.SpanContext{TraceID: traceID64, SpanID: spanID, TraceOptions: 1},
+			format:  "0000000000000000a3ce929d0e0e4736:00f067aa0ba902b7:0:1",
+		},
+		{
+			name:   "64-bit",
+			value:  "a3ce929d0e0e4736:00f067aa0ba902b7:0:0",
+			want:   trace.SpanContext{TraceID: traceID64, SpanID: spanID},
+			format: "a3ce929d0e0e4736:00f067aa0ba902b7:0:0",
+		},
+		{
+			name:   "odd-length 64-bit",
+			value:  "3ce929d0e0e4736:f067aa0ba902b7:0:1",
+			want:   trace.SpanContext{TraceID: trace.TraceID{8: 0x03, 9: 0xce, 10: 0x92, 11: 0x9d, 12: 0x0e, 13: 0x0e, 14: 0x47, 15: 0x36}, SpanID: spanID, TraceOptions: 1},
+			format: "03ce929d0e0e4736:00f067aa0ba902b7:0:1",
+		},
+		{
+			name:   "parent span ID and flags",
+			value:  "a3ce929d0e0e4736:00f067aa0ba902b7:53995c3f42cd8ad8:b",
+			want:   trace.SpanContext{TraceID: traceID64, SpanID: spanID, TraceOptions: 0xb},
+			format: "a3ce929d0e0e4736:00f067aa0ba902b7:0:b",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			sc, err := tc.options.ParseStrict(tc.value)
+			if err != nil || sc != tc.want {
+				t.Fatalf("ParseStrict(%q) = %v, %v; want %v", tc.value, sc, err, tc.want)
+			}
+			jv, ok := tc.options.Format(sc)
+			if ok != (tc.format != "") || jv != tc.format {
+				t.Fatalf("Format(%v) = %q, %v; want %q", sc, jv, ok, tc.format)
+			}
+			if !ok {
+				return
+			}
+			back, err := tc.options.ParseStrict(jv)
+			if err != nil {
+				t.Fatalf("ParseStrict(%q) = %v", jv, err)
+			}
+			// Truncated trace IDs lose their high bits.
+			want := sc
+			if tc.options.LongTraceIDs != WriteLongTraceIDs {
+				copy(want.TraceID[:8], make([]byte, 8))
+			}
+			if back != want {
+				t.Errorf("ParseStrict(%q) = %v, want %v", jv, back, want)
+			}
+		})
+	}
+}
+
+func BenchmarkJaegerExtract(b *testing.B) {
+	md := metadata.Pairs(JaegerKey, "4bf92f3577b34da6a3ce929d0e0e4736:00f067aa0ba902b7:0:1")
+	p := JaegerPropagator{}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		p.Extract(md)
+	}
+}
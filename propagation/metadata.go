@@ -0,0 +1,65 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package propagation
+
+import (
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// Lookup returns the values of key in md. gRPC metadata keys are
+// case-insensitive: grpc-go lowercases the keys it receives, but metadata
+// built by hand or forwarded by non-Go proxies may not be, so keys are also
+// matched ignoring case.
+//
+// Keys ending in "-bin" follow the same rule; their values are the decoded
+// binary values whatever the case of the key.
+func Lookup(md metadata.MD, key string) []string {
+	if vs := md[key]; len(vs) > 0 {
+		return vs
+	}
+	for k, vs := range md {
+		if len(vs) > 0 && strings.EqualFold(k, key) {
+			return vs
+		}
+	}
+	return nil
+}
+
+// CanonicalKey returns the canonical, lowercase, form of the metadata key k.
+func CanonicalKey(k string) string {
+	return strings.ToLower(k)
+}
+
+// Allowlist is a list of metadata keys, matched ignoring case. Keys ending in
+// "*" match every key starting with the rest of the key, such as "uberctx-*".
+type Allowlist []string
+
+// Allows reports whether key is in l.
+func (l Allowlist) Allows(key string) bool {
+	key = CanonicalKey(key)
+	for _, k := range l {
+		k = CanonicalKey(k)
+		if strings.HasSuffix(k, "*") {
+			if strings.HasPrefix(key, strings.TrimSuffix(k, "*")) {
+				return true
+			}
+		} else if k == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,160 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package propagation
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+
+	"go.opencensus.io/trace"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// OTSpanContextKey is the gRPC metadata key and HTTP header of the span
+// context of the OpenTracing tracers of Envoy, such as LightStep.
+const OTSpanContextKey = "x-ot-span-context"
+
+// Field numbers of the LightStep BinaryCarrier message and of its
+// BasicTracerCarrier.
+const (
+	otBasicContextField = 2
+	otTraceIDField      = 1
+	otSpanIDField       = 2
+	otSampledField      = 3
+)
+
+// FromOTSpanContext parses an x-ot-span-context value: the hexadecimal
+// encoding of a LightStep BinaryCarrier protocol buffer, as written by Envoy.
+// Its 64-bit trace ID is stored in the lower bits of the TraceID. Baggage
+// items are ignored.
+func FromOTSpanContext(v string) (sc trace.SpanContext, ok bool) {
+	sc, err := ParseOTSpanContext(v)
+	return sc, err == nil
+}
+
+// ParseOTSpanContext is like FromOTSpanContext but reports why v can't be
+// parsed with a *ParseError.
+func ParseOTSpanContext(v string) (sc trace.SpanContext, err error) {
+	b, err := hex.DecodeString(v)
+	if err != nil {
+		return sc, otSpanContextError("", v, ErrBadHex)
+	}
+	basic, ok := otField(b, otBasicContextField, protowire.BytesType)
+	if !ok {
+		return sc, otSpanContextError("", v, ErrMalformed)
+	}
+	traceID, ok := otField(basic, otTraceIDField, protowire.Fixed64Type)
+	if !ok {
+		return sc, otSpanContextError("trace ID", v, ErrMalformed)
+	}
+	spanID, ok := otField(basic, otSpanIDField, protowire.Fixed64Type)
+	if !ok {
+		return sc, otSpanContextError("span ID", v, ErrMalformed)
+	}
+	binary.BigEndian.PutUint64(sc.TraceID[8:], binary.LittleEndian.Uint64(traceID))
+	binary.BigEndian.PutUint64(sc.SpanID[:], binary.LittleEndian.Uint64(spanID))
+	if sampled, ok := otField(basic, otSampledField, protowire.VarintType); ok {
+		if n, _ := protowire.ConsumeVarint(sampled); n != 0 {
+			sc.TraceOptions = 1
+		}
+	}
+	switch {
+	case sc.TraceID == trace.TraceID{}:
+		return trace.SpanContext{}, otSpanContextError("trace ID", v, ErrZeroID)
+	case sc.SpanID == trace.SpanID{}:
+		return trace.SpanContext{}, otSpanContextError("span ID", v, ErrZeroID)
+	}
+	return sc, nil
+}
+
+// OTSpanContext formats sc as an x-ot-span-context value, writing the lower
+// 64 bits of its TraceID.
+func OTSpanContext(sc trace.SpanContext) string {
+	var basic []byte
+	basic = protowire.AppendTag(basic, otTraceIDField, protowire.Fixed64Type)
+	basic = protowire.AppendFixed64(basic, binary.BigEndian.Uint64(sc.TraceID[8:]))
+	basic = protowire.AppendTag(basic, otSpanIDField, protowire.Fixed64Type)
+	basic = protowire.AppendFixed64(basic, binary.BigEndian.Uint64(sc.SpanID[:]))
+	basic = protowire.AppendTag(basic, otSampledField, protowire.VarintType)
+	basic = protowire.AppendVarint(basic, protowire.EncodeBool(sc.IsSampled()))
+	var b []byte
+	b = protowire.AppendTag(b, otBasicContextField, protowire.BytesType)
+	b = protowire.AppendBytes(b, basic)
+	return hex.EncodeToString(b)
+}
+
+// otField returns the last value of the field num, of type typ, of the
+// protocol buffer message b: the raw bytes of fixed64 fields, the payload of
+// bytes fields and the encoded varint of varint fields. ok is false when the
+// field is missing or b is malformed.
+func otField(b []byte, num protowire.Number, typ protowire.Type) (v []byte, ok bool) {
+	for len(b) > 0 {
+		n, t, l := protowire.ConsumeTag(b)
+		if l < 0 {
+			return nil, false
+		}
+		b = b[l:]
+		l = protowire.ConsumeFieldValue(n, t, b)
+		if l < 0 {
+			return nil, false
+		}
+		if n == num && t == typ {
+			switch t {
+			case protowire.BytesType:
+				v, _ = protowire.ConsumeBytes(b[:l])
+			default:
+				v = b[:l]
+			}
+			ok = true
+		}
+		b = b[l:]
+	}
+	return v, ok
+}
+
+func otSpanContextError(field, value string, err error) error {
+	return &ParseError{Format: "ot", Field: field, Value: value, Err: err}
+}
+
+// OTSpanContextPropagator is the Propagator of the x-ot-span-context format,
+// carried by the OTSpanContextKey metadata.
+type OTSpanContextPropagator struct{}
+
+// Name returns "ot".
+func (OTSpanContextPropagator) Name() string { return "ot" }
+
+// Extract implements Propagator.
+func (OTSpanContextPropagator) Extract(md metadata.MD) (sc trace.SpanContext, ok bool) {
+	vs := Lookup(md, OTSpanContextKey)
+	if len(vs) == 0 {
+		return sc, false
+	}
+	return FromOTSpanContext(vs[0])
+}
+
+// Validate implements Validator.
+func (OTSpanContextPropagator) Validate(md metadata.MD) error {
+	if vs := Lookup(md, OTSpanContextKey); len(vs) > 0 {
+		_, err := ParseOTSpanContext(vs[0])
+		return err
+	}
+	return nil
+}
+
+// Inject implements Propagator.
+func (OTSpanContextPropagator) Inject(sc trace.SpanContext, md metadata.MD) {
+	md.Set(OTSpanContextKey, OTSpanContext(sc))
+}
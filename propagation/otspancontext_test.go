@@ -0,0 +1,80 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package propagation
+
+import (
+	"errors"
+	"testing"
+
+	"go.opencensus.io/trace"
+)
+
+func TestParseOTSpanContext(t *testing.T) {
+	traceID := trace.TraceID{8: 0x01, 9: 0x02, 10: 0x03, 11: 0x04, 12: 0x05, 13: 0x06, 14: 0x07, 15: 0x08}
+	spanID := trace.SpanID{0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18}
+	for _, tc := range []struct {
+		name    string
+		v       string
+		want    trace.SpanContext
+		wantErr error
+	}{
+		{name: "sampled", v: "12140908070605040302011118171615141312111801", want: trace.SpanContext{TraceID: traceID, SpanID: spanID, TraceOptions: 1}},
+		{name: "unsampled", v: "12140908070605040302011118171615141312111800", want: trace.SpanContext{TraceID: traceID, SpanID: spanID}},
+		{name: "no sampled field", v: "1212090807060504030201111817161514131211", want: trace.SpanContext{TraceID: traceID, SpanID: spanID}},
+		{name: "baggage ignored", v: "121c090807060504030201111817161514131211180122060a016b120176", want: trace.SpanContext{TraceID: traceID, SpanID: spanID, TraceOptions: 1}},
+		{name: "unknown field ignored", v: "0a0012140908070605040302011118171615141312111801", want: trace.SpanContext{TraceID: traceID, SpanID: spanID, TraceOptions: 1}},
+		{name: "empty", v: "", wantErr: ErrMalformed},
+		{name: "no basic context", v: "0a00", wantErr: ErrMalformed},
+		{name: "no trace ID", v: "120b1118171615141312111801", wantErr: ErrMalformed},
+		{name: "no span ID", v: "120b0908070605040302011801", wantErr: ErrMalformed},
+		{name: "truncated", v: "1214090807060504030201111817161514131211", wantErr: ErrMalformed},
+		{name: "not hex", v: "12zz", wantErr: ErrBadHex},
+		{name: "odd length", v: "121", wantErr: ErrBadHex},
+		{name: "zero trace ID", v: "12140900000000000000001118171615141312111801", wantErr: ErrZeroID},
+		{name: "zero span ID", v: "12140908070605040302011100000000000000001801", wantErr: ErrZeroID},
+	} {
+		sc, err := ParseOTSpanContext(tc.v)
+		if !errors.Is(err, tc.wantErr) || sc != tc.want {
+			t.Errorf("%s: ParseOTSpanContext(%q) = %v, %v; want %v, %v", tc.name, tc.v, sc, err, tc.want, tc.wantErr)
+		}
+	}
+}
+
+func TestOTSpanContextRoundTrip(t *testing.T) {
+	traceID := trace.TraceID{8: 0x01, 9: 0x02, 10: 0x03, 11: 0x04, 12: 0x05, 13: 0x06, 14: 0x07, 15: 0x08}
+	spanID := trace.SpanID{0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18}
+	for _, tc := range []struct {
+		sc   trace.SpanContext
+		want string
+	}{
+		{trace.SpanContext{TraceID: traceID, SpanID: spanID, TraceOptions: 1}, "12140908070605040302011118171615141312111801"},
+		{trace.SpanContext{TraceID: traceID, SpanID: spanID}, "12140908070605040302011118171615141312111800"},
+	} {
+		v := OTSpanContext(tc.sc)
+		if v != tc.want {
+			t.Errorf("OTSpanContext(%v) = %q, want %q", tc.sc, v, tc.want)
+		}
+		if got, err := ParseOTSpanContext(v); err != nil || got != tc.sc {
+			t.Errorf("ParseOTSpanContext(OTSpanContext(%v)) = %v, %v", tc.sc, got, err)
+		}
+	}
+
+	// Only the lower 64 bits of the trace ID are carried.
+	sc := trace.SpanContext{TraceID: trace.TraceID{0: 0xff, 15: 1}, SpanID: spanID}
+	want := trace.SpanContext{TraceID: trace.TraceID{15: 1}, SpanID: spanID}
+	if got, err := ParseOTSpanContext(OTSpanContext(sc)); err != nil || got != want {
+		t.Errorf("ParseOTSpanContext(OTSpanContext(%v)) = %v, %v; want %v", sc, got, err, want)
+	}
+}
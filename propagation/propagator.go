@@ -0,0 +1,286 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package propagation
+
+import (
+	"go.opencensus.io/trace"
+	"go.opencensus.io/trace/propagation"
+	"google.golang.org/grpc/metadata"
+)
+
+// Propagator reads and writes trace contexts in one format of gRPC metadata.
+type Propagator interface {
+	// Extract returns the SpanContext carried by md. ok is false when md
+	// carries no valid SpanContext in the format of the Propagator.
+	Extract(md metadata.MD) (sc trace.SpanContext, ok bool)
+
+	// Inject writes sc to md, replacing any SpanContext already written in
+	// the format of the Propagator.
+	Inject(sc trace.SpanContext, md metadata.MD)
+}
+
+// Named is implemented by the Propagators reporting the name of their format,
+// such as "jaeger", in stats, logs and decisions.
+type Named interface {
+	Name() string
+}
+
+// Validator is implemented by the Propagators telling why md carries an
+// invalid SpanContext. Validate returns nil when md carries a valid
+// SpanContext or none in the format of the Propagator.
+type Validator interface {
+	Validate(md metadata.MD) error
+}
+
+// DuplicateValidator is implemented by the Propagators reading every value of
+// their metadata, such as BinaryPropagator, when a client appended it twice.
+// MalformedDuplicates returns why the values of md that couldn't be parsed
+// were ignored, when md has several values and one of them was used.
+type DuplicateValidator interface {
+	MalformedDuplicates(md metadata.MD) []error
+}
+
+// NameOf returns the name of the format of p, or "custom" when p isn't Named.
+func NameOf(p Propagator) string {
+	if n, ok := p.(Named); ok {
+		return n.Name()
+	}
+	return "custom"
+}
+
+// BinaryPropagator is the Propagator of the OpenCensus binary format, carried
+// by the BinaryKey metadata. When the key has several values, such as when a
+// client appended it twice, the first value that can be parsed is used.
+type BinaryPropagator struct {
+	// DecodeBase64 may be set to true to also accept values that were
+	// base64 encoded by proxies, see FromBase64Binary.
+	DecodeBase64 bool
+}
+
+// Name returns "binary".
+func (BinaryPropagator) Name() string { return "binary" }
+
+// Extract implements Propagator.
+func (p BinaryPropagator) Extract(md metadata.MD) (sc trace.SpanContext, ok bool) {
+	for _, v := range Lookup(md, BinaryKey) {
+		if sc, ok = p.parse(v); ok {
+			return sc, true
+		}
+	}
+	return trace.SpanContext{}, false
+}
+
+func (p BinaryPropagator) parse(v string) (sc trace.SpanContext, ok bool) {
+	if sc, ok = FromBinary([]byte(v)); !ok && p.DecodeBase64 {
+		sc, ok = FromBase64Binary(v)
+	}
+	return sc, ok
+}
+
+// Validate implements Validator.
+func (p BinaryPropagator) Validate(md metadata.MD) error {
+	if _, ok := p.Extract(md); ok {
+		return nil
+	}
+	if vs := Lookup(md, BinaryKey); len(vs) > 0 {
+		_, err := ParseBinary([]byte(vs[0]))
+		return err
+	}
+	return nil
+}
+
+// MalformedDuplicates implements DuplicateValidator.
+func (p BinaryPropagator) MalformedDuplicates(md metadata.MD) []error {
+	vs := Lookup(md, BinaryKey)
+	if len(vs) < 2 {
+		return nil
+	}
+	var errs []error
+	for _, v := range vs {
+		if _, ok := p.parse(v); !ok {
+			_, err := ParseBinary([]byte(v))
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == len(vs) {
+		// None was used: Validate reports the failure.
+		return nil
+	}
+	return errs
+}
+
+// Inject implements Propagator.
+func (BinaryPropagator) Inject(sc trace.SpanContext, md metadata.MD) {
+	md.Set(BinaryKey, string(propagation.Binary(sc)))
+}
+
+// JaegerPropagator is the Propagator of the Jaeger format, carried by the
+// JaegerKey metadata.
+type JaegerPropagator struct {
+	Options JaegerOptions
+}
+
+// Name returns "jaeger".
+func (JaegerPropagator) Name() string { return "jaeger" }
+
+// Extract implements Propagator.
+func (p JaegerPropagator) Extract(md metadata.MD) (sc trace.SpanContext, ok bool) {
+	vs := Lookup(md, JaegerKey)
+	if len(vs) == 0 {
+		return sc, false
+	}
+	return p.Options.Parse(vs[0])
+}
+
+// Validate implements Validator.
+func (p JaegerPropagator) Validate(md metadata.MD) error {
+	if _, ok := p.Extract(md); ok {
+		return nil
+	}
+	if vs := Lookup(md, JaegerKey); len(vs) > 0 {
+		_, err := p.Options.ParseStrict(vs[0])
+		return err
+	}
+	return nil
+}
+
+// Inject implements Propagator. Span contexts that p.Options don't allow to
+// write are not injected.
+func (p JaegerPropagator) Inject(sc trace.SpanContext, md metadata.MD) {
+	md.Delete(JaegerKey)
+	if jv, ok := p.Options.Format(sc); ok {
+		md.Set(JaegerKey, jv)
+	}
+}
+
+// TraceContextPropagator is the Propagator of the W3C Trace Context format,
+// carried by the TraceparentKey and TracestateKey metadata.
+type TraceContextPropagator struct{}
+
+// Name returns "w3c".
+func (TraceContextPropagator) Name() string { return "w3c" }
+
+// Extract implements Propagator. The tracestate is set as the Tracestate of
+// sc.
+func (p TraceContextPropagator) Extract(md metadata.MD) (sc trace.SpanContext, ok bool) {
+	vs := Lookup(md, TraceparentKey)
+	if len(vs) == 0 {
+		return sc, false
+	}
+	if sc, ok = FromTraceparent(vs[0]); ok {
+		sc.Tracestate = FromTracestate(Lookup(md, TracestateKey))
+	}
+	return sc, ok
+}
+
+// Validate implements Validator.
+func (TraceContextPropagator) Validate(md metadata.MD) error {
+	if vs := Lookup(md, TraceparentKey); len(vs) > 0 {
+		_, err := ParseTraceparent(vs[0])
+		return err
+	}
+	return nil
+}
+
+// Inject implements Propagator.
+func (TraceContextPropagator) Inject(sc trace.SpanContext, md metadata.MD) {
+	md.Set(TraceparentKey, Traceparent(sc))
+	md.Delete(TracestateKey)
+	if ts := Tracestate(sc.Tracestate); ts != "" {
+		md.Set(TracestateKey, ts)
+	}
+}
+
+// B3Propagator is the Propagator of the B3 multi-header format.
+type B3Propagator struct {
+	// TraceID64 may be set to true to write only the lower 64 bits of trace
+	// IDs, for Zipkin backends rejecting 128-bit trace IDs.
+	TraceID64 bool
+}
+
+// Name returns "b3".
+func (B3Propagator) Name() string { return "b3" }
+
+// Extract implements Propagator.
+func (p B3Propagator) Extract(md metadata.MD) (sc trace.SpanContext, ok bool) {
+	sc, err := p.parse(md)
+	return sc, err == nil && IsValid(sc)
+}
+
+// Validate implements Validator.
+func (p B3Propagator) Validate(md metadata.MD) error {
+	_, err := p.parse(md)
+	return err
+}
+
+func (B3Propagator) parse(md metadata.MD) (sc trace.SpanContext, err error) {
+	traceID := Lookup(md, B3TraceIDKey)
+	if len(traceID) == 0 {
+		return sc, nil
+	}
+	return ParseB3(traceID[0], firstValue(md, B3SpanIDKey), firstValue(md, B3SampledKey), firstValue(md, B3FlagsKey))
+}
+
+// Inject implements Propagator.
+func (p B3Propagator) Inject(sc trace.SpanContext, md metadata.MD) {
+	md.Delete(B3ParentSpanIDKey)
+	md.Delete(B3FlagsKey)
+	md.Set(B3TraceIDKey, b3TraceID(sc.TraceID, p.TraceID64))
+	md.Set(B3SpanIDKey, sc.SpanID.String())
+	md.Set(B3SampledKey, B3Sampled(sc))
+}
+
+// B3SinglePropagator is the Propagator of the B3 single header format,
+// carried by the B3Key metadata.
+type B3SinglePropagator struct {
+	// TraceID64 may be set to true to write only the lower 64 bits of trace
+	// IDs, for Zipkin backends rejecting 128-bit trace IDs.
+	TraceID64 bool
+}
+
+// Name returns "b3single", telling it apart from the B3 multi-header format
+// of B3Propagator.
+func (B3SinglePropagator) Name() string { return "b3single" }
+
+// Extract implements Propagator.
+func (B3SinglePropagator) Extract(md metadata.MD) (sc trace.SpanContext, ok bool) {
+	vs := Lookup(md, B3Key)
+	if len(vs) == 0 {
+		return sc, false
+	}
+	return FromB3Single(vs[0])
+}
+
+// Validate implements Validator.
+func (B3SinglePropagator) Validate(md metadata.MD) error {
+	if vs := Lookup(md, B3Key); len(vs) > 0 {
+		_, err := ParseB3Single(vs[0])
+		return err
+	}
+	return nil
+}
+
+// Inject implements Propagator.
+func (p B3SinglePropagator) Inject(sc trace.SpanContext, md metadata.MD) {
+	md.Set(B3Key, b3TraceID(sc.TraceID, p.TraceID64)+"-"+sc.SpanID.String()+"-"+B3Sampled(sc))
+}
+
+// firstValue returns the first value of key in md, or an empty string.
+func firstValue(md metadata.MD, key string) string {
+	if vs := Lookup(md, key); len(vs) > 0 {
+		return vs[0]
+	}
+	return ""
+}
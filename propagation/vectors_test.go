@@ -0,0 +1,59 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package propagation_test
+
+import (
+	"testing"
+
+	propag "github.com/akhenakh/ocgrpc_propagation/propagation"
+	"github.com/akhenakh/ocgrpc_propagation/propagationtest"
+	"go.opencensus.io/trace"
+	"google.golang.org/grpc/metadata"
+)
+
+// extractor returns the parse function of RunVectors that has p extract the
+// value from the metadata key.
+func extractor(p propag.Propagator, key string) func(string) (trace.SpanContext, bool) {
+	return func(v string) (trace.SpanContext, bool) {
+		return p.Extract(metadata.Pairs(key, v))
+	}
+}
+
+func fromBinary(v string) (trace.SpanContext, bool) {
+	return propag.FromBinary([]byte(v))
+}
+
+func TestVectors(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		vectors []propagationtest.Vector
+		parse   func(string) (trace.SpanContext, bool)
+		p       propag.Propagator
+		key     string
+	}{
+		{"jaeger", propagationtest.JaegerVectors, propag.FromJaeger, propag.JaegerPropagator{}, propag.JaegerKey},
+		{"w3c", propagationtest.W3CVectors, propag.FromTraceparent, propag.TraceContextPropagator{}, propag.TraceparentKey},
+		{"b3single", propagationtest.B3Vectors, propag.FromB3Single, propag.B3SinglePropagator{}, propag.B3Key},
+		{"binary", propagationtest.BinaryVectors, fromBinary, propag.BinaryPropagator{}, propag.BinaryKey},
+		{"binary/base64", propagationtest.BinaryVectors, fromBinary, propag.BinaryPropagator{DecodeBase64: true}, propag.BinaryKey},
+	} {
+		t.Run(tc.name+"/parse", func(t *testing.T) {
+			propagationtest.RunVectors(t, tc.vectors, tc.parse)
+		})
+		t.Run(tc.name+"/extract", func(t *testing.T) {
+			propagationtest.RunVectors(t, tc.vectors, extractor(tc.p, tc.key))
+		})
+	}
+}
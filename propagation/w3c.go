@@ -0,0 +1,183 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package propagation
+
+import (
+	"encoding/hex"
+	"strings"
+
+	"go.opencensus.io/trace"
+	"go.opencensus.io/trace/tracestate"
+)
+
+// W3C Trace Context and Baggage metadata keys and limits.
+const (
+	// TraceparentKey is the gRPC metadata key and HTTP header of the W3C
+	// Trace Context format.
+	TraceparentKey = "traceparent"
+
+	// TracestateKey is the gRPC metadata key and HTTP header carrying the
+	// vendor specific state accompanying TraceparentKey.
+	TracestateKey = "tracestate"
+
+	// BaggageKey is the gRPC metadata key and HTTP header of the W3C
+	// Baggage format.
+	BaggageKey = "baggage"
+
+	maxTracestateLength = 512
+)
+
+// FromTraceparent parses a W3C traceparent value of the form
+// {version}-{trace-id}-{parent-id}-{trace-flags}.
+func FromTraceparent(tp string) (sc trace.SpanContext, ok bool) {
+	sc, err := ParseTraceparent(tp)
+	return sc, err == nil
+}
+
+// ParseTraceparent is like FromTraceparent but reports why tp can't be parsed
+// with a *ParseError.
+//
+// Versions other than 00 are parsed as version 00, ignoring any additional
+// field, as the specification requires.
+func ParseTraceparent(tp string) (sc trace.SpanContext, err error) {
+	v := strings.TrimSpace(tp)
+	if len(v) < traceparentLength || v[traceparentTraceID-1] != '-' || v[traceparentParentID-1] != '-' || v[traceparentFlags-1] != '-' {
+		return sc, w3cError("", tp, ErrMalformed)
+	}
+	version := v[:traceparentTraceID-1]
+	switch {
+	case !isLowerHex(version) || version == "ff":
+		return sc, w3cError("version", version, ErrBadVersion)
+	case len(v) > traceparentLength && (version == "00" || v[traceparentLength] != '-'):
+		return sc, w3cError("", tp, ErrMalformed)
+	}
+
+	traceID := v[traceparentTraceID : traceparentParentID-1]
+	if err := decodeLowerHex(sc.TraceID[:], traceID); err != nil {
+		return trace.SpanContext{}, w3cError("trace ID", traceID, err)
+	}
+	parentID := v[traceparentParentID : traceparentFlags-1]
+	if err := decodeLowerHex(sc.SpanID[:], parentID); err != nil {
+		return trace.SpanContext{}, w3cError("parent ID", parentID, err)
+	}
+	var flags [1]byte
+	if err := decodeLowerHex(flags[:], v[traceparentFlags:traceparentLength]); err != nil {
+		return trace.SpanContext{}, w3cError("flags", v[traceparentFlags:traceparentLength], ErrBadFlags)
+	}
+	sc.TraceOptions = trace.TraceOptions(flags[0] & 1)
+
+	switch {
+	case sc.TraceID == trace.TraceID{}:
+		return trace.SpanContext{}, w3cError("trace ID", traceID, ErrZeroID)
+	case sc.SpanID == trace.SpanID{}:
+		return trace.SpanContext{}, w3cError("parent ID", parentID, ErrZeroID)
+	}
+	return sc, nil
+}
+
+// Offsets of the fields of a traceparent value, and length of a version 00
+// value. Later versions may append fields after a dash.
+const (
+	traceparentTraceID  = len("00-")
+	traceparentParentID = traceparentTraceID + 2*len(trace.TraceID{}) + len("-")
+	traceparentFlags    = traceparentParentID + 2*len(trace.SpanID{}) + len("-")
+	traceparentLength   = traceparentFlags + len("00")
+)
+
+// Traceparent formats sc as a version 00 W3C traceparent value.
+func Traceparent(sc trace.SpanContext) string {
+	var buf [len("00-") + len(trace.TraceID{})*2 + 1 + len(trace.SpanID{})*2 + len("-00")]byte
+	n := copy(buf[:], "00-")
+	n += hex.Encode(buf[n:], sc.TraceID[:])
+	buf[n] = '-'
+	n++
+	n += hex.Encode(buf[n:], sc.SpanID[:])
+	n += copy(buf[n:], "-0")
+	buf[n] = '0' + byte(sc.TraceOptions&1)
+	return string(buf[:])
+}
+
+// FromTracestate parses the W3C tracestate values vs, in order. It returns nil
+// if they are empty or invalid, in which case the state must be discarded.
+func FromTracestate(vs []string) *tracestate.Tracestate {
+	h := strings.Join(vs, ",")
+	if strings.TrimSpace(h) == "" || len(h) > maxTracestateLength {
+		return nil
+	}
+	var entries []tracestate.Entry
+	for _, member := range strings.Split(h, ",") {
+		member = strings.Trim(member, " \t")
+		if member == "" {
+			continue
+		}
+		kv := strings.SplitN(member, "=", 2)
+		if len(kv) != 2 {
+			return nil
+		}
+		entries = append(entries, tracestate.Entry{Key: kv[0], Value: kv[1]})
+	}
+	ts, err := tracestate.New(nil, entries...)
+	if err != nil {
+		return nil
+	}
+	return ts
+}
+
+// Tracestate formats ts as a W3C tracestate value. It returns an empty string
+// if ts is nil, empty, or too long to be propagated.
+func Tracestate(ts *tracestate.Tracestate) string {
+	if ts == nil {
+		return ""
+	}
+	members := make([]string, 0, len(ts.Entries()))
+	for _, e := range ts.Entries() {
+		members = append(members, e.Key+"="+e.Value)
+	}
+	h := strings.Join(members, ",")
+	if len(h) > maxTracestateLength {
+		return ""
+	}
+	return h
+}
+
+// decodeLowerHex decodes the lowercase hexadecimal s to dst, which s must fill
+// exactly.
+func decodeLowerHex(dst []byte, s string) error {
+	if len(s) != 2*len(dst) {
+		return ErrBadLength
+	}
+	if !isLowerHex(s) {
+		return ErrBadHex
+	}
+	for i := range dst {
+		hi, _ := fromHexChar(s[2*i])
+		lo, _ := fromHexChar(s[2*i+1])
+		dst[i] = hi<<4 | lo
+	}
+	return nil
+}
+
+func isLowerHex(s string) bool {
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func w3cError(field, value string, err error) error {
+	return &ParseError{Format: "w3c", Field: field, Value: value, Err: err}
+}
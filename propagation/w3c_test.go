@@ -0,0 +1,75 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package propagation
+
+import (
+	"errors"
+	"testing"
+
+	"go.opencensus.io/trace"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	want := trace.SpanContext{
+		TraceID:      trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:       trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		TraceOptions: 1,
+	}
+	for _, tc := range []struct {
+		tp      string
+		wantErr error
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", nil},
+		{" 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01 ", nil},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-03", nil},
+		{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", nil},
+		{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future", nil},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future", ErrMalformed},
+		{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01future", ErrMalformed},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7", ErrMalformed},
+		{"00-a3ce929d0e0e4736-00f067aa0ba902b7-01", ErrMalformed},
+		{"00_4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", ErrMalformed},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", ErrBadVersion},
+		{"0g-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", ErrBadVersion},
+		{"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", ErrBadHex},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-0x", ErrBadFlags},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", ErrZeroID},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", ErrZeroID},
+	} {
+		sc, err := ParseTraceparent(tc.tp)
+		if !errors.Is(err, tc.wantErr) {
+			t.Errorf("ParseTraceparent(%q) error = %v, want %v", tc.tp, err, tc.wantErr)
+			continue
+		}
+		if err == nil && (sc.TraceID != want.TraceID || sc.SpanID != want.SpanID || !sc.IsSampled()) {
+			t.Errorf("ParseTraceparent(%q) = %v, want %v", tc.tp, sc, want)
+		}
+	}
+}
+
+func TestTraceparentRoundTrip(t *testing.T) {
+	sc := trace.SpanContext{TraceID: trace.TraceID{15: 1}, SpanID: trace.SpanID{0: 0xff}}
+	got, err := ParseTraceparent(Traceparent(sc))
+	if err != nil || got != sc {
+		t.Errorf("ParseTraceparent(Traceparent(%v)) = %v, %v", sc, got, err)
+	}
+}
+
+func BenchmarkParseTraceparent(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	}
+}
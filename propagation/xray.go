@@ -0,0 +1,138 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package propagation
+
+import (
+	"encoding/hex"
+	"strings"
+
+	"go.opencensus.io/trace"
+	"google.golang.org/grpc/metadata"
+)
+
+// XRayKey is the gRPC metadata key and HTTP header of the AWS X-Ray format,
+// as set by Application Load Balancers.
+const XRayKey = "x-amzn-trace-id"
+
+// FromXRay parses an AWS X-Ray trace header of the form
+// Root=1-{epoch}-{unique-id};Parent={span-id};Sampled={0|1}.
+//
+// Load balancers only set Root: the returned SpanContext then has a zero
+// SpanID, and spans started with it as remote parent are the roots of the
+// X-Ray trace.
+func FromXRay(v string) (sc trace.SpanContext, ok bool) {
+	sc, err := ParseXRay(v)
+	return sc, err == nil
+}
+
+// ParseXRay is like FromXRay but reports why v can't be parsed with a
+// *ParseError. Fields other than Root, Parent and Sampled are ignored.
+func ParseXRay(v string) (sc trace.SpanContext, err error) {
+	var root, parent, sampled string
+	for _, field := range strings.Split(v, ";") {
+		k, fv, _ := strings.Cut(strings.TrimSpace(field), "=")
+		switch k {
+		case "Root":
+			root = fv
+		case "Parent":
+			parent = fv
+		case "Sampled":
+			sampled = fv
+		}
+	}
+	if root == "" {
+		return sc, xrayError("", v, ErrMalformed)
+	}
+
+	parts := strings.Split(root, "-")
+	switch {
+	case len(parts) != 3:
+		return sc, xrayError("root", root, ErrMalformed)
+	case parts[0] != "1":
+		return sc, xrayError("root", root, ErrBadVersion)
+	case len(parts[1]) != 8 || len(parts[2]) != 24:
+		return sc, xrayError("root", root, ErrBadLength)
+	}
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1]+parts[2])); err != nil {
+		return trace.SpanContext{}, xrayError("root", root, ErrBadHex)
+	}
+	if sc.TraceID == (trace.TraceID{}) {
+		return trace.SpanContext{}, xrayError("root", root, ErrZeroID)
+	}
+
+	if parent != "" {
+		if err := decodeB3SpanID(&sc.SpanID, parent); err != nil {
+			return trace.SpanContext{}, xrayError("parent", parent, err)
+		}
+	}
+
+	// "?" asks the receiver to decide, which is left to the local sampler.
+	switch sampled {
+	case "1":
+		sc.TraceOptions = 1
+	case "", "0", "?":
+	default:
+		return trace.SpanContext{}, xrayError("sampled", sampled, ErrBadFlags)
+	}
+	return sc, nil
+}
+
+// XRay formats sc as an AWS X-Ray trace header.
+func XRay(sc trace.SpanContext) string {
+	id := sc.TraceID.String()
+	v := "Root=1-" + id[:8] + "-" + id[8:]
+	if sc.SpanID != (trace.SpanID{}) {
+		v += ";Parent=" + sc.SpanID.String()
+	}
+	if sc.IsSampled() {
+		return v + ";Sampled=1"
+	}
+	return v + ";Sampled=0"
+}
+
+func xrayError(field, value string, err error) error {
+	return &ParseError{Format: "xray", Field: field, Value: value, Err: err}
+}
+
+// XRayPropagator is the Propagator of the AWS X-Ray format, carried by the
+// XRayKey metadata. Unlike other Propagators, it extracts span contexts
+// without span ID, see FromXRay.
+type XRayPropagator struct{}
+
+// Name returns "xray".
+func (XRayPropagator) Name() string { return "xray" }
+
+// Extract implements Propagator.
+func (XRayPropagator) Extract(md metadata.MD) (sc trace.SpanContext, ok bool) {
+	vs := Lookup(md, XRayKey)
+	if len(vs) == 0 {
+		return sc, false
+	}
+	return FromXRay(vs[0])
+}
+
+// Validate implements Validator.
+func (XRayPropagator) Validate(md metadata.MD) error {
+	if vs := Lookup(md, XRayKey); len(vs) > 0 {
+		_, err := ParseXRay(vs[0])
+		return err
+	}
+	return nil
+}
+
+// Inject implements Propagator.
+func (XRayPropagator) Inject(sc trace.SpanContext, md metadata.MD) {
+	md.Set(XRayKey, XRay(sc))
+}
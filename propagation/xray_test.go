@@ -0,0 +1,77 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package propagation
+
+import (
+	"errors"
+	"testing"
+
+	"go.opencensus.io/trace"
+)
+
+func TestParseXRay(t *testing.T) {
+	traceID := trace.TraceID{0x57, 0x59, 0xe9, 0x88, 0xbd, 0x86, 0x2e, 0x3f, 0xe1, 0xbe, 0x46, 0xa9, 0x94, 0x27, 0x27, 0x93}
+	spanID := trace.SpanID{0x53, 0x99, 0x5c, 0x3f, 0x42, 0xcd, 0x8a, 0xd8}
+	for _, tc := range []struct {
+		v       string
+		want    trace.SpanContext
+		wantErr error
+	}{
+		{v: "Root=1-5759e988-bd862e3fe1be46a994272793", want: trace.SpanContext{TraceID: traceID}},
+		{v: "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1", want: trace.SpanContext{TraceID: traceID, SpanID: spanID, TraceOptions: 1}},
+		{v: "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=0", want: trace.SpanContext{TraceID: traceID, SpanID: spanID}},
+		{v: "Root=1-5759e988-bd862e3fe1be46a994272793;Sampled=?", want: trace.SpanContext{TraceID: traceID}},
+		{v: "Sampled=1; Parent=53995c3f42cd8ad8; Root=1-5759e988-bd862e3fe1be46a994272793", want: trace.SpanContext{TraceID: traceID, SpanID: spanID, TraceOptions: 1}},
+		{v: "Self=1-67891234-12456789abcdef012345678;Root=1-5759e988-bd862e3fe1be46a994272793;Lineage=a87bd80c:1", want: trace.SpanContext{TraceID: traceID}},
+		{v: "", wantErr: ErrMalformed},
+		{v: "Parent=53995c3f42cd8ad8;Sampled=1", wantErr: ErrMalformed},
+		{v: "Root=", wantErr: ErrMalformed},
+		{v: "Root=1-5759e988bd862e3fe1be46a994272793", wantErr: ErrMalformed},
+		{v: "Root=1-5759e988-bd862e3fe1be46a9-94272793", wantErr: ErrMalformed},
+		{v: "Root=2-5759e988-bd862e3fe1be46a994272793", wantErr: ErrBadVersion},
+		{v: "Root=1-5759e98-bd862e3fe1be46a994272793", wantErr: ErrBadLength},
+		{v: "Root=1-5759e988-bd862e3fe1be46a99427279", wantErr: ErrBadLength},
+		{v: "Root=1-5759e98z-bd862e3fe1be46a994272793", wantErr: ErrBadHex},
+		{v: "Root=1-00000000-000000000000000000000000", wantErr: ErrZeroID},
+		{v: "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8adz", wantErr: ErrBadHex},
+		{v: "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=0000000000000000", wantErr: ErrZeroID},
+		{v: "Root=1-5759e988-bd862e3fe1be46a994272793;Sampled=true", wantErr: ErrBadFlags},
+	} {
+		sc, err := ParseXRay(tc.v)
+		if !errors.Is(err, tc.wantErr) || sc != tc.want {
+			t.Errorf("ParseXRay(%q) = %v, %v; want %v, %v", tc.v, sc, err, tc.want, tc.wantErr)
+		}
+	}
+}
+
+func TestXRayRoundTrip(t *testing.T) {
+	traceID := trace.TraceID{0x57, 0x59, 0xe9, 0x88, 0xbd, 0x86, 0x2e, 0x3f, 0xe1, 0xbe, 0x46, 0xa9, 0x94, 0x27, 0x27, 0x93}
+	for _, tc := range []struct {
+		sc   trace.SpanContext
+		want string
+	}{
+		{trace.SpanContext{TraceID: traceID, SpanID: trace.SpanID{0: 0xff}, TraceOptions: 1}, "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=ff00000000000000;Sampled=1"},
+		{trace.SpanContext{TraceID: traceID, SpanID: trace.SpanID{7: 1}}, "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=0000000000000001;Sampled=0"},
+		{trace.SpanContext{TraceID: traceID}, "Root=1-5759e988-bd862e3fe1be46a994272793;Sampled=0"},
+	} {
+		v := XRay(tc.sc)
+		if v != tc.want {
+			t.Errorf("XRay(%v) = %q, want %q", tc.sc, v, tc.want)
+		}
+		if got, err := ParseXRay(v); err != nil || got != tc.sc {
+			t.Errorf("ParseXRay(XRay(%v)) = %v, %v", tc.sc, got, err)
+		}
+	}
+}
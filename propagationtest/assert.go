@@ -0,0 +1,62 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package propagationtest
+
+import (
+	"testing"
+
+	"go.opencensus.io/trace"
+)
+
+// AssertRemoteParent fails t unless one of spans belongs to the trace traceID
+// and has a remote parent, as the server span of an RPC carrying traceID does.
+// It returns the first such span.
+func AssertRemoteParent(t testing.TB, spans []*trace.SpanData, traceID trace.TraceID) *trace.SpanData {
+	t.Helper()
+	for _, s := range spans {
+		if s.TraceID == traceID && s.HasRemoteParent {
+			return s
+		}
+	}
+	t.Errorf("no span with a remote parent in trace %v among %d spans", traceID, len(spans))
+	return nil
+}
+
+// AssertLinked fails t unless one of spans links to the trace traceID, as the
+// server span of a public endpoint receiving traceID does. It returns the
+// first such span.
+func AssertLinked(t testing.TB, spans []*trace.SpanData, traceID trace.TraceID) *trace.SpanData {
+	t.Helper()
+	for _, s := range spans {
+		for _, l := range s.Links {
+			if l.TraceID == traceID {
+				return s
+			}
+		}
+	}
+	t.Errorf("no span linked to trace %v among %d spans", traceID, len(spans))
+	return nil
+}
+
+// AssertNotInTrace fails t if one of spans belongs to the trace traceID, as
+// happens when tracing metadata that should have been ignored is propagated.
+func AssertNotInTrace(t testing.TB, spans []*trace.SpanData, traceID trace.TraceID) {
+	t.Helper()
+	for _, s := range spans {
+		if s.TraceID == traceID {
+			t.Errorf("span %q unexpectedly in trace %v", s.Name, traceID)
+		}
+	}
+}
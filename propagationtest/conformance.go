@@ -0,0 +1,118 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package propagationtest
+
+import (
+	"testing"
+
+	propag "github.com/akhenakh/ocgrpc_propagation/propagation"
+	"go.opencensus.io/trace"
+	"google.golang.org/grpc/metadata"
+)
+
+// Span contexts exercised by RunPropagatorTests.
+var (
+	sampledSpanContext = trace.SpanContext{
+		TraceID:      trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:       trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		TraceOptions: 1,
+	}
+	unsampledSpanContext = trace.SpanContext{
+		TraceID: sampledSpanContext.TraceID,
+		SpanID:  sampledSpanContext.SpanID,
+	}
+	shortTraceIDSpanContext = trace.SpanContext{
+		TraceID:      trace.TraceID{8: 0x0e, 9: 0x0e, 10: 0x47, 11: 0x36, 12: 0x4b, 13: 0xf9, 14: 0x2f, 15: 0x35},
+		SpanID:       trace.SpanID{7: 0x01},
+		TraceOptions: 1,
+	}
+)
+
+// malformedValues replace the values written by the Propagator under test.
+var malformedValues = []string{
+	"",
+	"garbage",
+	":::",
+	"\x00\x00\x00\x00",
+	"00000000000000000000000000000000:0000000000000000:0:1",
+}
+
+// RunPropagatorTests runs the conformance tests every Propagator must pass:
+//   - injected span contexts, sampled or not, are extracted unchanged,
+//   - 64-bit trace IDs keep their high bits zero,
+//   - empty metadata and invalid span contexts are reported as absent,
+//   - malformed values never yield an invalid SpanContext.
+func RunPropagatorTests(t *testing.T, p propag.Propagator) {
+	t.Run("RoundTrip", func(t *testing.T) {
+		for _, sc := range []trace.SpanContext{sampledSpanContext, unsampledSpanContext} {
+			assertRoundTrip(t, p, sc)
+		}
+	})
+
+	t.Run("64BitTraceID", func(t *testing.T) {
+		assertRoundTrip(t, p, shortTraceIDSpanContext)
+	})
+
+	t.Run("EmptyMetadata", func(t *testing.T) {
+		if sc, ok := p.Extract(metadata.MD{}); ok {
+			t.Errorf("Extract(empty metadata) = %v, want none", sc)
+		}
+	})
+
+	t.Run("InvalidSpanContext", func(t *testing.T) {
+		md := metadata.MD{}
+		p.Inject(trace.SpanContext{}, md)
+		if sc, ok := p.Extract(md); ok {
+			t.Errorf("Extract(%v) = %v, want none", md, sc)
+		}
+	})
+
+	t.Run("Overwrite", func(t *testing.T) {
+		md := metadata.MD{}
+		p.Inject(shortTraceIDSpanContext, md)
+		p.Inject(sampledSpanContext, md)
+		if sc, ok := p.Extract(md); !ok || sc != sampledSpanContext {
+			t.Errorf("Extract after two Injects = %v, %v; want %v, true", sc, ok, sampledSpanContext)
+		}
+	})
+
+	t.Run("Malformed", func(t *testing.T) {
+		written := metadata.MD{}
+		p.Inject(sampledSpanContext, written)
+		if len(written) == 0 {
+			t.Fatal("Inject wrote no metadata")
+		}
+		for _, v := range malformedValues {
+			md := metadata.MD{}
+			for k := range written {
+				md.Set(k, v)
+			}
+			if sc, ok := p.Extract(md); ok && !propag.IsValid(sc) {
+				t.Errorf("Extract(%v) = %v, true; want a valid SpanContext or none", md, sc)
+			}
+		}
+	})
+}
+
+// assertRoundTrip fails t unless sc injected by p is extracted unchanged.
+func assertRoundTrip(t *testing.T, p propag.Propagator, sc trace.SpanContext) {
+	t.Helper()
+	md := metadata.MD{}
+	p.Inject(sc, md)
+	got, ok := p.Extract(md)
+	if !ok || got != sc {
+		t.Errorf("Extract(Inject(%v)) = %v, %v; want %v, true", sc, got, ok, sc)
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package propagationtest contains helpers to test the trace propagation of
+// services instrumented with the ocgrpc package: an in-memory span exporter,
+// builders of incoming contexts carrying trace metadata, and assertions on the
+// exported spans.
+package propagationtest
+
+import (
+	"sync"
+	"testing"
+
+	"go.opencensus.io/trace"
+)
+
+// Exporter is a trace.Exporter keeping the exported spans in memory.
+type Exporter struct {
+	mu    sync.Mutex
+	spans []*trace.SpanData
+}
+
+// NewExporter returns an empty Exporter. It must be registered with
+// trace.RegisterExporter to receive spans; see Install.
+func NewExporter() *Exporter {
+	return &Exporter{}
+}
+
+// Install registers a new Exporter for the duration of the test t and returns
+// it. Only sampled spans are exported: use trace.AlwaysSample in the
+// StartOptions of the handlers under test.
+func Install(t testing.TB) *Exporter {
+	e := NewExporter()
+	trace.RegisterExporter(e)
+	t.Cleanup(func() { trace.UnregisterExporter(e) })
+	return e
+}
+
+// ExportSpan implements trace.Exporter.
+func (e *Exporter) ExportSpan(s *trace.SpanData) {
+	e.mu.Lock()
+	e.spans = append(e.spans, s)
+	e.mu.Unlock()
+}
+
+// Spans returns the spans exported so far, in the order they ended.
+func (e *Exporter) Spans() []*trace.SpanData {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]*trace.SpanData(nil), e.spans...)
+}
+
+// Reset drops the spans exported so far.
+func (e *Exporter) Reset() {
+	e.mu.Lock()
+	e.spans = nil
+	e.mu.Unlock()
+}
@@ -0,0 +1,127 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package propagationtest
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+
+	propag "github.com/akhenakh/ocgrpc_propagation/propagation"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/stats"
+)
+
+// FaultInjector is a client stats.Handler damaging the trace metadata written
+// by Handler, to check that services and dashboards degrade gracefully when
+// propagation breaks. It is meant for tests only.
+//
+// Each metadata key is dropped, corrupted or duplicated independently, with
+// the configured probabilities, checked in that order.
+type FaultInjector struct {
+	// Handler is the instrumented client handler, typically an
+	// *ocgrpc.ClientHandler.
+	Handler stats.Handler
+
+	// Keys are the metadata keys damaged. It defaults to the binary and
+	// Jaeger keys.
+	Keys []string
+
+	// DropProbability is the probability of removing a key.
+	DropProbability float64
+
+	// CorruptProbability is the probability of truncating the values of a
+	// key, or flipping one of their bytes.
+	CorruptProbability float64
+
+	// DuplicateProbability is the probability of repeating the first value
+	// of a key.
+	DuplicateProbability float64
+
+	// Rand is the source of randomness. It defaults to a source seeded with
+	// 1, so runs are reproducible.
+	Rand *rand.Rand
+
+	mu sync.Mutex
+}
+
+func (f *FaultInjector) keys() []string {
+	if len(f.Keys) > 0 {
+		return f.Keys
+	}
+	return []string{propag.BinaryKey, propag.JaegerKey}
+}
+
+// TagRPC implements stats.Handler.
+func (f *FaultInjector) TagRPC(ctx context.Context, info *stats.RPCTagInfo) context.Context {
+	ctx = f.Handler.TagRPC(ctx, info)
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if !ok {
+		return ctx
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Rand == nil {
+		f.Rand = rand.New(rand.NewSource(1))
+	}
+	for _, k := range f.keys() {
+		k = propag.CanonicalKey(k)
+		vs := md[k]
+		if len(vs) == 0 {
+			continue
+		}
+		switch {
+		case f.Rand.Float64() < f.DropProbability:
+			delete(md, k)
+		case f.Rand.Float64() < f.CorruptProbability:
+			for i, v := range vs {
+				vs[i] = f.corrupt(v)
+			}
+		case f.Rand.Float64() < f.DuplicateProbability:
+			md[k] = append(vs, vs[0])
+		}
+	}
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+// corrupt truncates v or flips one of its bytes.
+func (f *FaultInjector) corrupt(v string) string {
+	if len(v) == 0 {
+		return "\xff"
+	}
+	b := []byte(v)
+	i := f.Rand.Intn(len(b))
+	if f.Rand.Intn(2) == 0 {
+		return string(b[:i])
+	}
+	b[i] ^= 0xff
+	return string(b)
+}
+
+// HandleRPC implements stats.Handler.
+func (f *FaultInjector) HandleRPC(ctx context.Context, rs stats.RPCStats) {
+	f.Handler.HandleRPC(ctx, rs)
+}
+
+// TagConn implements stats.Handler.
+func (f *FaultInjector) TagConn(ctx context.Context, info *stats.ConnTagInfo) context.Context {
+	return f.Handler.TagConn(ctx, info)
+}
+
+// HandleConn implements stats.Handler.
+func (f *FaultInjector) HandleConn(ctx context.Context, cs stats.ConnStats) {
+	f.Handler.HandleConn(ctx, cs)
+}
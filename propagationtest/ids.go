@@ -0,0 +1,71 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package propagationtest
+
+import (
+	"encoding/binary"
+	"sync"
+	"testing"
+
+	"go.opencensus.io/trace"
+)
+
+// SequentialIDGenerator generates predictable trace and span IDs, so the
+// metadata written by instrumented clients is the same on every test run. The
+// nth trace ID has both halves set to n, and the nth span ID is n, starting at
+// 1.
+type SequentialIDGenerator struct {
+	mu      sync.Mutex
+	traceID uint64
+	spanID  uint64
+}
+
+// NewTraceID returns the next trace ID.
+func (g *SequentialIDGenerator) NewTraceID() [16]byte {
+	g.mu.Lock()
+	g.traceID++
+	n := g.traceID
+	g.mu.Unlock()
+
+	var id [16]byte
+	binary.BigEndian.PutUint64(id[:8], n)
+	binary.BigEndian.PutUint64(id[8:], n)
+	return id
+}
+
+// NewSpanID returns the next span ID.
+func (g *SequentialIDGenerator) NewSpanID() [8]byte {
+	g.mu.Lock()
+	g.spanID++
+	n := g.spanID
+	g.mu.Unlock()
+
+	var id [8]byte
+	binary.BigEndian.PutUint64(id[:], n)
+	return id
+}
+
+// DeterministicIDs installs a new SequentialIDGenerator as the ID generator of
+// OpenCensus and returns it.
+//
+// The OpenCensus configuration is global and can't be read back, so the
+// generator stays installed after t ends; every call installs a fresh one so
+// each test sees the same IDs. Tests using it must not run in parallel.
+func DeterministicIDs(t testing.TB) *SequentialIDGenerator {
+	t.Helper()
+	g := &SequentialIDGenerator{}
+	trace.ApplyConfig(trace.Config{IDGenerator: g})
+	return g
+}
@@ -0,0 +1,80 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package jaegerinterop checks Jaeger trace context parsers and formatters
+// against the uber-trace-id wire format of jaeger-client-go, the reference
+// implementation.
+package jaegerinterop
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/uber/jaeger-client-go"
+	"go.opencensus.io/trace"
+)
+
+// SpanContexts are the span contexts exchanged with jaeger-client-go by
+// RunTests.
+var SpanContexts = []jaeger.SpanContext{
+	jaeger.NewSpanContext(jaeger.TraceID{High: 0x4bf92f3577b34da6, Low: 0xa3ce929d0e0e4736}, 0x00f067aa0ba902b7, 0, true, nil),
+	jaeger.NewSpanContext(jaeger.TraceID{High: 0x4bf92f3577b34da6, Low: 0xa3ce929d0e0e4736}, 0x00f067aa0ba902b7, 0, false, nil),
+	jaeger.NewSpanContext(jaeger.TraceID{Low: 0xa3ce929d0e0e4736}, 0x00f067aa0ba902b7, 0x53995c3f42cd8ad8, true, nil),
+	jaeger.NewSpanContext(jaeger.TraceID{High: 1, Low: 1}, 1, 0, true, nil),
+	jaeger.NewSpanContext(jaeger.TraceID{Low: 1}, 1, 1, false, nil),
+	jaeger.NewSpanContext(jaeger.TraceID{High: ^uint64(0), Low: ^uint64(0)}, jaeger.SpanID(^uint64(0)), 0, true, nil),
+}
+
+// FromJaeger converts a jaeger-client-go SpanContext to an OpenCensus one.
+func FromJaeger(jsc jaeger.SpanContext) trace.SpanContext {
+	var sc trace.SpanContext
+	binary.BigEndian.PutUint64(sc.TraceID[:8], jsc.TraceID().High)
+	binary.BigEndian.PutUint64(sc.TraceID[8:], jsc.TraceID().Low)
+	binary.BigEndian.PutUint64(sc.SpanID[:], uint64(jsc.SpanID()))
+	if jsc.IsSampled() {
+		sc.TraceOptions = 1
+	}
+	return sc
+}
+
+// RunTests checks that parse reads the uber-trace-id values written by
+// jaeger-client-go for SpanContexts, and, if format isn't nil, that
+// jaeger-client-go reads the values written by format unchanged.
+func RunTests(t *testing.T, parse func(string) (trace.SpanContext, bool), format func(trace.SpanContext) string) {
+	for _, jsc := range SpanContexts {
+		want := FromJaeger(jsc)
+		value := jsc.String()
+
+		t.Run("Parse/"+value, func(t *testing.T) {
+			sc, ok := parse(value)
+			if !ok || sc != want {
+				t.Errorf("parse(%q) = %v, %v; want %v, true", value, sc, ok, want)
+			}
+		})
+
+		if format == nil {
+			continue
+		}
+		t.Run("Format/"+value, func(t *testing.T) {
+			v := format(want)
+			got, err := jaeger.ContextFromString(v)
+			if err != nil {
+				t.Fatalf("jaeger.ContextFromString(%q) failed: %v", v, err)
+			}
+			if got.TraceID() != jsc.TraceID() || got.SpanID() != jsc.SpanID() || got.IsSampled() != jsc.IsSampled() {
+				t.Errorf("jaeger.ContextFromString(%q) = %v, want %v", v, got, jsc)
+			}
+		})
+	}
+}
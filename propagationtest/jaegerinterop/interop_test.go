@@ -0,0 +1,38 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jaegerinterop_test
+
+import (
+	"testing"
+
+	propag "github.com/akhenakh/ocgrpc_propagation/propagation"
+	"github.com/akhenakh/ocgrpc_propagation/propagationtest/jaegerinterop"
+	"go.opencensus.io/trace"
+)
+
+func TestJaegerOptions(t *testing.T) {
+	var o propag.JaegerOptions
+	jaegerinterop.RunTests(t, o.Parse, func(sc trace.SpanContext) string {
+		jv, ok := o.Format(sc)
+		if !ok {
+			t.Errorf("Format(%v) failed", sc)
+		}
+		return jv
+	})
+}
+
+func TestFromJaeger(t *testing.T) {
+	jaegerinterop.RunTests(t, propag.FromJaeger, nil)
+}
@@ -0,0 +1,52 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package propagationtest
+
+import (
+	"context"
+	"fmt"
+
+	propag "github.com/akhenakh/ocgrpc_propagation/propagation"
+	"go.opencensus.io/trace"
+	"go.opencensus.io/trace/propagation"
+	"google.golang.org/grpc/metadata"
+)
+
+// IncomingContext returns a copy of ctx carrying mds, joined, as incoming gRPC
+// metadata, as seen by the handlers of a server receiving them.
+func IncomingContext(ctx context.Context, mds ...metadata.MD) context.Context {
+	return metadata.NewIncomingContext(ctx, metadata.Join(mds...))
+}
+
+// BinaryMetadata returns the metadata carrying sc in the OpenCensus binary
+// format.
+func BinaryMetadata(sc trace.SpanContext) metadata.MD {
+	return metadata.Pairs(propag.BinaryKey, string(propagation.Binary(sc)))
+}
+
+// JaegerMetadata returns the metadata carrying sc in the Jaeger format.
+func JaegerMetadata(sc trace.SpanContext) metadata.MD {
+	return metadata.Pairs(propag.JaegerKey, JaegerValue(sc))
+}
+
+// JaegerValue formats sc as a Jaeger trace context with the full 128-bit trace
+// ID and no parent span ID.
+func JaegerValue(sc trace.SpanContext) string {
+	flags := 0
+	if sc.IsSampled() {
+		flags = 1
+	}
+	return fmt.Sprintf("%x:%x:0:%d", sc.TraceID[:], sc.SpanID[:], flags)
+}
@@ -0,0 +1,169 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package propagationtest
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/stats"
+)
+
+// Call is a call to a stats.Handler captured by a Recorder. Exactly one of
+// RPCTag, RPCStats, ConnTag and ConnStats is set.
+type Call struct {
+	// Time is when the call was received.
+	Time time.Time
+
+	RPCTag    *stats.RPCTagInfo
+	RPCStats  stats.RPCStats
+	ConnTag   *stats.ConnTagInfo
+	ConnStats stats.ConnStats
+}
+
+// Kind returns the name of the call: "TagRPC", "TagConn", or the name of the
+// type of the RPC or connection stats, such as "Begin", "OutPayload" or "End".
+func (c Call) Kind() string {
+	switch {
+	case c.RPCTag != nil:
+		return "TagRPC"
+	case c.ConnTag != nil:
+		return "TagConn"
+	case c.RPCStats != nil:
+		return statsKind(c.RPCStats)
+	case c.ConnStats != nil:
+		return statsKind(c.ConnStats)
+	}
+	return ""
+}
+
+// statsKind returns the name of the type of the stats s, without the
+// pointer.
+func statsKind(s interface{}) string {
+	t := reflect.TypeOf(s)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Name()
+}
+
+// Recorder is a stats.Handler capturing every call it receives, then
+// forwarding it to Next if set. Install it in place of, or around, the
+// handler under test.
+type Recorder struct {
+	// Next, if set, receives every call after it is captured.
+	Next stats.Handler
+
+	mu    sync.Mutex
+	calls []Call
+}
+
+func (r *Recorder) record(c Call) {
+	c.Time = time.Now()
+	r.mu.Lock()
+	r.calls = append(r.calls, c)
+	r.mu.Unlock()
+}
+
+// TagRPC implements stats.Handler.
+func (r *Recorder) TagRPC(ctx context.Context, info *stats.RPCTagInfo) context.Context {
+	r.record(Call{RPCTag: info})
+	if r.Next != nil {
+		return r.Next.TagRPC(ctx, info)
+	}
+	return ctx
+}
+
+// HandleRPC implements stats.Handler.
+func (r *Recorder) HandleRPC(ctx context.Context, rs stats.RPCStats) {
+	r.record(Call{RPCStats: rs})
+	if r.Next != nil {
+		r.Next.HandleRPC(ctx, rs)
+	}
+}
+
+// TagConn implements stats.Handler.
+func (r *Recorder) TagConn(ctx context.Context, info *stats.ConnTagInfo) context.Context {
+	r.record(Call{ConnTag: info})
+	if r.Next != nil {
+		return r.Next.TagConn(ctx, info)
+	}
+	return ctx
+}
+
+// HandleConn implements stats.Handler.
+func (r *Recorder) HandleConn(ctx context.Context, cs stats.ConnStats) {
+	r.record(Call{ConnStats: cs})
+	if r.Next != nil {
+		r.Next.HandleConn(ctx, cs)
+	}
+}
+
+// Calls returns the calls captured so far, in the order they were received.
+func (r *Recorder) Calls() []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Call(nil), r.calls...)
+}
+
+// Kinds returns the Kind of the calls captured so far.
+func (r *Recorder) Kinds() []string {
+	calls := r.Calls()
+	kinds := make([]string, len(calls))
+	for i, c := range calls {
+		kinds[i] = c.Kind()
+	}
+	return kinds
+}
+
+// PayloadLengths returns the lengths of the received and sent payloads
+// captured so far, in order.
+func (r *Recorder) PayloadLengths() (in, out []int) {
+	for _, c := range r.Calls() {
+		switch rs := c.RPCStats.(type) {
+		case *stats.InPayload:
+			in = append(in, rs.Length)
+		case *stats.OutPayload:
+			out = append(out, rs.Length)
+		}
+	}
+	return in, out
+}
+
+// Reset drops the calls captured so far.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	r.calls = nil
+	r.mu.Unlock()
+}
+
+// AssertOrder fails t unless the calls captured by r include kinds in that
+// order. Other calls may be interleaved.
+func (r *Recorder) AssertOrder(t testing.TB, kinds ...string) {
+	t.Helper()
+	got := r.Kinds()
+	i := 0
+	for _, k := range got {
+		if i < len(kinds) && k == kinds[i] {
+			i++
+		}
+	}
+	if i < len(kinds) {
+		t.Errorf("calls %v don't include %v in order, %q is missing", got, kinds, kinds[i])
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package propagationtest
+
+import (
+	"testing"
+
+	"go.opencensus.io/trace"
+)
+
+// Vector is a canonical pair of a header value and the SpanContext it
+// carries, shared by every implementation of a format.
+type Vector struct {
+	// Name describes the case.
+	Name string
+
+	// Value is the header or metadata value. Binary values are the raw
+	// bytes.
+	Value string
+
+	// SpanContext is the SpanContext carried by Value. It is zero for
+	// invalid values.
+	SpanContext trace.SpanContext
+
+	// Valid is false when Value must be treated as absent.
+	Valid bool
+}
+
+// IDs of the sampled span context used by the vectors, from the examples of
+// the W3C Trace Context specification.
+var (
+	vectorTraceID   = trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36}
+	vectorTraceID64 = trace.TraceID{8: 0xa3, 9: 0xce, 10: 0x92, 11: 0x9d, 12: 0x0e, 13: 0x0e, 14: 0x47, 15: 0x36}
+	vectorSpanID    = trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7}
+)
+
+func vectorSpanContext(traceID trace.TraceID, sampled bool) trace.SpanContext {
+	sc := trace.SpanContext{TraceID: traceID, SpanID: vectorSpanID}
+	if sampled {
+		sc.TraceOptions = 1
+	}
+	return sc
+}
+
+// JaegerVectors are the vectors of the uber-trace-id format,
+// {trace-id}:{span-id}:{parent-span-id}:{flags}, read with the default
+// JaegerOptions.
+var JaegerVectors = []Vector{
+	{"sampled", "4bf92f3577b34da6a3ce929d0e0e4736:00f067aa0ba902b7:0:1", vectorSpanContext(vectorTraceID, true), true},
+	{"unsampled", "4bf92f3577b34da6a3ce929d0e0e4736:00f067aa0ba902b7:0:0", vectorSpanContext(vectorTraceID, false), true},
+	{"sampled and debug", "4bf92f3577b34da6a3ce929d0e0e4736:00f067aa0ba902b7:0:3", trace.SpanContext{TraceID: vectorTraceID, SpanID: vectorSpanID, TraceOptions: 3}, true},
+	{"64-bit trace ID", "a3ce929d0e0e4736:00f067aa0ba902b7:0:1", vectorSpanContext(vectorTraceID64, true), true},
+	{"unpadded IDs", "a3ce929d0e0e4736:f067aa0ba902b7:0:1", vectorSpanContext(vectorTraceID64, true), true},
+	{"odd-length 128-bit trace ID", "bf92f3577b34da6a3ce929d0e0e4736:00f067aa0ba902b7:0:1", vectorSpanContext(trace.TraceID{0x0b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36}, true), true},
+	{"parent span ID", "4bf92f3577b34da6a3ce929d0e0e4736:00f067aa0ba902b7:53995c3f42cd8ad8:1", vectorSpanContext(vectorTraceID, true), true},
+	{"URL-encoded", "4bf92f3577b34da6a3ce929d0e0e4736%3A00f067aa0ba902b7%3A0%3A1", vectorSpanContext(vectorTraceID, true), true},
+	{"empty", "", trace.SpanContext{}, false},
+	{"garbage", "garbage", trace.SpanContext{}, false},
+	{"zero trace ID", "0:00f067aa0ba902b7:0:1", trace.SpanContext{}, false},
+	{"zero span ID", "4bf92f3577b34da6a3ce929d0e0e4736:0:0:1", trace.SpanContext{}, false},
+	{"trace ID too long", "14bf92f3577b34da6a3ce929d0e0e4736:00f067aa0ba902b7:0:1", trace.SpanContext{}, false},
+	{"span ID too long", "4bf92f3577b34da6a3ce929d0e0e4736:100f067aa0ba902b7:0:1", trace.SpanContext{}, false},
+	{"bad hex", "4bf92f3577b34da6a3ce929d0e0e473z:00f067aa0ba902b7:0:1", trace.SpanContext{}, false},
+}
+
+// W3CVectors are the vectors of the W3C traceparent header,
+// {version}-{trace-id}-{parent-id}-{trace-flags}.
+var W3CVectors = []Vector{
+	{"sampled", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", vectorSpanContext(vectorTraceID, true), true},
+	{"unsampled", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", vectorSpanContext(vectorTraceID, false), true},
+	{"empty", "", trace.SpanContext{}, false},
+	{"invalid version", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", trace.SpanContext{}, false},
+	{"zero trace ID", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", trace.SpanContext{}, false},
+	{"zero parent ID", "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", trace.SpanContext{}, false},
+	{"uppercase hex", "00-4BF92F3577B34DA6A3CE929D0E0E4736-00F067AA0BA902B7-01", trace.SpanContext{}, false},
+	{"short trace ID", "00-a3ce929d0e0e4736-00f067aa0ba902b7-01", trace.SpanContext{}, false},
+}
+
+// B3Vectors are the vectors of the B3 single header,
+// {trace-id}-{span-id}-{sampling-state}-{parent-span-id}.
+var B3Vectors = []Vector{
+	{"sampled", "4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-1", vectorSpanContext(vectorTraceID, true), true},
+	{"unsampled", "4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-0", vectorSpanContext(vectorTraceID, false), true},
+	{"debug", "4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-d", vectorSpanContext(vectorTraceID, true), true},
+	{"64-bit trace ID", "a3ce929d0e0e4736-00f067aa0ba902b7-1", vectorSpanContext(vectorTraceID64, true), true},
+	{"parent span ID", "4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-1-53995c3f42cd8ad8", vectorSpanContext(vectorTraceID, true), true},
+	{"empty", "", trace.SpanContext{}, false},
+	{"sampling state only", "0", trace.SpanContext{}, false},
+	{"zero trace ID", "00000000000000000000000000000000-00f067aa0ba902b7-1", trace.SpanContext{}, false},
+	{"zero span ID", "4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-1", trace.SpanContext{}, false},
+	{"bad hex", "4bf92f3577b34da6a3ce929d0e0e473z-00f067aa0ba902b7-1", trace.SpanContext{}, false},
+}
+
+// binaryValue returns the OpenCensus binary format of the trace and span IDs
+// and trace options.
+func binaryValue(traceID trace.TraceID, spanID trace.SpanID, options byte) string {
+	return "\x00" + "\x00" + string(traceID[:]) + "\x01" + string(spanID[:]) + "\x02" + string(options)
+}
+
+// BinaryVectors are the vectors of the OpenCensus binary format of the
+// grpc-trace-bin metadata.
+var BinaryVectors = []Vector{
+	{"sampled", binaryValue(vectorTraceID, vectorSpanID, 1), vectorSpanContext(vectorTraceID, true), true},
+	{"unsampled", binaryValue(vectorTraceID, vectorSpanID, 0), vectorSpanContext(vectorTraceID, false), true},
+	{"empty", "", trace.SpanContext{}, false},
+	{"unknown version", "\x01" + binaryValue(vectorTraceID, vectorSpanID, 1)[1:], trace.SpanContext{}, false},
+	{"truncated", binaryValue(vectorTraceID, vectorSpanID, 1)[:10], trace.SpanContext{}, false},
+	{"zero trace ID", binaryValue(trace.TraceID{}, vectorSpanID, 1), trace.SpanContext{}, false},
+	{"zero span ID", binaryValue(vectorTraceID, trace.SpanID{}, 1), trace.SpanContext{}, false},
+}
+
+// RunVectors checks that parse returns the SpanContext of every vector,
+// and reports the invalid ones as absent.
+func RunVectors(t *testing.T, vectors []Vector, parse func(string) (trace.SpanContext, bool)) {
+	for _, v := range vectors {
+		t.Run(v.Name, func(t *testing.T) {
+			sc, ok := parse(v.Value)
+			if ok != v.Valid || (v.Valid && sc != v.SpanContext) {
+				t.Errorf("parse(%q) = %v, %v; want %v, %v", v.Value, sc, ok, v.SpanContext, v.Valid)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"context"
+
+	"go.opencensus.io/trace"
+	"google.golang.org/grpc/stats"
+)
+
+// PublicEndpointFunc reports whether the RPC described by rti is served on a
+// public endpoint, see ServerHandler.IsPublicEndpoint.
+type PublicEndpointFunc func(ctx context.Context, rti *stats.RPCTagInfo) bool
+
+// PublicMethods returns a PublicEndpointFunc reporting the RPCs of the full
+// method names methods, such as "/api.v1.Orders/Create", as public.
+func PublicMethods(methods ...string) PublicEndpointFunc {
+	public := make(map[string]bool, len(methods))
+	for _, m := range methods {
+		public[m] = true
+	}
+	return func(_ context.Context, rti *stats.RPCTagInfo) bool {
+		return public[rti.FullMethodName]
+	}
+}
+
+// UntrustedParentPolicy controls how the ServerHandler represents the incoming
+// trace contexts of RPCs served on public endpoints.
+type UntrustedParentPolicy int
+
+const (
+	// LinkUntrustedParent starts a new trace, linked to the incoming trace
+	// context.
+	LinkUntrustedParent UntrustedParentPolicy = iota
+
+	// ChildOfUntrustedParent makes the incoming trace context the parent of
+	// the span, as on non-public endpoints. Signatures and
+	// SampledParentsPerPeer still apply.
+	ChildOfUntrustedParent
+
+	// DropUntrustedParent starts a new trace, ignoring the incoming trace
+	// context entirely.
+	DropUntrustedParent
+)
+
+// UntrustedParentFunc returns the UntrustedParentPolicy applied to parent, the
+// incoming trace context of the RPC described by rti, served on a public
+// endpoint.
+type UntrustedParentFunc func(ctx context.Context, rti *stats.RPCTagInfo, parent trace.SpanContext) UntrustedParentPolicy
+
+// untrustedParentPolicy returns the policy applied to parent, the incoming
+// trace context of an RPC served on a public endpoint.
+func (s *ServerHandler) untrustedParentPolicy(ctx context.Context, rti *stats.RPCTagInfo, parent trace.SpanContext) UntrustedParentPolicy {
+	if s.UntrustedParentFunc != nil {
+		return s.UntrustedParentFunc(ctx, rti, parent)
+	}
+	return s.OnUntrustedParent
+}
+
+// isPublicEndpoint reports whether the RPC of rti is served on a public
+// endpoint, according to s.IsPublicEndpointFunc if set, else to
+// s.IsPublicEndpoint.
+func (s *ServerHandler) isPublicEndpoint(ctx context.Context, rti *stats.RPCTagInfo) bool {
+	if s.IsPublicEndpointFunc != nil {
+		return s.IsPublicEndpointFunc(ctx, rti)
+	}
+	return s.IsPublicEndpoint
+}
@@ -0,0 +1,63 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/peer"
+)
+
+// parentLimiter counts the sampled remote parents honored for each peer
+// during the current second.
+type parentLimiter struct {
+	mu     sync.Mutex
+	window time.Time
+	counts map[string]int
+}
+
+// allow reports whether a sampled remote parent sent by the peer of ctx may
+// be honored, at most limit times per second and per peer. The RPCs of
+// unknown peers share the same limit.
+func (l *parentLimiter) allow(ctx context.Context, limit int) bool {
+	if limit <= 0 {
+		return true
+	}
+	var key string
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		if ip := peerIP(p.Addr); ip != nil {
+			key = ip.String()
+		} else {
+			key = p.Addr.String()
+		}
+	}
+
+	now := time.Now().Truncate(time.Second)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !now.Equal(l.window) || l.counts == nil {
+		// Counts are reset every second, so the map never outgrows the
+		// peers of a second.
+		l.window = now
+		l.counts = make(map[string]int)
+	}
+	if l.counts[key] >= limit {
+		return false
+	}
+	l.counts[key]++
+	return true
+}
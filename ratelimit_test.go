@@ -0,0 +1,78 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"google.golang.org/grpc/peer"
+)
+
+func TestParentLimiterConcurrently(t *testing.T) {
+	var l parentLimiter
+	const limit = 10
+	peers := []context.Context{
+		peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1234}}),
+		peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(10, 0, 0, 2), Port: 1234}}),
+		context.Background(),
+	}
+
+	var allowed [3]int64
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		for p, ctx := range peers {
+			wg.Add(1)
+			go func(p int, ctx context.Context) {
+				defer wg.Done()
+				if l.allow(ctx, limit) {
+					atomic.AddInt64(&allowed[p], 1)
+				}
+			}(p, ctx)
+		}
+	}
+	wg.Wait()
+	// The goroutines may straddle two one-second windows.
+	for p, n := range allowed {
+		if n < limit || n > 2*limit {
+			t.Errorf("peer %d: %d parents allowed, want %d", p, n, limit)
+		}
+	}
+}
+
+func TestParentLimiterSharesPortsOfAPeer(t *testing.T) {
+	var l parentLimiter
+	ctx := func(port int) context.Context {
+		return peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: port}})
+	}
+	if !l.allow(ctx(1), 1) {
+		t.Fatal("first parent not allowed")
+	}
+	if l.allow(ctx(2), 1) {
+		t.Error("second parent from another port of the peer allowed")
+	}
+}
+
+func TestParentLimiterDisabled(t *testing.T) {
+	var l parentLimiter
+	for i := 0; i < 10; i++ {
+		if !l.allow(context.Background(), 0) {
+			t.Fatal("parent not allowed without a limit")
+		}
+	}
+}
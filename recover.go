@@ -0,0 +1,97 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"context"
+	"fmt"
+
+	"go.opencensus.io/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/grpclog"
+	"google.golang.org/grpc/stats"
+)
+
+// Logger receives the warnings of the handlers. grpclog is used when a handler
+// has no Logger.
+type Logger interface {
+	Warningf(format string, args ...interface{})
+}
+
+func warningf(l Logger, format string, args ...interface{}) {
+	if l == nil {
+		grpclog.Warningf(format, args...)
+		return
+	}
+	l.Warningf(format, args...)
+}
+
+// recoverTagRPC recovers from a panic while tagging an RPC, in which case it
+// sets *ret to the context tagged so far, *tagged, if the handler already
+// started the span of the RPC: the panic is recorded on the span, which
+// HandleRPC still ends. Otherwise *ret is set to ctx, the context the handler
+// was given, and the handler ignores the RPC.
+func recoverTagRPC(ctx context.Context, tagged *context.Context, l Logger, ret *context.Context) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	warningf(l, "opencensus: recovered from panic in TagRPC: %v", r)
+	if span := trace.FromContext(*tagged); span != nil && span != trace.FromContext(ctx) {
+		recordPanic(span, r, "Recovered from panic while tagging RPC")
+		*ret = *tagged
+		return
+	}
+	*ret = ctx
+}
+
+// endSpanOnPanic ends span, recording the panic, when tagging its RPC panics
+// before the span is returned to the handler, and panics again for the
+// handler to recover.
+func endSpanOnPanic(span *trace.Span) {
+	if r := recover(); r != nil {
+		recordPanic(span, r, "Recovered from panic while tagging RPC")
+		span.End()
+		panic(r)
+	}
+}
+
+// recordPanic records the recovered panic r on span, as an annotation with
+// the message msg and an Internal status.
+func recordPanic(span *trace.Span, r interface{}, msg string) {
+	span.Annotate([]trace.Attribute{
+		trace.StringAttribute("panic", fmt.Sprint(r)),
+	}, msg)
+	span.SetStatus(trace.Status{Code: int32(codes.Internal), Message: fmt.Sprintf("panic: %v", r)})
+}
+
+// recoverHandleRPC recovers from a panic while handling rs, recording it on
+// the span of the RPC, as an annotation and an Internal status. The span is
+// ended if rs is the end of the RPC.
+func recoverHandleRPC(ctx context.Context, l Logger, rs stats.RPCStats) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	warningf(l, "opencensus: recovered from panic in HandleRPC(%T): %v", rs, r)
+	span := trace.FromContext(ctx)
+	if span == nil || !traced(ctx, rs.IsClient()) {
+		return
+	}
+	recordPanic(span, r, "Recovered from panic while handling RPC stats")
+	if _, ok := rs.(*stats.End); ok {
+		span.End()
+	}
+}
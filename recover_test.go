@@ -0,0 +1,101 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/akhenakh/ocgrpc_propagation/propagationtest"
+	"go.opencensus.io/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/stats"
+)
+
+type discardLogger struct{}
+
+func (discardLogger) Warningf(format string, args ...interface{}) {}
+
+var testRTI = &stats.RPCTagInfo{FullMethodName: "/svc/Method"}
+
+// onlySpan returns the only span exported to e.
+func onlySpan(t *testing.T, e *propagationtest.Exporter) *trace.SpanData {
+	t.Helper()
+	spans := e.Spans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	return spans[0]
+}
+
+// assertPanicRecorded checks that s has the Internal status and annotation of
+// a recovered panic.
+func assertPanicRecorded(t *testing.T, s *trace.SpanData) {
+	t.Helper()
+	if s.Status.Code != int32(codes.Internal) {
+		t.Errorf("got status %v, want Internal", s.Status)
+	}
+	for _, a := range s.Annotations {
+		if a.Attributes["panic"] == "boom" {
+			return
+		}
+	}
+	t.Errorf("no annotation of the panic in %v", s.Annotations)
+}
+
+func TestPanicInSpanStartHook(t *testing.T) {
+	e := propagationtest.Install(t)
+	h := &ServerHandler{
+		StartOptions: trace.StartOptions{Sampler: trace.AlwaysSample()},
+		Logger:       discardLogger{},
+		OnSpanStart:  func(context.Context, *trace.Span, *stats.RPCTagInfo) { panic("boom") },
+	}
+	ctx := h.TagRPC(context.Background(), testRTI)
+	if trace.FromContext(ctx) == nil {
+		t.Fatal("the RPC isn't traced after a panic in OnSpanStart")
+	}
+	h.HandleRPC(ctx, &stats.End{EndTime: time.Now()})
+	assertPanicRecorded(t, onlySpan(t, e))
+}
+
+func TestPanicAfterSpanStart(t *testing.T) {
+	e := propagationtest.Install(t)
+	h := &ServerHandler{
+		StartOptions:       trace.StartOptions{Sampler: trace.AlwaysSample()},
+		Logger:             discardLogger{},
+		OnSamplingDecision: func(context.Context, *stats.RPCTagInfo, SamplingDecision) { panic("boom") },
+	}
+	ctx := h.TagRPC(context.Background(), testRTI)
+	// The span started before the panic was ended.
+	assertPanicRecorded(t, onlySpan(t, e))
+	h.HandleRPC(ctx, &stats.End{EndTime: time.Now()})
+	if n := len(e.Spans()); n != 1 {
+		t.Errorf("got %d spans after the end of the RPC, want 1", n)
+	}
+}
+
+func TestPanicInHandleRPC(t *testing.T) {
+	e := propagationtest.Install(t)
+	h := &ClientHandler{
+		StartOptions:       trace.StartOptions{Sampler: trace.AlwaysSample()},
+		Logger:             discardLogger{},
+		PayloadAnnotations: &PayloadAnnotations{Filter: func(interface{}) bool { panic("boom") }},
+	}
+	ctx := h.TagRPC(context.Background(), testRTI)
+	h.HandleRPC(ctx, &stats.OutPayload{Client: true, Payload: "msg", SentTime: time.Now()})
+	h.HandleRPC(ctx, &stats.End{Client: true, EndTime: time.Now()})
+	assertPanicRecorded(t, onlySpan(t, e))
+}
@@ -0,0 +1,64 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"context"
+
+	"github.com/akhenakh/ocgrpc_propagation/baggage"
+	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// RedactUnaryInterceptor redacts the outgoing metadata values matched by r in
+// the context passed to the handler. Chained after the propagation
+// interceptors, it ensures secrets found in forwarded baggage or metadata
+// aren't propagated further.
+func RedactUnaryInterceptor(r *baggage.Redactor) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(redactOutgoingMetadata(ctx, r), req)
+	}
+}
+
+// RedactStreamInterceptor redacts the outgoing metadata values matched by r in
+// the context passed to the handler, see RedactUnaryInterceptor.
+func RedactStreamInterceptor(r *baggage.Redactor) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		wrapped := grpc_middleware.WrapServerStream(stream)
+		wrapped.WrappedContext = redactOutgoingMetadata(stream.Context(), r)
+		return handler(srv, wrapped)
+	}
+}
+
+func redactOutgoingMetadata(ctx context.Context, r *baggage.Redactor) context.Context {
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if !ok {
+		return ctx
+	}
+	redacted := false
+	for _, vs := range md {
+		for i, v := range vs {
+			if rv, ok := r.Redact(v); ok {
+				vs[i] = rv
+				redacted = true
+			}
+		}
+	}
+	if !redacted {
+		return ctx
+	}
+	return metadata.NewOutgoingContext(ctx, md)
+}
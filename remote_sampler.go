@@ -0,0 +1,307 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opencensus.io/trace"
+)
+
+// Defaults of RemoteSamplerOptions.
+const (
+	defaultSamplingHostPort        = "localhost:5778"
+	defaultSamplingRefreshInterval = time.Minute
+	defaultInitialSamplingRate     = 0.001
+)
+
+// RemoteSamplerOptions configures a RemoteSampler.
+type RemoteSamplerOptions struct {
+	// HostPort of the jaeger-agent sampling endpoint, defaults to
+	// localhost:5778.
+	HostPort string
+
+	// RefreshInterval between two polls of the agent, defaults to one minute.
+	RefreshInterval time.Duration
+
+	// InitialSampler is used until the strategies have been fetched once.
+	// It defaults to a probability of 0.001, as in jaeger-client-go.
+	InitialSampler trace.Sampler
+
+	// Client used to poll the agent, defaults to http.DefaultClient.
+	Client *http.Client
+
+	// Logger receives the polling errors. It defaults to grpclog.
+	Logger Logger
+}
+
+// RemoteSampler polls a jaeger-agent for the sampling strategies of a
+// service, following the jaeger-client-go protocol, so sampling can be
+// changed centrally without redeploying services. Probabilistic and rate
+// limiting strategies are supported, as well as per-operation strategies
+// keyed by span name. Use its Sampler as the DefaultSampler of the handlers.
+type RemoteSampler struct {
+	url             string
+	refreshInterval time.Duration
+	client          *http.Client
+	logger          Logger
+
+	mu      sync.RWMutex
+	sampler trace.Sampler
+	ops     map[string]trace.Sampler
+
+	// limiters of the samplers, only accessed by the polling goroutine.
+	limiters rateLimiters
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	stopped  sync.WaitGroup
+}
+
+// NewRemoteSampler returns a RemoteSampler for serviceName and starts polling
+// the agent. Close must be called to stop polling.
+func NewRemoteSampler(serviceName string, o RemoteSamplerOptions) *RemoteSampler {
+	if o.HostPort == "" {
+		o.HostPort = defaultSamplingHostPort
+	}
+	if o.RefreshInterval <= 0 {
+		o.RefreshInterval = defaultSamplingRefreshInterval
+	}
+	if o.InitialSampler == nil {
+		o.InitialSampler = trace.ProbabilitySampler(defaultInitialSamplingRate)
+	}
+	if o.Client == nil {
+		o.Client = http.DefaultClient
+	}
+	r := &RemoteSampler{
+		url:             fmt.Sprintf("http://%s/sampling?service=%s", o.HostPort, url.QueryEscape(serviceName)),
+		refreshInterval: o.RefreshInterval,
+		client:          o.Client,
+		logger:          o.Logger,
+		sampler:         o.InitialSampler,
+		stop:            make(chan struct{}),
+	}
+	r.stopped.Add(1)
+	go r.poll()
+	return r
+}
+
+// Sampler returns the trace.Sampler applying the strategies of r.
+func (r *RemoteSampler) Sampler() trace.Sampler {
+	return func(p trace.SamplingParameters) trace.SamplingDecision {
+		r.mu.RLock()
+		sampler, ok := r.ops[p.Name]
+		if !ok {
+			sampler = r.sampler
+		}
+		r.mu.RUnlock()
+		return sampler(p)
+	}
+}
+
+// Close stops polling the agent. It may be called more than once.
+func (r *RemoteSampler) Close() error {
+	r.stopOnce.Do(func() { close(r.stop) })
+	r.stopped.Wait()
+	return nil
+}
+
+func (r *RemoteSampler) poll() {
+	defer r.stopped.Done()
+	r.update()
+	ticker := time.NewTicker(r.refreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.update()
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+func (r *RemoteSampler) update() {
+	s, err := r.fetch()
+	if err != nil {
+		warningf(r.logger, "opencensus: Failed to fetch sampling strategies: %v", err)
+		return
+	}
+	sampler, ops, limiters := s.samplers(r.limiters)
+	r.limiters = limiters
+	r.mu.Lock()
+	r.sampler = sampler
+	r.ops = ops
+	r.mu.Unlock()
+}
+
+func (r *RemoteSampler) fetch() (*samplingStrategy, error) {
+	resp, err := r.client.Get(r.url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %q from %s", resp.Status, r.url)
+	}
+	var s samplingStrategy
+	if err := json.NewDecoder(resp.Body).Decode(&s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// samplingStrategy is the JSON representation of the strategies returned by
+// the agent.
+type samplingStrategy struct {
+	StrategyType          strategyType `json:"strategyType"`
+	ProbabilisticSampling *struct {
+		SamplingRate float64 `json:"samplingRate"`
+	} `json:"probabilisticSampling"`
+	RateLimitingSampling *struct {
+		MaxTracesPerSecond float64 `json:"maxTracesPerSecond"`
+	} `json:"rateLimitingSampling"`
+	OperationSampling *struct {
+		DefaultSamplingProbability       float64 `json:"defaultSamplingProbability"`
+		DefaultLowerBoundTracesPerSecond float64 `json:"defaultLowerBoundTracesPerSecond"`
+		PerOperationStrategies           []struct {
+			Operation             string `json:"operation"`
+			ProbabilisticSampling struct {
+				SamplingRate float64 `json:"samplingRate"`
+			} `json:"probabilisticSampling"`
+		} `json:"perOperationStrategies"`
+	} `json:"operationSampling"`
+}
+
+// strategyType is a strategy type, sent as a number by the agent and as a
+// name by the collector.
+type strategyType string
+
+func (t *strategyType) UnmarshalJSON(b []byte) error {
+	switch s := strings.Trim(string(b), `"`); s {
+	case "0", "PROBABILISTIC":
+		*t = "PROBABILISTIC"
+	case "1", "RATE_LIMITING":
+		*t = "RATE_LIMITING"
+	default:
+		return fmt.Errorf("unknown sampling strategy type %s", b)
+	}
+	return nil
+}
+
+// samplers returns the default and per-operation samplers of s, and their
+// rate limiters. The limiters of previous whose rate is unchanged are reused,
+// so polling doesn't refill them.
+func (s *samplingStrategy) samplers(previous rateLimiters) (trace.Sampler, map[string]trace.Sampler, rateLimiters) {
+	limiters := rateLimiters{}
+	if o := s.OperationSampling; o != nil {
+		ops := make(map[string]trace.Sampler, len(o.PerOperationStrategies))
+		for _, op := range o.PerOperationStrategies {
+			ops[op.Operation] = guaranteedThroughputSampler(op.ProbabilisticSampling.SamplingRate, limiters.get(previous, op.Operation, o.DefaultLowerBoundTracesPerSecond))
+		}
+		return guaranteedThroughputSampler(o.DefaultSamplingProbability, limiters.get(previous, "", o.DefaultLowerBoundTracesPerSecond)), ops, limiters
+	}
+	if s.StrategyType == "RATE_LIMITING" && s.RateLimitingSampling != nil {
+		return rateLimitingSampler(limiters.get(previous, "", s.RateLimitingSampling.MaxTracesPerSecond)), nil, limiters
+	}
+	if s.ProbabilisticSampling != nil {
+		return trace.ProbabilitySampler(s.ProbabilisticSampling.SamplingRate), nil, limiters
+	}
+	return trace.ProbabilitySampler(defaultInitialSamplingRate), nil, limiters
+}
+
+// rateLimiters maps operations, "" for the default sampler, to the rate
+// limiter of their sampler.
+type rateLimiters map[string]*rateLimiter
+
+// get returns the limiter of op in previous if its rate is rate, else a new
+// one, or nil if rate isn't positive. It records the limiter in l.
+func (l rateLimiters) get(previous rateLimiters, op string, rate float64) *rateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	limiter, ok := previous[op]
+	if !ok || limiter.rate != rate {
+		limiter = newRateLimiter(rate)
+	}
+	l[op] = limiter
+	return limiter
+}
+
+// guaranteedThroughputSampler samples with probability, and at least the
+// traces allowed by limiter, if any.
+func guaranteedThroughputSampler(probability float64, limiter *rateLimiter) trace.Sampler {
+	sampler := trace.ProbabilitySampler(probability)
+	if limiter == nil {
+		return sampler
+	}
+	return func(p trace.SamplingParameters) trace.SamplingDecision {
+		if d := sampler(p); d.Sample {
+			return d
+		}
+		return trace.SamplingDecision{Sample: limiter.allow()}
+	}
+}
+
+// rateLimitingSampler samples at most the traces allowed by limiter, none
+// if it is nil.
+func rateLimitingSampler(limiter *rateLimiter) trace.Sampler {
+	return func(p trace.SamplingParameters) trace.SamplingDecision {
+		if p.ParentContext.IsSampled() {
+			return trace.SamplingDecision{Sample: true}
+		}
+		return trace.SamplingDecision{Sample: limiter != nil && limiter.allow()}
+	}
+}
+
+// rateLimiter is a token bucket refilled with rate tokens per second, holding
+// at most max(rate, 1) tokens.
+type rateLimiter struct {
+	mu      sync.Mutex
+	rate    float64
+	max     float64
+	balance float64
+	last    time.Time
+}
+
+func newRateLimiter(rate float64) *rateLimiter {
+	capacity := rate
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &rateLimiter{rate: rate, max: capacity, balance: capacity, last: time.Now()}
+}
+
+func (l *rateLimiter) allow() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	l.balance += now.Sub(l.last).Seconds() * l.rate
+	l.last = now
+	if l.balance > l.max {
+		l.balance = l.max
+	}
+	if l.balance < 1 {
+		return false
+	}
+	l.balance--
+	return true
+}
@@ -0,0 +1,111 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.opencensus.io/trace"
+)
+
+func parseStrategy(t *testing.T, s string) *samplingStrategy {
+	t.Helper()
+	var strategy samplingStrategy
+	if err := json.Unmarshal([]byte(s), &strategy); err != nil {
+		t.Fatal(err)
+	}
+	return &strategy
+}
+
+func TestSamplersKeepUnchangedLimiters(t *testing.T) {
+	const ops = `{"strategyType":0,"operationSampling":{"defaultSamplingProbability":0,"defaultLowerBoundTracesPerSecond":%s,
+		"perOperationStrategies":[{"operation":"op","probabilisticSampling":{"samplingRate":0}}]}}`
+	strategy := func(rate string) *samplingStrategy {
+		return parseStrategy(t, strings.Replace(ops, "%s", rate, 1))
+	}
+
+	sampler, _, limiters := strategy("1").samplers(nil)
+	if !sampler(trace.SamplingParameters{Name: "other"}).Sample {
+		t.Fatal("the lower bound didn't sample the first trace")
+	}
+	sampler, opSamplers, next := strategy("1").samplers(limiters)
+	if next[""] != limiters[""] || next["op"] != limiters["op"] {
+		t.Error("limiters with an unchanged rate were rebuilt")
+	}
+	if sampler(trace.SamplingParameters{Name: "other"}).Sample {
+		t.Error("polling refilled the limiter of the default sampler")
+	}
+	if !opSamplers["op"](trace.SamplingParameters{Name: "op"}).Sample {
+		t.Error("the limiter of op was shared with the default sampler")
+	}
+
+	_, _, changed := strategy("2").samplers(next)
+	if changed[""] == next[""] || changed[""].rate != 2 {
+		t.Error("the limiter wasn't rebuilt for a new rate")
+	}
+	if _, _, none := strategy("0").samplers(changed); len(none) != 0 {
+		t.Errorf("limiters = %v without a lower bound", none)
+	}
+}
+
+func TestRateLimitingSamplerKeepsLimiter(t *testing.T) {
+	s := parseStrategy(t, `{"strategyType":"RATE_LIMITING","rateLimitingSampling":{"maxTracesPerSecond":1}}`)
+	sampler, _, limiters := s.samplers(nil)
+	if !sampler(trace.SamplingParameters{}).Sample {
+		t.Fatal("the first trace wasn't sampled")
+	}
+	if sampler, _, _ = s.samplers(limiters); sampler(trace.SamplingParameters{}).Sample {
+		t.Error("polling refilled the rate limiter")
+	}
+}
+
+func TestRemoteSamplerConcurrentPolls(t *testing.T) {
+	var polls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Alternate the rates so the limiters are rebuilt.
+		rate := 1 + atomic.AddInt32(&polls, 1)%2
+		fmt.Fprintf(w, `{"strategyType":"RATE_LIMITING","rateLimitingSampling":{"maxTracesPerSecond":%d}}`, rate)
+	}))
+	defer srv.Close()
+
+	r := NewRemoteSampler("svc", RemoteSamplerOptions{
+		HostPort:        strings.TrimPrefix(srv.URL, "http://"),
+		RefreshInterval: time.Millisecond,
+		Logger:          discardLogger{},
+	})
+	sampler := r.Sampler()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for atomic.LoadInt32(&polls) < 3 {
+				sampler(trace.SamplingParameters{Name: "op"})
+				time.Sleep(10 * time.Microsecond)
+			}
+		}()
+	}
+	wg.Wait()
+	r.Close()
+	r.Close()
+}
@@ -0,0 +1,86 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	propag "github.com/akhenakh/ocgrpc_propagation/propagation"
+	"go.opencensus.io/trace"
+	"google.golang.org/grpc/metadata"
+)
+
+// requestIDAttribute is the server span attribute recording the request ID of
+// RPCs, named like the attribute set by Envoy.
+const requestIDAttribute = "guid:x-request-id"
+
+type requestIDKey struct{}
+
+// RequestIDFromContext returns the request ID of the RPC of ctx, as forwarded
+// or generated by a ServerHandler with PropagateRequestID set. It is empty
+// otherwise.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// requestIDTagRPC keeps the incoming x-request-id metadata of ctx, or a new
+// request ID if there is none, in ctx and copies it to the outgoing metadata,
+// when s.PropagateRequestID is set.
+func (s *ServerHandler) requestIDTagRPC(ctx context.Context) context.Context {
+	if !s.PropagateRequestID {
+		return ctx
+	}
+	in, _ := metadata.FromIncomingContext(ctx)
+	var id string
+	if vs := propag.Lookup(in, propag.RequestIDKey); len(vs) > 0 && vs[0] != "" {
+		id = vs[0]
+	} else {
+		id = newRequestID()
+	}
+	md, _ := metadata.FromOutgoingContext(ctx)
+	md = md.Copy()
+	md.Set(propag.RequestIDKey, id)
+	return context.WithValue(metadata.NewOutgoingContext(ctx, md), requestIDKey{}, id)
+}
+
+// addRequestIDAttribute records the request ID of ctx on its span.
+func addRequestIDAttribute(ctx context.Context) {
+	if id := RequestIDFromContext(ctx); id != "" {
+		trace.FromContext(ctx).AddAttributes(trace.StringAttribute(requestIDAttribute, id))
+	}
+}
+
+// newRequestID returns a random version 4 UUID, the format of the request IDs
+// generated by Envoy.
+func newRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	var buf [36]byte
+	hex.Encode(buf[0:8], b[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], b[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], b[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], b[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], b[10:])
+	return string(buf[:])
+}
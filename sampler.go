@@ -0,0 +1,58 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"sync"
+
+	"go.opencensus.io/trace"
+)
+
+// samplerFallback resolves the sampler used by a handler, warning once when
+// none is configured and the global default sampler silently applies.
+type samplerFallback struct {
+	warnOnce sync.Once
+}
+
+func (f *samplerFallback) sampler(name string, configured, fallback trace.Sampler, l Logger) trace.Sampler {
+	if configured != nil {
+		return configured
+	}
+	if fallback != nil {
+		return fallback
+	}
+	f.warnOnce.Do(func() {
+		warningf(l, "opencensus: %s has no sampler configured, the global default sampler applies", name)
+	})
+	return nil
+}
+
+// sampler returns the sampler of spans started by s for the RPCs of the full
+// method name method, nil meaning the global default sampler.
+func (s *ServerHandler) sampler(method string) trace.Sampler {
+	if sampler := s.MethodSamplers[method]; sampler != nil {
+		return sampler
+	}
+	return s.fallback.sampler("ServerHandler", s.StartOptions.Sampler, s.DefaultSampler, s.Logger)
+}
+
+// sampler returns the sampler of spans started by c for the RPCs of the full
+// method name method, nil meaning the global default sampler.
+func (c *ClientHandler) sampler(method string) trace.Sampler {
+	if sampler := c.MethodSamplers[method]; sampler != nil {
+		return sampler
+	}
+	return c.fallback.sampler("ClientHandler", c.StartOptions.Sampler, c.DefaultSampler, c.Logger)
+}
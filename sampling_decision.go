@@ -0,0 +1,78 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"context"
+	"strconv"
+
+	ocstats "go.opencensus.io/stats"
+	"go.opencensus.io/tag"
+	"google.golang.org/grpc/stats"
+)
+
+// SamplingReason tells why the span of an RPC was sampled or not.
+type SamplingReason string
+
+// Reasons of the sampling decisions of the ServerHandler.
+const (
+	// SamplingReasonSampler reports a decision of the sampler of the
+	// handler, which may itself follow the sampled flag of the parent.
+	SamplingReasonSampler SamplingReason = "sampler"
+
+	// SamplingReasonParent reports a decision inherited from the remote
+	// parent, see ServerHandler.RespectUpstreamSamplingDecision.
+	SamplingReasonParent SamplingReason = "parent"
+
+	// SamplingReasonForced reports a span sampled because of a Jaeger debug
+	// ID or flag, or the ForceTraceKey metadata.
+	SamplingReasonForced SamplingReason = "forced"
+
+	// SamplingReasonPriority reports a decision following the sampling
+	// priority of the caller, see ServerHandler.SamplingPriorityKey.
+	SamplingReasonPriority SamplingReason = "priority"
+
+	// SamplingReasonMalformedParent reports a decision of the sampler of the
+	// handler for an RPC whose trace contexts couldn't be used.
+	SamplingReasonMalformedParent SamplingReason = "malformed_parent"
+)
+
+// SamplingDecision is the sampling decision taken by a ServerHandler for the
+// span of an RPC.
+type SamplingDecision struct {
+	Sampled bool
+	Reason  SamplingReason
+}
+
+// SamplingDecisionFunc is called with the sampling decision taken for the
+// span of each RPC, see ServerHandler.OnSamplingDecision.
+type SamplingDecisionFunc func(ctx context.Context, rti *stats.RPCTagInfo, d SamplingDecision)
+
+// recordSamplingDecision records d in the ServerSamplingDecisions measure, and
+// calls s.OnSamplingDecision.
+func (s *ServerHandler) recordSamplingDecision(ctx context.Context, rti *stats.RPCTagInfo, d SamplingDecision) {
+	if !s.DisableStats {
+		ocstats.RecordWithTags(ctx,
+			[]tag.Mutator{
+				tag.Upsert(KeyServerMethod, methodName(rti.FullMethodName)),
+				tag.Upsert(KeyServerSampled, strconv.FormatBool(d.Sampled)),
+				tag.Upsert(KeyServerSamplingReason, string(d.Reason)),
+			},
+			ServerSamplingDecisions.M(1))
+	}
+	if s.OnSamplingDecision != nil {
+		s.OnSamplingDecision(ctx, rti, d)
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	propag "github.com/akhenakh/ocgrpc_propagation/propagation"
+	"go.opencensus.io/trace"
+	"google.golang.org/grpc/metadata"
+)
+
+// DefaultSamplingPriorityKey is the metadata key and HTTP header of the
+// sampling priority of Datadog tracers, suggested for the
+// SamplingPriorityKey of the handlers.
+const DefaultSamplingPriorityKey = "x-datadog-sampling-priority"
+
+// Sampling priorities, as defined by Datadog: positive priorities keep the
+// trace, the others drop it. The user priorities are set by applications and
+// the automatic ones by samplers.
+const (
+	SamplingPriorityUserReject = -1
+	SamplingPriorityAutoReject = 0
+	SamplingPriorityAutoKeep   = 1
+	SamplingPriorityUserKeep   = 2
+)
+
+// samplingPriorityAttribute is the server span attribute recording the
+// sampling priority read from the incoming metadata.
+const samplingPriorityAttribute = "sampling.priority"
+
+type samplingPriorityKey struct{}
+
+// samplingPriority returns the sampling priority carried by md under
+// s.SamplingPriorityKey. ok is false when the key isn't set, absent, or its
+// value isn't an integer.
+func (s *ServerHandler) samplingPriority(md metadata.MD) (priority int, ok bool) {
+	if s.SamplingPriorityKey == "" {
+		return 0, false
+	}
+	vs := propag.Lookup(md, s.SamplingPriorityKey)
+	if len(vs) == 0 {
+		return 0, false
+	}
+	priority, err := strconv.Atoi(strings.TrimSpace(vs[0]))
+	return priority, err == nil
+}
+
+// prioritySampler returns the sampler applying priority.
+func prioritySampler(priority int) trace.Sampler {
+	if priority > 0 {
+		return trace.AlwaysSample()
+	}
+	return trace.NeverSample()
+}
+
+// withSamplingPriority returns a copy of ctx carrying the sampling priority
+// to forward to the RPCs made with it.
+func withSamplingPriority(ctx context.Context, priority int) context.Context {
+	return context.WithValue(ctx, samplingPriorityKey{}, priority)
+}
+
+// outgoingSamplingPriority returns the sampling priority to forward with sc:
+// the priority carried by ctx when it agrees with the sampled flag of sc, so
+// user priorities survive, else the automatic priority of the flag.
+func outgoingSamplingPriority(ctx context.Context, sc trace.SpanContext) int {
+	if p, ok := ctx.Value(samplingPriorityKey{}).(int); ok && (p > 0) == sc.IsSampled() {
+		return p
+	}
+	if sc.IsSampled() {
+		return SamplingPriorityAutoKeep
+	}
+	return SamplingPriorityAutoReject
+}
@@ -15,9 +15,12 @@
 package ocgrpc
 
 import (
-	"go.opencensus.io/trace"
-	"golang.org/x/net/context"
+	"context"
+	"time"
 
+	propag "github.com/akhenakh/ocgrpc_propagation/propagation"
+	"go.opencensus.io/trace"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/stats"
 )
 
@@ -39,6 +42,21 @@ type ServerHandler struct {
 	// and trigger traces in your backend.
 	IsPublicEndpoint bool
 
+	// IsPublicEndpointFunc may be set to decide per RPC whether its endpoint
+	// is public, such as when some methods of the server are internet-facing
+	// and others are only called within the mesh. It overrides
+	// IsPublicEndpoint. See PublicMethods.
+	IsPublicEndpointFunc PublicEndpointFunc
+
+	// OnUntrustedParent controls how the incoming trace contexts of RPCs
+	// served on public endpoints are represented. It defaults to
+	// LinkUntrustedParent.
+	OnUntrustedParent UntrustedParentPolicy
+
+	// UntrustedParentFunc may be set to choose the policy per RPC. It
+	// overrides OnUntrustedParent.
+	UntrustedParentFunc UntrustedParentFunc
+
 	// StartOptions to use for to spans started around RPCs handled by this server.
 	//
 	// These will apply even if there is tracing metadata already
@@ -51,30 +69,378 @@ type ServerHandler struct {
 	// StartOptions.SpanKind will always be set to trace.SpanKindServer
 	// for spans started by this handler.
 	StartOptions trace.StartOptions
+
+	// MethodSamplers may be set to sample the spans of some methods, keyed
+	// by full method name such as "/pkg.Service/Method", with their own
+	// sampler instead of StartOptions.Sampler and DefaultSampler.
+	MethodSamplers map[string]trace.Sampler
+
+	// FormatSpanName may be set to name the spans of RPCs, such as to keep
+	// the full /pkg.Service/Method form, add a prefix or strip version
+	// suffixes. It defaults to DefaultSpanName.
+	FormatSpanName func(rti *stats.RPCTagInfo) string
+
+	// OnSpanStart may be set to add attributes to the span of every RPC.
+	OnSpanStart SpanStartHook
+
+	// OnSamplingDecision may be set to observe the sampling decision taken
+	// for the span of every RPC, also counted by the
+	// ServerSamplingDecisions measure.
+	OnSamplingDecision SamplingDecisionFunc
+
+	// DefaultSampler is used when StartOptions.Sampler is nil. If both are
+	// nil, the global default sampler applies and a warning is logged once.
+	DefaultSampler trace.Sampler
+
+	// Jaeger configures how incoming uber-trace-id metadata is read, notably
+	// whether 64-bit trace IDs are accepted.
+	Jaeger propag.JaegerOptions
+
+	// DecodeBase64Binary may be set to true to also accept grpc-trace-bin
+	// values that are still base64 encoded, as forwarded by some proxies.
+	// gRPC decodes -bin metadata itself, so this is a fallback tried only
+	// when the value can't be parsed as is.
+	DecodeBase64Binary bool
+
+	// Propagators may be set to read the trace context of incoming RPCs
+	// from these formats, the first valid one winning. If nil, the binary,
+	// grpc-web, Jaeger, W3C, B3 single header, B3, X-Ray, Google Cloud
+	// Trace and x-ot-span-context formats are read in this order, according
+	// to the Jaeger and DecodeBase64Binary fields.
+	Propagators []propag.Propagator
+
+	// RespectUpstreamSamplingDecision may be set to true to use the sampled
+	// flag of the incoming trace context, whatever its format, as the
+	// sampling decision of the span, so sampling stays consistent across
+	// the call graph. It doesn't apply to parents only linked to, such as
+	// on public endpoints.
+	RespectUpstreamSamplingDecision bool
+
+	// PropagateJaeger may be set to true to copy the incoming uber-trace-id
+	// metadata to the outgoing metadata of the RPC context, as
+	// JaegerTracePropagateUnaryInterceptor does, so the RPCs made by the
+	// handler propagate it without the extra interceptors.
+	PropagateJaeger bool
+
+	// PropagateRequestID may be set to true to copy the incoming
+	// x-request-id metadata, or a new request ID if there is none, to the
+	// outgoing metadata of the RPC context, so request IDs stay correlated
+	// with traces across services. The request ID is recorded in the
+	// guid:x-request-id span attribute, see RequestIDFromContext.
+	PropagateRequestID bool
+
+	// TraceIDKey may be set to return the trace ID of each sampled RPC to
+	// the caller in this trailer key, such as DefaultTraceIDKey, so a failed
+	// call can be referenced. The trace IDs of unsampled RPCs aren't
+	// returned, since no trace can be found with them. Stats handlers can't
+	// set response metadata: it requires the TraceID interceptors, see
+	// TraceIDUnaryInterceptor, which Config.ServerOptions installs.
+	TraceIDKey string
+
+	// TraceIDInHeader may be set to true to return the trace ID in the
+	// TraceIDKey response header, known before the call ends, instead of the
+	// trailer.
+	TraceIDInHeader bool
+
+	// AttributeTags may be set to also record the span attributes added by
+	// the handlers of RPCs with AddAttributes as tags of the measures
+	// recorded at the end of the RPCs, such as a customer tier.
+	AttributeTags AttributeTags
+
+	// HonorJaegerDebugID may be set to true to sample the spans of the RPCs
+	// carrying jaeger-debug-id metadata, whatever the sampler, and record
+	// its value in the jaeger-debug-id attribute, as Jaeger clients do. The
+	// spans continuing a remote parent with the Jaeger debug flag are also
+	// sampled, and marked with the jaeger.debug attribute. Beware that
+	// callers control how often this happens.
+	HonorJaegerDebugID bool
+
+	// ForceTraceKey may be set, such as to DefaultForceTraceKey, to sample
+	// the spans of the RPCs carrying this metadata key with a value other
+	// than "0" or "false", whatever the sampler, and mark them with the
+	// forced attribute. Beware that callers control how often this happens.
+	ForceTraceKey string
+
+	// SamplingPriorityKey may be set, such as to
+	// DefaultSamplingPriorityKey, to read the numeric sampling priority of
+	// the incoming trace from this metadata key: a positive priority samples
+	// the span of the RPC and zero or a negative one doesn't, whatever the
+	// sampler, unless the trace is forced. The priority is recorded in the
+	// sampling.priority attribute and forwarded by the ClientHandlers with
+	// a SamplingPriorityKey. It doesn't apply to parents only linked to.
+	SamplingPriorityKey string
+
+	// OnConflictingParents controls which trace context is continued when
+	// an RPC carries several formats with different trace IDs. It defaults
+	// to PreferFirstFormat.
+	OnConflictingParents ConflictingParentPolicy
+
+	// PreferredFormat may be set to the name of a format, such as "jaeger",
+	// to read it before the other Propagators, see propagation.NameOf.
+	PreferredFormat string
+
+	// OnExtractionFailure controls what happens when tracing metadata is
+	// present but can't be used. It defaults to StartNewTrace.
+	OnExtractionFailure ExtractionFailurePolicy
+
+	// LogInvalidSpanContexts may be set to true to log to Logger why each
+	// incoming span context couldn't be used, as reported by the strict
+	// parsers of the propagation package. Beware that callers control how
+	// often this happens.
+	LogInvalidSpanContexts bool
+
+	// ClassifyContextErrors may be set to true to tell caller cancellations
+	// from expired deadlines when an RPC ends with codes.Canceled or
+	// codes.DeadlineExceeded, whatever code the transport surfaced. The
+	// precise code is set as the span status and the cause is recorded in
+	// the grpc.context_error attribute.
+	ClassifyContextErrors bool
+
+	// ErrorCodes may be set to only set the span status of RPCs ending with
+	// one of these codes, such as OTelServerErrorCodes. The code of every RPC is
+	// recorded in the rpc.grpc.status_code attribute anyway. If nil, every
+	// code but OK sets the span status.
+	ErrorCodes map[codes.Code]bool
+
+	// DisableMessageEvents may be set to true to not add a message event to
+	// the span for every message sent or received, which is costly on long
+	// streams. Spans are still started, ended and given a status.
+	DisableMessageEvents bool
+
+	// MessageEventLimit may be set to cap the message events added to the
+	// span of long-lived streams, such as the first 100 and then one in
+	// 1000. The number of messages is then recorded in the
+	// grpc.sent_messages and grpc.received_messages attributes.
+	MessageEventLimit MessageEventLimit
+
+	// MessageGapThreshold may be set to annotate the span of an RPC when the
+	// time since the previous message sent or received, in the same
+	// direction, reaches it, so backpressure from flow control and slow peers
+	// is visible on streams. The longest gaps are recorded in the
+	// grpc.max_send_gap_ms and grpc.max_receive_gap_ms attributes.
+	MessageGapThreshold time.Duration
+
+	// SpanLimits may be set to cap the annotations, message events and
+	// attributes added to the span of each RPC, dropping and counting the
+	// events over the limits.
+	SpanLimits SpanLimits
+
+	// TransportAttributes may be set to true to record the connection of
+	// each RPC on its span: the local address and port in the net.host.ip
+	// and net.host.port attributes, telling instances apart, and the
+	// transport in net.transport. The address of callers is recorded
+	// according to PeerAddress.
+	TransportAttributes bool
+
+	// PayloadAnnotations may be set to annotate sampled spans with the
+	// messages sent and received, for debugging. It is off by default as
+	// messages may hold personal data or secrets.
+	PayloadAnnotations *PayloadAnnotations
+
+	// FormatStatus may be set to customize the span status of the RPCs
+	// ending with an error. It defaults to DefaultStatusFormatter.
+	FormatStatus StatusFormatter
+
+	// RedactStatusMessage may be set to redact the span status messages,
+	// which are error messages that may hold personal data.
+	RedactStatusMessage func(code codes.Code, msg string) string
+
+	// StatusMessageMaxLength may be set to truncate the span status
+	// messages to this length.
+	StatusMessageMaxLength int
+
+	// MessageSpans may be set to true to create a grpc.message child span
+	// per message of sampled streaming RPCs, with the index and size of
+	// messages as attributes. In lock-step streams, a request and its
+	// response share a span, measuring the time taken to answer.
+	MessageSpans bool
+
+	// ConnectionSpans may be set to true to trace connections: a
+	// grpc.connection span lasts from the establishment of each connection
+	// to its end, with its local address and transport as attributes, and
+	// the address of the caller according to PeerAddress.
+	ConnectionSpans bool
+
+	// DisableTracing may be set to true to not trace RPCs, such as when a
+	// sidecar traces them, rather than sampling them out.
+	DisableTracing bool
+
+	// DisableStats may be set to true to not record measures, nor extract propagated
+	// tags, when only tracing is wanted.
+	DisableStats bool
+
+	// BaggageTags may be set to import these incoming baggage items as tags
+	// of the RPC context, so the measures recorded while handling it are
+	// broken down by them. Items aren't restricted nor limited.
+	BaggageTags BaggageTags
+
+	// MaxTagsSize is the size above which the grpc-tags-bin metadata of
+	// incoming RPCs is ignored. It defaults to DefaultMaxTagsSize.
+	MaxTagsSize int
+
+	// Signer may be set to verify the signature of incoming trace contexts,
+	// as written by a ClientHandler with the same Signer.
+	Signer Signer
+
+	// OnSignatureFailure controls what happens when Signer is set and the
+	// signature of the incoming trace context is missing or invalid. It
+	// defaults to LinkUnsignedParent.
+	OnSignatureFailure SignatureFailurePolicy
+
+	// SampledParentsPerPeer may be set to limit the number of sampled remote
+	// parents honored per second for each peer, so a caller can't force
+	// every RPC to be sampled. Beyond the limit, new traces are started,
+	// linked to the remote parent, and the local sampler decides.
+	SampledParentsPerPeer int
+
+	// Principal may be set to record the authenticated principal of each RPC
+	// in the enduser.id span attribute, such as TLSPrincipal. It is off by
+	// default as principals may be personal data.
+	Principal PrincipalFunc
+
+	// PrincipalTag may be set to true to also tag the context of each RPC,
+	// and the measures recorded with it, with KeyServerPrincipal.
+	PrincipalTag bool
+
+	// TLSAttributes may be set to true to record the TLS connection of each
+	// RPC on its span: the negotiated version and cipher suite in the
+	// tls.protocol.version and tls.cipher attributes, and the subject of the
+	// verified client certificate in tls.client.subject. It is off by
+	// default as subjects may be personal data.
+	TLSAttributes bool
+
+	// MetadataAttributes may be set to record the values of these incoming
+	// metadata keys, such as "x-tenant-id" or "user-agent", on server spans
+	// in rpc.grpc.request.metadata.<key> attributes. Binary keys are
+	// ignored.
+	MetadataAttributes []string
+
+	// MetadataAttributeMaxLength is the length the values of
+	// MetadataAttributes are truncated to. It defaults to
+	// DefaultMetadataAttributeMaxLength.
+	MetadataAttributeMaxLength int
+
+	// RedactMetadataAttribute may be set to replace or drop the values of
+	// MetadataAttributes before they are recorded.
+	RedactMetadataAttribute MetadataAttributeRedactor
+
+	// Tenants may be set to customize the spans of each tenant.
+	Tenants *TenantHooks
+
+	// PeerAddress controls whether and how the IP address of callers is
+	// recorded on spans, in the net.peer.ip attribute. It defaults to
+	// OmitPeerAddress.
+	PeerAddress PeerAddressPolicy
+
+	// PeerAddressHashKey is the secret key of HashPeerAddress.
+	PeerAddressHashKey []byte
+
+	// Decisions may be set to keep the last propagation decisions of the
+	// handler, to inspect them on a debug endpoint.
+	Decisions *DecisionLog
+
+	// Filter may be set to ignore some RPCs, such as health checks: they get
+	// no span and no stats.
+	Filter FilterFunc
+
+	// TracingFlags may be set to turn tracing off for some methods at
+	// runtime. Stats are still recorded.
+	TracingFlags FeatureFlags
+
+	// Logger receives the warnings of the handler, such as panics recovered
+	// while handling RPC events. It defaults to grpclog.
+	Logger Logger
+
+	fallback   samplerFallback
+	duplicates duplicateDetector
+	parents    parentLimiter
 }
 
 var _ stats.Handler = (*ServerHandler)(nil)
 
-// HandleConn exists to satisfy gRPC stats.Handler.
+// HandleConn ends the spans of the RPCs still in flight when a connection
+// ends, with a "connection aborted" status.
 func (s *ServerHandler) HandleConn(ctx context.Context, cs stats.ConnStats) {
-	// no-op
+	if _, ok := cs.(*stats.ConnEnd); ok {
+		if spans := connSpansFromContext(ctx); spans != nil {
+			spans.endAll()
+		}
+	}
+	handleConnSpan(ctx, cs)
 }
 
-// TagConn exists to satisfy gRPC stats.Handler.
+// TagConn implements per-connection context management.
 func (s *ServerHandler) TagConn(ctx context.Context, cti *stats.ConnTagInfo) context.Context {
-	// no-op
+	ctx = newConnSpansContext(ctx)
+	if s.ConnectionSpans && !s.DisableTracing {
+		attrs := transportAttributes(cti.LocalAddr, cti.RemoteAddr, false)
+		if addr, ok := (peerAddressOptions{policy: s.PeerAddress, hashKey: s.PeerAddressHashKey}).format(cti.RemoteAddr); ok {
+			attrs = append(attrs, trace.StringAttribute(peerAddressAttribute, addr))
+		}
+		ctx = startConnSpan(ctx, s.sampler(""), attrs)
+	}
 	return ctx
 }
 
 // HandleRPC implements per-RPC tracing and stats instrumentation.
 func (s *ServerHandler) HandleRPC(ctx context.Context, rs stats.RPCStats) {
-	traceHandleRPC(ctx, rs)
-	statsHandleRPC(ctx, rs)
+	if !handles(ctx, serverOwnerKey{}, s, rs) {
+		return
+	}
+	defer recoverHandleRPC(ctx, s.Logger, rs)
+	if traced(ctx, false) {
+		traceHandleRPC(ctx, rs, traceOptions{
+			classifyContextErrors: s.ClassifyContextErrors,
+			errorCodes:            s.ErrorCodes,
+			peerAddress:           peerAddressOptions{policy: s.PeerAddress, hashKey: s.PeerAddressHashKey},
+			disableMessageEvents:  s.DisableMessageEvents,
+			messageEventLimit:     s.MessageEventLimit,
+			messageGapThreshold:   s.MessageGapThreshold,
+			spanLimits:            s.SpanLimits,
+			transportAttributes:   s.TransportAttributes,
+			payloads:              s.PayloadAnnotations,
+			status: statusOptions{
+				format:    s.FormatStatus,
+				redact:    s.RedactStatusMessage,
+				maxLength: s.StatusMessageMaxLength,
+			},
+			onEnd: s.tenantSpanEnding,
+		})
+	}
+	if !s.DisableStats {
+		statsHandleRPC(ctx, rs)
+	}
 }
 
 // TagRPC implements per-RPC context management.
-func (s *ServerHandler) TagRPC(ctx context.Context, rti *stats.RPCTagInfo) context.Context {
-	ctx = s.traceTagRPC(ctx, rti)
-	ctx = s.statsTagRPC(ctx, rti)
+func (s *ServerHandler) TagRPC(ctx context.Context, rti *stats.RPCTagInfo) (ret context.Context) {
+	defer recoverTagRPC(ctx, &ctx, s.Logger, &ret)
+	if filtered(s.Filter, rti.FullMethodName) {
+		// Not claiming the RPC makes HandleRPC ignore it too.
+		return ctx
+	}
+	ctx, ok := s.duplicates.claim(ctx, serverOwnerKey{}, s, "ServerHandler", s.Logger)
+	if !ok {
+		return ctx
+	}
+	ctx = withIncomingDeadline(ctx)
+	if s.PropagateJaeger {
+		ctx = forwardJaegerContext(ctx)
+	}
+	ctx = s.tenantTagRPC(ctx)
+	ctx = s.requestIDTagRPC(ctx)
+	ctx, tracing := tagTracing(ctx, s.DisableTracing, s.TracingFlags, rti.FullMethodName, false)
+	if tracing {
+		ctx = s.traceTagRPC(ctx, rti)
+		ctx = s.traceIDTagRPC(ctx)
+		addRequestIDAttribute(ctx)
+	}
+	if !s.DisableStats {
+		ctx = s.statsTagRPC(ctx, rti)
+	}
+	ctx = s.baggageToTags(ctx)
+	ctx = s.principalTagRPC(ctx)
+	s.tlsTagRPC(ctx)
+	s.metadataTagRPC(ctx)
 	return ctx
 }
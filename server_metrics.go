@@ -27,9 +27,47 @@ var (
 	ServerReceivedBytesPerRPC    = stats.Int64("grpc.io/server/received_bytes_per_rpc", "Total bytes received across all messages per RPC.", stats.UnitBytes)
 	ServerSentMessagesPerRPC     = stats.Int64("grpc.io/server/sent_messages_per_rpc", "Number of messages sent in each RPC. Has value 1 for non-streaming RPCs.", stats.UnitDimensionless)
 	ServerSentBytesPerRPC        = stats.Int64("grpc.io/server/sent_bytes_per_rpc", "Total bytes sent in across all response messages per RPC.", stats.UnitBytes)
+	ServerActiveRPCs             = stats.Int64("grpc.io/server/active_rpcs", "Change in the number of active RPCs, 1 when an RPC starts and -1 when it ends.", stats.UnitDimensionless)
 	ServerLatency                = stats.Float64("grpc.io/server/server_latency", "Time between first byte of request received to last byte of response sent, or terminal error.", stats.UnitMilliseconds)
 )
 
+// The following variables are measures of the compressed size of messages
+// recorded by ServerHandler. They are the same as ServerReceivedBytesPerRPC
+// and ServerSentBytesPerRPC when messages aren't compressed.
+var (
+	ServerReceivedCompressedBytesPerRPC = stats.Int64("grpc.io/server/received_compressed_message_bytes_per_rpc", "Total compressed bytes received across all messages per RPC, without gRPC framing.", stats.UnitBytes)
+	ServerSentCompressedBytesPerRPC     = stats.Int64("grpc.io/server/sent_compressed_message_bytes_per_rpc", "Total compressed bytes sent across all response messages per RPC, without gRPC framing.", stats.UnitBytes)
+)
+
+// The following variables are measures recorded by ServerHandler for
+// streaming RPCs only, so streams aren't drowned among unary RPCs:
+var (
+	ServerStreamDuration         = stats.Float64("grpc.io/server/stream_duration", "Time between the start of a stream and its end.", stats.UnitMilliseconds)
+	ServerStreamSentMessages     = stats.Int64("grpc.io/server/stream_sent_messages", "Number of messages sent per stream.", stats.UnitDimensionless)
+	ServerStreamReceivedMessages = stats.Int64("grpc.io/server/stream_received_messages", "Number of messages received per stream.", stats.UnitDimensionless)
+)
+
+// The following variables are measures of the metadata of the RPCs of
+// ServerHandler, since oversized metadata fails RPCs: the received header is
+// measured by its wire length, the sent header and trailer by their size
+// before compression, see metadataSize.
+var (
+	ServerReceivedHeaderBytes = stats.Int64("grpc.io/server/received_header_bytes", "Wire length of the header metadata received per RPC.", stats.UnitBytes)
+	ServerSentHeaderBytes     = stats.Int64("grpc.io/server/sent_header_bytes", "Size of the header metadata sent per RPC, before compression.", stats.UnitBytes)
+	ServerSentTrailerBytes    = stats.Int64("grpc.io/server/sent_trailer_bytes", "Size of the trailer metadata sent per RPC, before compression.", stats.UnitBytes)
+)
+
+// The following variables are measures recorded by ServerHandler while
+// extracting the incoming trace context:
+var (
+	ServerInvalidSpanContexts = stats.Int64("grpc.io/server/invalid_span_contexts", "Number of incoming span contexts ignored because they are malformed or have an invalid trace or span ID.", stats.UnitDimensionless)
+	ServerBaggageLimitedItems = stats.Int64("grpc.io/server/baggage_limited_items", "Number of incoming baggage items truncated or dropped because of the baggage limits.", stats.UnitDimensionless)
+	ServerSamplingDecisions   = stats.Int64("grpc.io/server/sampling_decisions", "Number of sampling decisions taken for the spans of RPCs.", stats.UnitDimensionless)
+
+	ServerForwardedMetadataLimitedItems = stats.Int64("grpc.io/server/forwarded_metadata_limited_items", "Number of incoming metadata values truncated or dropped because of the limits of the forwarded metadata.", stats.UnitDimensionless)
+	ServerSpanLimitDrops                = stats.Int64("grpc.io/server/span_limit_drops", "Number of span events dropped because of the span limits.", stats.UnitDimensionless)
+)
+
 // TODO(acetechnologist): This is temporary and will need to be replaced by a
 // mechanism to load these defaults from a common repository/config shared by
 // all supported languages. Likely a serialized protobuf of these defaults.
@@ -55,6 +93,22 @@ var (
 		Aggregation: DefaultBytesDistribution,
 	}
 
+	ServerReceivedCompressedBytesPerRPCView = &view.View{
+		Name:        "grpc.io/server/received_compressed_message_bytes_per_rpc",
+		Description: "Distribution of received compressed bytes per RPC, by method.",
+		Measure:     ServerReceivedCompressedBytesPerRPC,
+		TagKeys:     []tag.Key{KeyServerMethod},
+		Aggregation: DefaultBytesDistribution,
+	}
+
+	ServerSentCompressedBytesPerRPCView = &view.View{
+		Name:        "grpc.io/server/sent_compressed_message_bytes_per_rpc",
+		Description: "Distribution of total sent compressed bytes per RPC, by method.",
+		Measure:     ServerSentCompressedBytesPerRPC,
+		TagKeys:     []tag.Key{KeyServerMethod},
+		Aggregation: DefaultBytesDistribution,
+	}
+
 	ServerLatencyView = &view.View{
 		Name:        "grpc.io/server/server_latency",
 		Description: "Distribution of server latency in milliseconds, by method.",
@@ -71,6 +125,14 @@ var (
 		Aggregation: view.Count(),
 	}
 
+	ServerEndReasonsView = &view.View{
+		Name:        "grpc.io/server/end_reasons",
+		Description: "Count of RPCs by method and end reason.",
+		TagKeys:     []tag.Key{KeyServerMethod, KeyServerEndReason},
+		Measure:     ServerLatency,
+		Aggregation: view.Count(),
+	}
+
 	ServerReceivedMessagesPerRPCView = &view.View{
 		Name:        "grpc.io/server/received_messages_per_rpc",
 		Description: "Distribution of messages received count per RPC, by method.",
@@ -86,6 +148,102 @@ var (
 		Measure:     ServerSentMessagesPerRPC,
 		Aggregation: DefaultMessageCountDistribution,
 	}
+
+	ServerReceivedHeaderBytesView = &view.View{
+		Name:        "grpc.io/server/received_header_bytes",
+		Description: "Distribution of received header wire length, by method.",
+		TagKeys:     []tag.Key{KeyServerMethod},
+		Measure:     ServerReceivedHeaderBytes,
+		Aggregation: DefaultBytesDistribution,
+	}
+
+	ServerSentHeaderBytesView = &view.View{
+		Name:        "grpc.io/server/sent_header_bytes",
+		Description: "Distribution of sent header size, by method.",
+		TagKeys:     []tag.Key{KeyServerMethod},
+		Measure:     ServerSentHeaderBytes,
+		Aggregation: DefaultBytesDistribution,
+	}
+
+	ServerSentTrailerBytesView = &view.View{
+		Name:        "grpc.io/server/sent_trailer_bytes",
+		Description: "Distribution of sent trailer size, by method.",
+		TagKeys:     []tag.Key{KeyServerMethod},
+		Measure:     ServerSentTrailerBytes,
+		Aggregation: DefaultBytesDistribution,
+	}
+
+	ServerStreamDurationView = &view.View{
+		Name:        "grpc.io/server/stream_duration",
+		Description: "Distribution of stream duration, by method.",
+		TagKeys:     []tag.Key{KeyServerMethod},
+		Measure:     ServerStreamDuration,
+		Aggregation: DefaultMillisecondsDistribution,
+	}
+
+	ServerStreamSentMessagesView = &view.View{
+		Name:        "grpc.io/server/stream_sent_messages",
+		Description: "Distribution of messages sent count per stream, by method.",
+		TagKeys:     []tag.Key{KeyServerMethod},
+		Measure:     ServerStreamSentMessages,
+		Aggregation: DefaultMessageCountDistribution,
+	}
+
+	ServerStreamReceivedMessagesView = &view.View{
+		Name:        "grpc.io/server/stream_received_messages",
+		Description: "Distribution of messages received count per stream, by method.",
+		TagKeys:     []tag.Key{KeyServerMethod},
+		Measure:     ServerStreamReceivedMessages,
+		Aggregation: DefaultMessageCountDistribution,
+	}
+
+	ServerActiveRPCsView = &view.View{
+		Name:        "grpc.io/server/active_rpcs",
+		Description: "Number of active RPCs, by method.",
+		TagKeys:     []tag.Key{KeyServerMethod},
+		Measure:     ServerActiveRPCs,
+		Aggregation: view.Sum(),
+	}
+
+	ServerInvalidSpanContextsView = &view.View{
+		Name:        "grpc.io/server/invalid_span_contexts",
+		Description: "Count of ignored incoming span contexts, by method and format.",
+		TagKeys:     []tag.Key{KeyServerMethod, KeyServerPropagationFormat},
+		Measure:     ServerInvalidSpanContexts,
+		Aggregation: view.Count(),
+	}
+
+	ServerBaggageLimitedItemsView = &view.View{
+		Name:        "grpc.io/server/baggage_limited_items",
+		Description: "Sum of truncated or dropped baggage items, by method and action.",
+		TagKeys:     []tag.Key{KeyServerMethod, KeyBaggageLimitAction},
+		Measure:     ServerBaggageLimitedItems,
+		Aggregation: view.Sum(),
+	}
+
+	ServerForwardedMetadataLimitedItemsView = &view.View{
+		Name:        "grpc.io/server/forwarded_metadata_limited_items",
+		Description: "Sum of truncated or dropped forwarded metadata values, by method and action.",
+		TagKeys:     []tag.Key{KeyServerMethod, KeyBaggageLimitAction},
+		Measure:     ServerForwardedMetadataLimitedItems,
+		Aggregation: view.Sum(),
+	}
+
+	ServerSpanLimitDropsView = &view.View{
+		Name:        "grpc.io/server/span_limit_drops",
+		Description: "Sum of span events dropped by the span limits, by method and limit.",
+		TagKeys:     []tag.Key{KeyServerMethod, KeySpanLimit},
+		Measure:     ServerSpanLimitDrops,
+		Aggregation: view.Sum(),
+	}
+
+	ServerSamplingDecisionsView = &view.View{
+		Name:        "grpc.io/server/sampling_decisions",
+		Description: "Count of sampling decisions, by method, outcome and reason.",
+		TagKeys:     []tag.Key{KeyServerMethod, KeyServerSampled, KeyServerSamplingReason},
+		Measure:     ServerSamplingDecisions,
+		Aggregation: view.Count(),
+	}
 )
 
 // DefaultServerViews are the default server views provided by this package.
@@ -16,12 +16,13 @@
 package ocgrpc
 
 import (
+	"context"
 	"time"
 
-	"golang.org/x/net/context"
-
+	propag "github.com/akhenakh/ocgrpc_propagation/propagation"
 	"go.opencensus.io/tag"
 	"google.golang.org/grpc/grpclog"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/stats"
 )
 
@@ -36,20 +37,29 @@ func (h *ServerHandler) statsTagRPC(ctx context.Context, info *stats.RPCTagInfo)
 		return ctx
 	}
 	d := &rpcData{
-		startTime: startTime,
-		method:    info.FullMethodName,
+		startTime:     startTime,
+		method:        info.FullMethodName,
+		attributeTags: newRPCAttributeTags(h.AttributeTags),
 	}
 	propagated := h.extractPropagatedTags(ctx)
 	ctx = tag.NewContext(ctx, propagated)
 	ctx, _ = tag.New(ctx, tag.Upsert(KeyServerMethod, methodName(info.FullMethodName)))
-	return context.WithValue(ctx, rpcDataKey, d)
+	return context.WithValue(ctx, rpcDataKey{}, d)
 }
 
 // extractPropagatedTags creates a new tag map containing the tags extracted from the
-// gRPC metadata.
+// grpc-tags-bin metadata, ignored when larger than h.MaxTagsSize.
 func (h *ServerHandler) extractPropagatedTags(ctx context.Context) *tag.Map {
-	buf := stats.Tags(ctx)
-	if buf == nil {
+	md, _ := metadata.FromIncomingContext(ctx)
+	vs := propag.Lookup(md, tagsKey)
+	if len(vs) == 0 {
+		return nil
+	}
+	buf := []byte(vs[len(vs)-1])
+	if len(buf) > maxTagsSize(h.MaxTagsSize) {
+		if grpclog.V(2) {
+			grpclog.Warningf("opencensus: ignoring %d bytes of propagated tags, above the limit of %d", len(buf), maxTagsSize(h.MaxTagsSize))
+		}
 		return nil
 	}
 	propagated, err := tag.Decode(buf)
@@ -0,0 +1,86 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+
+	propag "github.com/akhenakh/ocgrpc_propagation/propagation"
+	"go.opencensus.io/trace"
+	"go.opencensus.io/trace/propagation"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/stats"
+)
+
+// Signer signs the trace contexts sent by clients, in the SignatureKey
+// metadata, and verifies the ones received by servers, so trace contexts
+// forged or altered on the way are detected.
+type Signer interface {
+	// Sign returns the signature of sc.
+	Sign(sc trace.SpanContext) []byte
+
+	// Verify reports whether sig is a valid signature of sc.
+	Verify(sc trace.SpanContext, sig []byte) bool
+}
+
+// HMACSigner signs trace contexts with HMAC-SHA256 keyed with Key. Every
+// service must share the same key.
+type HMACSigner struct {
+	Key []byte
+}
+
+// Sign implements Signer.
+func (h HMACSigner) Sign(sc trace.SpanContext) []byte {
+	mac := hmac.New(sha256.New, h.Key)
+	mac.Write(propagation.Binary(sc))
+	return mac.Sum(nil)
+}
+
+// Verify implements Signer.
+func (h HMACSigner) Verify(sc trace.SpanContext, sig []byte) bool {
+	return hmac.Equal(sig, h.Sign(sc))
+}
+
+// SignatureFailurePolicy controls what the ServerHandler does with incoming
+// trace contexts whose signature is missing or invalid.
+type SignatureFailurePolicy int
+
+const (
+	// LinkUnsignedParent starts a new trace linked to the incoming trace
+	// context, as public endpoints do.
+	LinkUnsignedParent SignatureFailurePolicy = iota
+
+	// InvalidateUnsignedParent ignores the incoming trace context as if it
+	// couldn't be parsed: OnExtractionFailure applies.
+	InvalidateUnsignedParent
+)
+
+var errInvalidSignature = errors.New("missing or invalid signature")
+
+// verifyParent reports whether the signature of parent in md is valid. It
+// always is when s has no Signer.
+func (s *ServerHandler) verifyParent(ctx context.Context, rti *stats.RPCTagInfo, md metadata.MD, parent trace.SpanContext) bool {
+	if s.Signer == nil {
+		return true
+	}
+	if sig := propag.Lookup(md, propag.SignatureKey); len(sig) > 0 && s.Signer.Verify(parent, []byte(sig[0])) {
+		return true
+	}
+	s.recordInvalidSpanContext(ctx, rti, "signature", errInvalidSignature)
+	return false
+}
@@ -0,0 +1,62 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	propag "github.com/akhenakh/ocgrpc_propagation/propagation"
+	"go.opencensus.io/trace"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestSignedSpanContextsConcurrently(t *testing.T) {
+	signer := HMACSigner{Key: []byte("secret")}
+	client := &ClientHandler{Signer: signer}
+	server := &ServerHandler{Signer: signer}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sc := trace.SpanContext{TraceID: trace.TraceID{15: byte(i + 1)}, SpanID: trace.SpanID{7: byte(i + 1)}, TraceOptions: 1}
+			out, _ := metadata.FromOutgoingContext(client.InjectSpanContext(context.Background(), sc))
+			got, ok := server.SpanContextFromIncomingContext(metadata.NewIncomingContext(context.Background(), out))
+			if !ok || got.TraceID != sc.TraceID || got.SpanID != sc.SpanID {
+				t.Errorf("got %v, %v, want %v, true", got, ok, sc)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestTamperedSpanContextRejected(t *testing.T) {
+	signer := HMACSigner{Key: []byte("secret")}
+	sc := trace.SpanContext{TraceID: trace.TraceID{15: 1}, SpanID: trace.SpanID{7: 1}, TraceOptions: 1}
+	out, _ := metadata.FromOutgoingContext((&ClientHandler{Signer: signer}).InjectSpanContext(context.Background(), sc))
+
+	other := &ServerHandler{Signer: HMACSigner{Key: []byte("other")}}
+	if _, ok := other.SpanContextFromIncomingContext(metadata.NewIncomingContext(context.Background(), out)); ok {
+		t.Error("signature of another key accepted")
+	}
+	unsigned := out.Copy()
+	unsigned.Delete(propag.SignatureKey)
+	if _, ok := (&ServerHandler{Signer: signer}).SpanContextFromIncomingContext(metadata.NewIncomingContext(context.Background(), unsigned)); ok {
+		t.Error("unsigned span context accepted")
+	}
+}
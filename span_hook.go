@@ -0,0 +1,43 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"context"
+
+	"go.opencensus.io/trace"
+	"google.golang.org/grpc/stats"
+)
+
+// SpanStartHook is called with the span of each RPC right after it is
+// started, to add attributes such as the tenant, the build version or feature
+// flags. ctx carries the span, and the incoming metadata on servers. Spans
+// that aren't sampled ignore attributes, see Span.IsRecordingEvents.
+type SpanStartHook func(ctx context.Context, span *trace.Span, rti *stats.RPCTagInfo)
+
+// spanStarted calls hook, if not nil, for the span of the RPC of ctx. A panic
+// in hook is recovered and recorded on the span, so the RPC is still traced.
+func spanStarted(ctx context.Context, hook SpanStartHook, l Logger, span *trace.Span, rti *stats.RPCTagInfo) {
+	if hook == nil {
+		return
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			warningf(l, "opencensus: recovered from panic in OnSpanStart: %v", r)
+			recordPanic(span, r, "Recovered from panic in OnSpanStart")
+		}
+	}()
+	hook(ctx, span, rti)
+}
@@ -0,0 +1,216 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"context"
+	"sync"
+
+	ocstats "go.opencensus.io/stats"
+	"go.opencensus.io/tag"
+	"go.opencensus.io/trace"
+)
+
+// Attributes recording the events dropped from the span of an RPC by its
+// SpanLimits.
+const (
+	droppedAnnotationsAttribute   = "grpc.dropped_annotations"
+	droppedMessageEventsAttribute = "grpc.dropped_message_events"
+	droppedAttributesAttribute    = "grpc.dropped_attributes"
+)
+
+// SpanLimits caps the annotations, message events and attributes the handlers
+// add to the span of an RPC while it runs, so the span of a high-volume
+// stream degrades gracefully instead of exceeding the span size limits of
+// exporters and being dropped entirely. Events over a limit are dropped; the
+// number dropped is recorded in the grpc.dropped_annotations,
+// grpc.dropped_message_events and grpc.dropped_attributes attributes and in
+// the ServerSpanLimitDrops and ClientSpanLimitDrops measures. Zero fields
+// don't limit.
+//
+// The limits apply to the events added after the span is started, message
+// events left by a MessageEventLimit included. The attributes recorded at the
+// end of the RPC, such as rpc.grpc.status_code, are always recorded.
+type SpanLimits struct {
+	// MaxAnnotations is the maximum number of annotations of the span.
+	MaxAnnotations int
+
+	// MaxMessageEvents is the maximum number of message events of the span,
+	// sent and received together.
+	MaxMessageEvents int
+
+	// MaxAttributes is the maximum number of distinct attributes the
+	// handlers add to the span. Attributes already set are still updated.
+	MaxAttributes int
+}
+
+func (l SpanLimits) enabled() bool {
+	return l.MaxAnnotations > 0 || l.MaxMessageEvents > 0 || l.MaxAttributes > 0
+}
+
+// spanLimitCounts counts the events added to and dropped from the span of an
+// RPC. It has its own mutex, as the events are added while rpcTraceData.mu is
+// held.
+type spanLimitCounts struct {
+	mu sync.Mutex
+
+	annotations, messageEvents int
+	attributes                 map[string]struct{}
+
+	droppedAnnotations, droppedMessageEvents, droppedAttributes int64
+}
+
+// limit sets the span traceHandleRPC adds the events of the RPC of d to:
+// span, limited by l, whose events are counted in d. It is called once per
+// RPC, by TagRPC.
+func (l SpanLimits) limit(span *trace.Span, d *rpcTraceData) {
+	if !l.enabled() || span == nil || d == nil {
+		return
+	}
+	d.limited = trace.NewSpan(&limitedSpan{SpanInterface: span.Internal(), limits: l, counts: &d.limits})
+}
+
+// span returns the span limited by SpanLimits.limit, or raw if the RPC of d
+// isn't limited.
+func (d *rpcTraceData) span(raw *trace.Span) *trace.Span {
+	if d == nil || d.limited == nil {
+		return raw
+	}
+	return d.limited
+}
+
+// limitedSpan drops the events of a span over its SpanLimits.
+type limitedSpan struct {
+	trace.SpanInterface
+	limits SpanLimits
+	counts *spanLimitCounts
+}
+
+func (s *limitedSpan) AddAttributes(attributes ...trace.Attribute) {
+	if s.limits.MaxAttributes <= 0 {
+		s.SpanInterface.AddAttributes(attributes...)
+		return
+	}
+	c := s.counts
+	c.mu.Lock()
+	kept := attributes[:0:0]
+	for i := range attributes {
+		k := attributes[i].Key()
+		if _, ok := c.attributes[k]; !ok {
+			if len(c.attributes) >= s.limits.MaxAttributes {
+				c.droppedAttributes++
+				continue
+			}
+			if c.attributes == nil {
+				c.attributes = make(map[string]struct{})
+			}
+			c.attributes[k] = struct{}{}
+		}
+		kept = append(kept, attributes[i])
+	}
+	c.mu.Unlock()
+	if len(kept) > 0 {
+		s.SpanInterface.AddAttributes(kept...)
+	}
+}
+
+// keepAnnotation reports whether another annotation may be added.
+func (s *limitedSpan) keepAnnotation() bool {
+	if s.limits.MaxAnnotations <= 0 {
+		return true
+	}
+	c := s.counts
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.annotations >= s.limits.MaxAnnotations {
+		c.droppedAnnotations++
+		return false
+	}
+	c.annotations++
+	return true
+}
+
+func (s *limitedSpan) Annotate(attributes []trace.Attribute, str string) {
+	if s.keepAnnotation() {
+		s.SpanInterface.Annotate(attributes, str)
+	}
+}
+
+func (s *limitedSpan) Annotatef(attributes []trace.Attribute, format string, a ...interface{}) {
+	if s.keepAnnotation() {
+		s.SpanInterface.Annotatef(attributes, format, a...)
+	}
+}
+
+// keepMessageEvent reports whether another message event may be added.
+func (s *limitedSpan) keepMessageEvent() bool {
+	if s.limits.MaxMessageEvents <= 0 {
+		return true
+	}
+	c := s.counts
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.messageEvents >= s.limits.MaxMessageEvents {
+		c.droppedMessageEvents++
+		return false
+	}
+	c.messageEvents++
+	return true
+}
+
+func (s *limitedSpan) AddMessageSendEvent(messageID, uncompressedByteSize, compressedByteSize int64) {
+	if s.keepMessageEvent() {
+		s.SpanInterface.AddMessageSendEvent(messageID, uncompressedByteSize, compressedByteSize)
+	}
+}
+
+func (s *limitedSpan) AddMessageReceiveEvent(messageID, uncompressedByteSize, compressedByteSize int64) {
+	if s.keepMessageEvent() {
+		s.SpanInterface.AddMessageReceiveEvent(messageID, uncompressedByteSize, compressedByteSize)
+	}
+}
+
+// addDropped records the events d dropped from span, which must not be
+// limited itself, when the RPC ends.
+func (l SpanLimits) addDropped(ctx context.Context, span *trace.Span, d *rpcTraceData, client bool) {
+	if !l.enabled() || d == nil {
+		return
+	}
+	c := &d.limits
+	c.mu.Lock()
+	dropped := []struct {
+		attribute, limit string
+		n                int64
+	}{
+		{droppedAnnotationsAttribute, "annotations", c.droppedAnnotations},
+		{droppedMessageEventsAttribute, "message_events", c.droppedMessageEvents},
+		{droppedAttributesAttribute, "attributes", c.droppedAttributes},
+	}
+	c.mu.Unlock()
+
+	key, m := KeyServerMethod, ServerSpanLimitDrops
+	if client {
+		key, m = KeyClientMethod, ClientSpanLimitDrops
+	}
+	for _, dr := range dropped {
+		if dr.n == 0 {
+			continue
+		}
+		span.AddAttributes(trace.Int64Attribute(dr.attribute, dr.n))
+		ocstats.RecordWithTags(ctx,
+			[]tag.Mutator{tag.Upsert(key, methodName(d.method)), tag.Upsert(KeySpanLimit, dr.limit)},
+			m.M(dr.n))
+	}
+}
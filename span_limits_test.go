@@ -0,0 +1,91 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/akhenakh/ocgrpc_propagation/propagationtest"
+	"go.opencensus.io/trace"
+	"google.golang.org/grpc/stats"
+)
+
+func TestSpanLimitsConcurrently(t *testing.T) {
+	e := propagationtest.Install(t)
+	ctx := startTracedRPC(t)
+	opts := traceOptions{spanLimits: SpanLimits{MaxAnnotations: 5, MaxMessageEvents: 10, MaxAttributes: 3}}
+	opts.spanLimits.limit(trace.FromContext(ctx), traceDataFromContext(ctx))
+	span := traceDataFromContext(ctx).span(trace.FromContext(ctx))
+
+	const n = 40
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			span.Annotate(nil, "event")
+		}()
+		go func(i int) {
+			defer wg.Done()
+			span.AddAttributes(trace.Int64Attribute(fmt.Sprintf("key%d", i%20), int64(i)))
+		}(i)
+	}
+	sendAndReceive(ctx, n, opts)
+	wg.Wait()
+	traceHandleRPC(ctx, &stats.End{EndTime: time.Now()}, opts)
+	// Each of the 20 keys is added twice: the 3 first keys are kept, the
+	// other 34 additions are dropped. The attributes added at the end of
+	// the RPC aren't limited.
+	c := &traceDataFromContext(ctx).limits
+	c.mu.Lock()
+	if want := int64(n - 2*3); c.droppedAttributes != want {
+		t.Errorf("%d attributes dropped, want %d", c.droppedAttributes, want)
+	}
+	c.mu.Unlock()
+
+	s := onlySpan(t, e)
+	if len(s.Annotations) != 5 {
+		t.Errorf("got %d annotations, want 5", len(s.Annotations))
+	}
+	if len(s.MessageEvents) != 10 {
+		t.Errorf("got %d message events, want 10", len(s.MessageEvents))
+	}
+	kept := 0
+	for k := range s.Attributes {
+		if strings.HasPrefix(k, "key") {
+			kept++
+		}
+	}
+	if kept != 3 {
+		t.Errorf("got %d attributes, want 3", kept)
+	}
+	for _, k := range []string{statusCodeAttribute, statusNameAttribute, endReasonAttribute, endTimeAttribute} {
+		if _, ok := s.Attributes[k]; !ok {
+			t.Errorf("attribute %s not recorded", k)
+		}
+	}
+	for attribute, want := range map[string]int64{
+		droppedAnnotationsAttribute:   n - 5,
+		droppedMessageEventsAttribute: 2*n - 10,
+	} {
+		if got := s.Attributes[attribute]; got != want {
+			t.Errorf("%s = %v, want %d", attribute, got, want)
+		}
+	}
+}
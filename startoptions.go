@@ -0,0 +1,47 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"context"
+
+	"go.opencensus.io/trace"
+)
+
+type startOptionsKey struct{}
+
+// WithStartOptions returns a copy of ctx with o, consulted by ClientHandler
+// before starting the span of the RPCs made with the returned context, so a
+// call site can choose another sampler or span kind without a second
+// ClientConn. The zero Sampler and SpanKind keep the ones of the handler.
+func WithStartOptions(ctx context.Context, o trace.StartOptions) context.Context {
+	return context.WithValue(ctx, startOptionsKey{}, o)
+}
+
+// clientStartOptions returns the sampler and span kind of the client span of
+// the RPC of ctx, overriding sampler and trace.SpanKindClient with the
+// StartOptions set by WithStartOptions.
+func clientStartOptions(ctx context.Context, sampler trace.Sampler) (trace.Sampler, int) {
+	kind := trace.SpanKindClient
+	if o, ok := ctx.Value(startOptionsKey{}).(trace.StartOptions); ok {
+		if o.Sampler != nil {
+			sampler = o.Sampler
+		}
+		if o.SpanKind != trace.SpanKindUnspecified {
+			kind = o.SpanKind
+		}
+	}
+	return sampler, kind
+}
@@ -22,16 +22,21 @@ import (
 	"sync/atomic"
 	"time"
 
+	"go.opencensus.io/metric/metricdata"
 	ocstats "go.opencensus.io/stats"
 	"go.opencensus.io/stats/view"
 	"go.opencensus.io/tag"
+	"go.opencensus.io/trace"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/grpclog"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/stats"
 	"google.golang.org/grpc/status"
 )
 
-type grpcInstrumentationKey string
+// rpcDataKey is the context key of the *rpcData of an RPC. Context keys are
+// unexported struct types, so they can't collide with keys of other packages.
+type rpcDataKey struct{}
 
 // rpcData holds the instrumentation RPC data that is needed between the start
 // and end of an call. It holds the info that this package needs to keep track
@@ -41,11 +46,26 @@ type rpcData struct {
 	// in order to be 64-aligned on 32-bit architectures.
 	sentCount, sentBytes, recvCount, recvBytes int64 // access atomically
 
+	// sentCompressedBytes and recvCompressedBytes are the compressed
+	// counterparts of sentBytes and recvBytes.
+	sentCompressedBytes, recvCompressedBytes int64 // access atomically
+
+	// streaming is 1 for streaming RPCs, as reported by stats.Begin.
+	streaming int32 // access atomically
+
+	// trailerReceived is 1 once a client RPC received the trailer of the
+	// server.
+	trailerReceived int32 // access atomically
+
 	// startTime represents the time at which TagRPC was invoked at the
 	// beginning of an RPC. It is an appoximation of the time when the
 	// application code invoked GRPC code.
 	startTime time.Time
 	method    string
+
+	// attributeTags holds the tags added with AddAttributes, nil unless the
+	// ServerHandler has AttributeTags.
+	attributeTags *rpcAttributeTags
 }
 
 // The following variables define the default hard-coded auxiliary data used by
@@ -56,6 +76,21 @@ var (
 	DefaultMessageCountDistribution = view.Distribution(0, 1, 2, 4, 8, 16, 32, 64, 128, 256, 512, 1024, 2048, 4096, 8192, 16384, 32768, 65536)
 )
 
+// tagsKey is the metadata key carrying the encoded tags of the client.
+const tagsKey = "grpc-tags-bin"
+
+// DefaultMaxTagsSize is the size above which encoded tags aren't propagated
+// when the MaxTagsSize of the handlers isn't set.
+const DefaultMaxTagsSize = 8192
+
+// maxTagsSize returns size, or DefaultMaxTagsSize if it isn't set.
+func maxTagsSize(size int) int {
+	if size <= 0 {
+		return DefaultMaxTagsSize
+	}
+	return size
+}
+
 // Server tags are applied to the context used to process each RPC, as well as
 // the measures at the end of each RPC.
 var (
@@ -63,15 +98,78 @@ var (
 	KeyServerStatus, _ = tag.NewKey("grpc_server_status")
 )
 
+// KeyServerEndReason and KeyClientEndReason are applied to the measures at
+// the end of each RPC. Their value tells why the RPC ended: ok, canceled,
+// deadline_exceeded, transport_error or application_error.
+var (
+	KeyServerEndReason, _ = tag.NewKey("grpc_server_end_reason")
+	KeyClientEndReason, _ = tag.NewKey("grpc_client_end_reason")
+)
+
+// KeyServerPropagationFormat is applied to the measures recorded while
+// extracting the incoming trace context. Its value is the format the trace
+// context was read from, such as "binary" or "jaeger".
+var KeyServerPropagationFormat, _ = tag.NewKey("grpc_server_propagation_format")
+
+// KeyServerSampled and KeyServerSamplingReason are applied to the
+// ServerSamplingDecisions measure. Their values are "true" or "false", and
+// the SamplingReason of the decision.
+var (
+	KeyServerSampled, _        = tag.NewKey("grpc_server_sampled")
+	KeyServerSamplingReason, _ = tag.NewKey("grpc_server_sampling_reason")
+)
+
+// KeyBaggageLimitAction is applied to the measures of baggage items and
+// forwarded metadata values limited while being propagated. Its value is either "truncated" or "dropped".
+var KeyBaggageLimitAction, _ = tag.NewKey("grpc_baggage_limit_action")
+
+// KeySpanLimit is applied to the measures of the events dropped by
+// SpanLimits. Its value is "annotations", "message_events" or "attributes".
+var KeySpanLimit, _ = tag.NewKey("grpc_span_limit")
+
+// KeyServerPrincipal is applied to the context used to process each RPC when
+// ServerHandler.PrincipalTag is set. Its value is the authenticated principal
+// returned by ServerHandler.Principal. Add it to the TagKeys of a view to
+// aggregate by principal.
+var KeyServerPrincipal, _ = tag.NewKey("grpc_server_principal")
+
 // Client tags are applied to measures at the end of each RPC.
 var (
 	KeyClientMethod, _ = tag.NewKey("grpc_client_method")
 	KeyClientStatus, _ = tag.NewKey("grpc_client_status")
 )
 
-var (
-	rpcDataKey = grpcInstrumentationKey("opencensus-rpcData")
-)
+// KeyClientTarget is applied to the measures of the connections of
+// ClientHandler. Its value is the remote address of the connection, the
+// address of the server instance.
+var KeyClientTarget, _ = tag.NewKey("grpc_client_target")
+
+// KeyClientDialTarget is applied to the measures of the RPCs of ClientHandler
+// when the Target client interceptors are installed. Its value is the target
+// the connection was dialed with, such as dns:///backend:443, identifying the
+// destination service rather than one of its instances.
+var KeyClientDialTarget, _ = tag.NewKey("grpc_client_dial_target")
+
+// grpcMessageHeaderLength is the length of the gRPC framing of each message:
+// a compression flag and the length of the message.
+const grpcMessageHeaderLength = 5
+
+// payloadSizes returns the uncompressed and compressed sizes of a message,
+// without gRPC framing, from the lengths of an InPayload or OutPayload. Older
+// gRPC versions don't set CompressedLength, in which case it is derived from
+// WireLength, if set, or else the message is considered uncompressed.
+func payloadSizes(length, compressedLength, wireLength int) (uncompressed, compressed int64) {
+	uncompressed = int64(length)
+	switch {
+	case compressedLength > 0:
+		compressed = int64(compressedLength)
+	case wireLength >= grpcMessageHeaderLength:
+		compressed = int64(wireLength - grpcMessageHeaderLength)
+	default:
+		compressed = uncompressed
+	}
+	return uncompressed, compressed
+}
 
 func methodName(fullname string) string {
 	return strings.TrimLeft(fullname, "/")
@@ -80,8 +178,10 @@ func methodName(fullname string) string {
 // statsHandleRPC processes the RPC events.
 func statsHandleRPC(ctx context.Context, s stats.RPCStats) {
 	switch st := s.(type) {
-	case *stats.Begin, *stats.OutHeader, *stats.InHeader, *stats.InTrailer, *stats.OutTrailer:
-		// do nothing for client
+	case *stats.Begin:
+		handleRPCBegin(ctx, st)
+	case *stats.OutHeader, *stats.InHeader, *stats.InTrailer, *stats.OutTrailer:
+		handleRPCMetadata(ctx, st)
 	case *stats.OutPayload:
 		handleRPCOutPayload(ctx, st)
 	case *stats.InPayload:
@@ -93,8 +193,94 @@ func statsHandleRPC(ctx context.Context, s stats.RPCStats) {
 	}
 }
 
+// handleRPCBegin counts the RPC as active until handleRPCEnd.
+func handleRPCBegin(ctx context.Context, s *stats.Begin) {
+	d, ok := ctx.Value(rpcDataKey{}).(*rpcData)
+	if !ok {
+		if grpclog.V(2) {
+			grpclog.Infoln("Failed to retrieve *rpcData from context.")
+		}
+		return
+	}
+
+	if s.IsClientStream || s.IsServerStream {
+		atomic.StoreInt32(&d.streaming, 1)
+	}
+	if s.Client {
+		ocstats.RecordWithTags(ctx,
+			[]tag.Mutator{tag.Upsert(KeyClientMethod, methodName(d.method))},
+			ClientActiveRPCs.M(1))
+		if s.IsTransparentRetryAttempt || attemptFromContext(ctx) > 1 {
+			ocstats.RecordWithTags(ctx,
+				[]tag.Mutator{tag.Upsert(KeyClientMethod, methodName(d.method))},
+				ClientRetries.M(1))
+		}
+	} else {
+		ocstats.Record(ctx, ServerActiveRPCs.M(1))
+	}
+}
+
+// handleRPCMetadata records the size of the header or trailer of s.
+func handleRPCMetadata(ctx context.Context, s stats.RPCStats) {
+	d, ok := ctx.Value(rpcDataKey{}).(*rpcData)
+	if !ok {
+		if grpclog.V(2) {
+			grpclog.Infoln("Failed to retrieve *rpcData from context.")
+		}
+		return
+	}
+
+	var m ocstats.Measurement
+	switch s := s.(type) {
+	case *stats.InHeader:
+		m = ServerReceivedHeaderBytes.M(int64(s.WireLength))
+		if s.Client {
+			m = ClientReceivedHeaderBytes.M(int64(s.WireLength))
+		}
+	case *stats.OutHeader:
+		m = ServerSentHeaderBytes.M(metadataSize(s.Header))
+		if s.Client {
+			m = ClientSentHeaderBytes.M(metadataSize(s.Header))
+		}
+	case *stats.InTrailer:
+		// Only clients receive trailers.
+		if !s.Client {
+			return
+		}
+		atomic.StoreInt32(&d.trailerReceived, 1)
+		m = ClientReceivedTrailerBytes.M(int64(s.WireLength))
+	case *stats.OutTrailer:
+		// Only servers send trailers.
+		if s.Client {
+			return
+		}
+		m = ServerSentTrailerBytes.M(metadataSize(s.Trailer))
+	}
+	if s.IsClient() {
+		ocstats.RecordWithTags(ctx,
+			[]tag.Mutator{tag.Upsert(KeyClientMethod, methodName(d.method))},
+			m)
+	} else {
+		ocstats.Record(ctx, m)
+	}
+}
+
+// metadataSize returns the size of md as counted by the HTTP/2 header list
+// size limits: the length of every key and value plus 32 bytes per field.
+// gRPC doesn't report the wire length of the metadata it sends, which is
+// HPACK-compressed after the stats event.
+func metadataSize(md metadata.MD) int64 {
+	var n int64
+	for k, vs := range md {
+		for _, v := range vs {
+			n += int64(len(k) + len(v) + 32)
+		}
+	}
+	return n
+}
+
 func handleRPCOutPayload(ctx context.Context, s *stats.OutPayload) {
-	d, ok := ctx.Value(rpcDataKey).(*rpcData)
+	d, ok := ctx.Value(rpcDataKey{}).(*rpcData)
 	if !ok {
 		if grpclog.V(2) {
 			grpclog.Infoln("Failed to retrieve *rpcData from context.")
@@ -102,12 +288,14 @@ func handleRPCOutPayload(ctx context.Context, s *stats.OutPayload) {
 		return
 	}
 
-	atomic.AddInt64(&d.sentBytes, int64(s.Length))
+	uncompressed, compressed := payloadSizes(s.Length, s.CompressedLength, s.WireLength)
+	atomic.AddInt64(&d.sentBytes, uncompressed)
+	atomic.AddInt64(&d.sentCompressedBytes, compressed)
 	atomic.AddInt64(&d.sentCount, 1)
 }
 
 func handleRPCInPayload(ctx context.Context, s *stats.InPayload) {
-	d, ok := ctx.Value(rpcDataKey).(*rpcData)
+	d, ok := ctx.Value(rpcDataKey{}).(*rpcData)
 	if !ok {
 		if grpclog.V(2) {
 			grpclog.Infoln("Failed to retrieve *rpcData from context.")
@@ -115,12 +303,14 @@ func handleRPCInPayload(ctx context.Context, s *stats.InPayload) {
 		return
 	}
 
-	atomic.AddInt64(&d.recvBytes, int64(s.Length))
+	uncompressed, compressed := payloadSizes(s.Length, s.CompressedLength, s.WireLength)
+	atomic.AddInt64(&d.recvBytes, uncompressed)
+	atomic.AddInt64(&d.recvCompressedBytes, compressed)
 	atomic.AddInt64(&d.recvCount, 1)
 }
 
 func handleRPCEnd(ctx context.Context, s *stats.End) {
-	d, ok := ctx.Value(rpcDataKey).(*rpcData)
+	d, ok := ctx.Value(rpcDataKey{}).(*rpcData)
 	if !ok {
 		if grpclog.V(2) {
 			grpclog.Infoln("Failed to retrieve *rpcData from context.")
@@ -129,40 +319,78 @@ func handleRPCEnd(ctx context.Context, s *stats.End) {
 	}
 
 	elapsedTime := time.Since(d.startTime)
+	ctx = d.attributeTags.apply(ctx)
 
-	var st string
+	st := "OK"
 	if s.Error != nil {
-		s, ok := status.FromError(s.Error)
-		if ok {
-			st = statusCodeToString(s)
-		}
-	} else {
-		st = "OK"
+		// Errors that aren't statuses are tagged with the code gRPC maps
+		// them to, rather than an empty status.
+		code := codes.Code(DefaultStatusFormatter(s.Error).Code)
+		st = statusCodeToString(status.New(code, ""))
 	}
 
+	reason := endReason(ctx, s, atomic.LoadInt32(&d.trailerReceived) == 1)
 	latencyMillis := float64(elapsedTime) / float64(time.Millisecond)
 	if s.Client {
-		ocstats.RecordWithTags(ctx,
-			[]tag.Mutator{
+		ocstats.RecordWithOptions(ctx,
+			ocstats.WithAttachments(exemplarAttachments(ctx)),
+			ocstats.WithTags(
 				tag.Upsert(KeyClientMethod, methodName(d.method)),
 				tag.Upsert(KeyClientStatus, st),
-			},
-			ClientSentBytesPerRPC.M(atomic.LoadInt64(&d.sentBytes)),
-			ClientSentMessagesPerRPC.M(atomic.LoadInt64(&d.sentCount)),
-			ClientReceivedMessagesPerRPC.M(atomic.LoadInt64(&d.recvCount)),
-			ClientReceivedBytesPerRPC.M(atomic.LoadInt64(&d.recvBytes)),
-			ClientRoundtripLatency.M(latencyMillis))
-	} else {
+				tag.Upsert(KeyClientEndReason, reason),
+			),
+			ocstats.WithMeasurements(
+				ClientSentBytesPerRPC.M(atomic.LoadInt64(&d.sentBytes)),
+				ClientSentMessagesPerRPC.M(atomic.LoadInt64(&d.sentCount)),
+				ClientReceivedMessagesPerRPC.M(atomic.LoadInt64(&d.recvCount)),
+				ClientReceivedBytesPerRPC.M(atomic.LoadInt64(&d.recvBytes)),
+				ClientSentCompressedBytesPerRPC.M(atomic.LoadInt64(&d.sentCompressedBytes)),
+				ClientReceivedCompressedBytesPerRPC.M(atomic.LoadInt64(&d.recvCompressedBytes)),
+				ClientRoundtripLatency.M(latencyMillis)))
 		ocstats.RecordWithTags(ctx,
-			[]tag.Mutator{
+			[]tag.Mutator{tag.Upsert(KeyClientMethod, methodName(d.method))},
+			ClientActiveRPCs.M(-1))
+		if atomic.LoadInt32(&d.streaming) == 1 {
+			ocstats.RecordWithTags(ctx,
+				[]tag.Mutator{tag.Upsert(KeyClientMethod, methodName(d.method))},
+				ClientStreamDuration.M(latencyMillis),
+				ClientStreamSentMessages.M(atomic.LoadInt64(&d.sentCount)),
+				ClientStreamReceivedMessages.M(atomic.LoadInt64(&d.recvCount)))
+		}
+	} else {
+		ocstats.RecordWithOptions(ctx,
+			ocstats.WithAttachments(exemplarAttachments(ctx)),
+			ocstats.WithTags(
 				tag.Upsert(KeyServerStatus, st),
-			},
-			ServerSentBytesPerRPC.M(atomic.LoadInt64(&d.sentBytes)),
-			ServerSentMessagesPerRPC.M(atomic.LoadInt64(&d.sentCount)),
-			ServerReceivedMessagesPerRPC.M(atomic.LoadInt64(&d.recvCount)),
-			ServerReceivedBytesPerRPC.M(atomic.LoadInt64(&d.recvBytes)),
-			ServerLatency.M(latencyMillis))
+				tag.Upsert(KeyServerEndReason, reason),
+			),
+			ocstats.WithMeasurements(
+				ServerSentBytesPerRPC.M(atomic.LoadInt64(&d.sentBytes)),
+				ServerSentMessagesPerRPC.M(atomic.LoadInt64(&d.sentCount)),
+				ServerReceivedMessagesPerRPC.M(atomic.LoadInt64(&d.recvCount)),
+				ServerReceivedBytesPerRPC.M(atomic.LoadInt64(&d.recvBytes)),
+				ServerSentCompressedBytesPerRPC.M(atomic.LoadInt64(&d.sentCompressedBytes)),
+				ServerReceivedCompressedBytesPerRPC.M(atomic.LoadInt64(&d.recvCompressedBytes)),
+				ServerLatency.M(latencyMillis)))
+		ocstats.Record(ctx, ServerActiveRPCs.M(-1))
+		if atomic.LoadInt32(&d.streaming) == 1 {
+			ocstats.Record(ctx,
+				ServerStreamDuration.M(latencyMillis),
+				ServerStreamSentMessages.M(atomic.LoadInt64(&d.sentCount)),
+				ServerStreamReceivedMessages.M(atomic.LoadInt64(&d.recvCount)))
+		}
+	}
+}
+
+// exemplarAttachments returns the attachments of the measures recorded at the
+// end of the RPC of ctx: the SpanContext of its span if it is sampled, so
+// exporters supporting exemplars can link distribution buckets to traces.
+func exemplarAttachments(ctx context.Context) metricdata.Attachments {
+	span := trace.FromContext(ctx)
+	if span == nil || !span.SpanContext().IsSampled() {
+		return nil
 	}
+	return metricdata.Attachments{metricdata.AttachmentKeySpanContext: span.SpanContext()}
 }
 
 func statusCodeToString(s *status.Status) string {
@@ -0,0 +1,107 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"context"
+	"errors"
+
+	"go.opencensus.io/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Attributes recording the final code of every RPC, including successful
+// ones, so queries over status codes don't need to handle missing values.
+const (
+	statusCodeAttribute = "rpc.grpc.status_code"
+	statusNameAttribute = "rpc.grpc.status_name"
+)
+
+// OTelServerErrorCodes are the codes considered errors on server spans by the
+// OpenTelemetry gRPC semantic conventions. Other codes, such as NotFound, are
+// the caller's problem rather than the server's.
+var OTelServerErrorCodes = map[codes.Code]bool{
+	codes.Unknown:          true,
+	codes.DeadlineExceeded: true,
+	codes.Unimplemented:    true,
+	codes.Internal:         true,
+	codes.Unavailable:      true,
+	codes.DataLoss:         true,
+}
+
+// OTelClientErrorCodes are the codes considered errors on client spans by the
+// OpenTelemetry gRPC semantic conventions: every code but OK.
+var OTelClientErrorCodes = map[codes.Code]bool{
+	codes.Canceled:           true,
+	codes.Unknown:            true,
+	codes.InvalidArgument:    true,
+	codes.DeadlineExceeded:   true,
+	codes.NotFound:           true,
+	codes.AlreadyExists:      true,
+	codes.PermissionDenied:   true,
+	codes.ResourceExhausted:  true,
+	codes.FailedPrecondition: true,
+	codes.Aborted:            true,
+	codes.OutOfRange:         true,
+	codes.Unimplemented:      true,
+	codes.Internal:           true,
+	codes.Unavailable:        true,
+	codes.DataLoss:           true,
+	codes.Unauthenticated:    true,
+}
+
+// StatusFormatter returns the status of the span of an RPC ending with err, a
+// non-nil error.
+type StatusFormatter func(err error) trace.Status
+
+// DefaultStatusFormatter is the StatusFormatter used when the handlers don't
+// set FormatStatus. The code and message of gRPC statuses are used as is,
+// since OpenCensus codes are the canonical gRPC codes. Context errors map to
+// Canceled and DeadlineExceeded, and other errors to Unknown, as gRPC does.
+func DefaultStatusFormatter(err error) trace.Status {
+	if s, ok := status.FromError(err); ok {
+		return trace.Status{Code: int32(s.Code()), Message: s.Message()}
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		s := status.FromContextError(err)
+		return trace.Status{Code: int32(s.Code()), Message: s.Message()}
+	}
+	return trace.Status{Code: int32(codes.Unknown), Message: err.Error()}
+}
+
+// statusOptions holds the handler settings of span statuses.
+type statusOptions struct {
+	format    StatusFormatter
+	redact    func(code codes.Code, msg string) string
+	maxLength int
+}
+
+// status returns the span status of an RPC ending with err, a non-nil error,
+// with its message redacted and truncated.
+func (o statusOptions) status(err error) trace.Status {
+	format := o.format
+	if format == nil {
+		format = DefaultStatusFormatter
+	}
+	st := format(err)
+	if o.redact != nil {
+		st.Message = o.redact(codes.Code(st.Code), st.Message)
+	}
+	if o.maxLength > 0 {
+		st.Message = truncateUTF8(st.Message, o.maxLength)
+	}
+	return st
+}
@@ -0,0 +1,77 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"go.opencensus.io/trace"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// annotateStatusDetails annotates span with the details of s, such as the
+// RetryInfo or the field violations of a BadRequest, so they can be seen
+// along the code and message of the status. Details of other types are
+// annotated with their type only.
+func annotateStatusDetails(span *trace.Span, s *status.Status) {
+	if len(s.Proto().GetDetails()) == 0 || !span.IsRecordingEvents() {
+		return
+	}
+	for _, d := range s.Details() {
+		switch d := d.(type) {
+		case *errdetails.RetryInfo:
+			span.Annotate([]trace.Attribute{
+				trace.Int64Attribute("retry_delay_ms", d.GetRetryDelay().AsDuration().Milliseconds()),
+			}, "Retry info")
+		case *errdetails.ErrorInfo:
+			span.Annotate([]trace.Attribute{
+				trace.StringAttribute("reason", d.GetReason()),
+				trace.StringAttribute("domain", d.GetDomain()),
+			}, "Error info")
+		case *errdetails.BadRequest:
+			for _, v := range d.GetFieldViolations() {
+				span.Annotate([]trace.Attribute{
+					trace.StringAttribute("field", v.GetField()),
+					trace.StringAttribute("description", v.GetDescription()),
+				}, "Bad request field violation")
+			}
+		case *errdetails.QuotaFailure:
+			for _, v := range d.GetViolations() {
+				span.Annotate([]trace.Attribute{
+					trace.StringAttribute("subject", v.GetSubject()),
+					trace.StringAttribute("description", v.GetDescription()),
+				}, "Quota failure violation")
+			}
+		case *errdetails.PreconditionFailure:
+			for _, v := range d.GetViolations() {
+				span.Annotate([]trace.Attribute{
+					trace.StringAttribute("type", v.GetType()),
+					trace.StringAttribute("subject", v.GetSubject()),
+					trace.StringAttribute("description", v.GetDescription()),
+				}, "Precondition failure violation")
+			}
+		case *errdetails.ResourceInfo:
+			span.Annotate([]trace.Attribute{
+				trace.StringAttribute("resource_type", d.GetResourceType()),
+				trace.StringAttribute("resource_name", d.GetResourceName()),
+				trace.StringAttribute("description", d.GetDescription()),
+			}, "Resource info")
+		case proto.Message:
+			span.Annotate([]trace.Attribute{
+				trace.StringAttribute("type", string(d.ProtoReflect().Descriptor().FullName())),
+			}, "Status detail")
+		}
+	}
+}
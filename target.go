@@ -0,0 +1,68 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"context"
+
+	"go.opencensus.io/tag"
+	"google.golang.org/grpc"
+)
+
+// targetAttribute is the attribute of client spans holding the target the
+// connection of the RPC was dialed with.
+const targetAttribute = "grpc.target"
+
+type targetKey struct{}
+
+// TargetUnaryClientInterceptor records the target the connection was dialed
+// with, as returned by grpc.ClientConn.Target, for ClientHandler to add it to
+// the client span in the grpc.target attribute and to the measures of the RPC
+// in the KeyClientDialTarget tag. This splits latency and errors by
+// destination when a single ClientHandler serves the connections to several
+// backends.
+func TargetUnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(context.WithValue(ctx, targetKey{}, cc.Target()), method, req, reply, cc, opts...)
+	}
+}
+
+// TargetStreamClientInterceptor records the target of the connection of each
+// stream, see TargetUnaryClientInterceptor.
+func TargetStreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(context.WithValue(ctx, targetKey{}, cc.Target()), desc, cc, method, opts...)
+	}
+}
+
+// targetFromContext returns the target recorded by the Target interceptors,
+// or an empty string.
+func targetFromContext(ctx context.Context) string {
+	t, _ := ctx.Value(targetKey{}).(string)
+	return t
+}
+
+// targetTagRPC returns a copy of ctx with the KeyClientDialTarget tag set to
+// the target of the RPC, if it was recorded.
+func targetTagRPC(ctx context.Context) context.Context {
+	t := targetFromContext(ctx)
+	if t == "" {
+		return ctx
+	}
+	if tctx, err := tag.New(ctx, tag.Upsert(KeyClientDialTarget, t)); err == nil {
+		return tctx
+	}
+	return ctx
+}
@@ -0,0 +1,88 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"context"
+
+	"go.opencensus.io/trace"
+	"google.golang.org/grpc/stats"
+)
+
+// TenantHooks customizes the server spans of each tenant, such as their
+// attributes or sampling, or suppresses them for tenants who opted out of
+// tracing.
+type TenantHooks struct {
+	// Tenant returns the tenant of the RPC of ctx, from its incoming
+	// metadata for instance. ok is false when the RPC has no tenant, in
+	// which case the other hooks aren't called.
+	Tenant func(ctx context.Context) (tenant string, ok bool)
+
+	// Sampler may be set to return the sampler of the spans of tenant, such
+	// as trace.NeverSample() for tenants who opted out. If it returns nil,
+	// the sampler of the handler applies.
+	Sampler func(tenant string) trace.Sampler
+
+	// Start may be set to be called when the span of an RPC of tenant has
+	// started.
+	Start func(ctx context.Context, tenant string, span *trace.Span)
+
+	// End may be set to be called before the span of an RPC of tenant ends.
+	End func(ctx context.Context, tenant string, span *trace.Span, end *stats.End)
+}
+
+type tenantKey struct{}
+
+// tenantTagRPC returns ctx with the tenant of its RPC, if any.
+func (s *ServerHandler) tenantTagRPC(ctx context.Context) context.Context {
+	if s.Tenants == nil || s.Tenants.Tenant == nil {
+		return ctx
+	}
+	if tenant, ok := s.Tenants.Tenant(ctx); ok {
+		ctx = context.WithValue(ctx, tenantKey{}, tenant)
+	}
+	return ctx
+}
+
+// tenantFromContext returns the tenant stored by tenantTagRPC.
+func tenantFromContext(ctx context.Context) (string, bool) {
+	tenant, ok := ctx.Value(tenantKey{}).(string)
+	return tenant, ok
+}
+
+// spanSampler returns the sampler of the span of the RPC of ctx and method,
+// nil meaning the global default sampler.
+func (s *ServerHandler) spanSampler(ctx context.Context, method string) trace.Sampler {
+	if tenant, ok := tenantFromContext(ctx); ok && s.Tenants.Sampler != nil {
+		if sampler := s.Tenants.Sampler(tenant); sampler != nil {
+			return sampler
+		}
+	}
+	return s.sampler(method)
+}
+
+// tenantSpanStarted calls the Start hook for the span of the RPC of ctx.
+func (s *ServerHandler) tenantSpanStarted(ctx context.Context, span *trace.Span) {
+	if tenant, ok := tenantFromContext(ctx); ok && s.Tenants.Start != nil {
+		s.Tenants.Start(ctx, tenant, span)
+	}
+}
+
+// tenantSpanEnding calls the End hook for the span of the RPC of ctx.
+func (s *ServerHandler) tenantSpanEnding(ctx context.Context, span *trace.Span, end *stats.End) {
+	if tenant, ok := tenantFromContext(ctx); ok && s.Tenants.End != nil {
+		s.Tenants.End(ctx, tenant, span, end)
+	}
+}
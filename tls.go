@@ -0,0 +1,75 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+
+	"go.opencensus.io/trace"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// Attributes recording the TLS connection of an RPC when
+// ServerHandler.TLSAttributes is set, named after the OpenTelemetry
+// conventions.
+const (
+	tlsVersionAttribute       = "tls.protocol.version"
+	tlsCipherAttribute        = "tls.cipher"
+	tlsClientSubjectAttribute = "tls.client.subject"
+)
+
+// tlsTagRPC records the negotiated TLS version and cipher suite of the RPC of
+// ctx, and the subject of the verified client certificate, on its span.
+func (s *ServerHandler) tlsTagRPC(ctx context.Context) {
+	if !s.TLSAttributes {
+		return
+	}
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return
+	}
+	attrs := []trace.Attribute{
+		trace.StringAttribute(tlsVersionAttribute, tlsVersionName(info.State.Version)),
+		trace.StringAttribute(tlsCipherAttribute, tls.CipherSuiteName(info.State.CipherSuite)),
+	}
+	if len(info.State.VerifiedChains) > 0 && len(info.State.VerifiedChains[0]) > 0 {
+		subject := info.State.VerifiedChains[0][0].Subject.String()
+		attrs = append(attrs, trace.StringAttribute(tlsClientSubjectAttribute, subject))
+	}
+	trace.FromContext(ctx).AddAttributes(attrs...)
+}
+
+// tlsVersionName returns the tls.protocol.version value of a TLS version,
+// such as "1.3".
+func tlsVersionName(version uint16) string {
+	switch version {
+	case tls.VersionTLS10:
+		return "1.0"
+	case tls.VersionTLS11:
+		return "1.1"
+	case tls.VersionTLS12:
+		return "1.2"
+	case tls.VersionTLS13:
+		return "1.3"
+	}
+	return fmt.Sprintf("0x%04x", version)
+}
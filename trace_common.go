@@ -15,14 +15,17 @@
 package ocgrpc
 
 import (
-	"encoding/hex"
-	"fmt"
+	"context"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	propag "github.com/akhenakh/ocgrpc_propagation/propagation"
 	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	ocstats "go.opencensus.io/stats"
+	"go.opencensus.io/tag"
 	"go.opencensus.io/trace"
-	"go.opencensus.io/trace/propagation"
-	"golang.org/x/net/context"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
@@ -30,23 +33,186 @@ import (
 	"google.golang.org/grpc/status"
 )
 
+const jaegerContextKey = propag.JaegerKey
+
+// traceIDAttribute is the client span attribute recording the full trace ID
+// when ClientHandler.TraceID64 is set.
+const traceIDAttribute = "grpc.trace_id"
+
+// DefaultSpanName returns the name of the span of the RPC described by rti,
+// its full method name without leading slash and with the other slashes
+// replaced by dots, such as pkg.Service.Method.
+func DefaultSpanName(rti *stats.RPCTagInfo) string {
+	if name, ok := spanNames.Load(rti.FullMethodName); ok {
+		return name.(string)
+	}
+	name := strings.TrimPrefix(rti.FullMethodName, "/")
+	name = strings.Replace(name, "/", ".", -1)
+	// Callers control the method names received by servers, so the cache
+	// is bounded.
+	if atomic.AddInt64(&spanNamesLen, 1) <= maxSpanNames {
+		spanNames.Store(rti.FullMethodName, name)
+	}
+	return name
+}
+
+// maxSpanNames bounds the number of method names whose span name is cached.
+const maxSpanNames = 1024
+
+// spanNames caches the span names returned by DefaultSpanName by full method
+// name, since the set of methods is small and names are needed for every
+// RPC. spanNamesLen counts the names stored in it.
+var (
+	spanNames    sync.Map
+	spanNamesLen int64 // access atomically
+)
+
+// FullMethodSpanName returns the full method name of the RPC described by
+// rti as the name of its span, keeping the slashes: /pkg.Service/Method. Use
+// it as the FormatSpanName of the handlers.
+func FullMethodSpanName(rti *stats.RPCTagInfo) string {
+	return rti.FullMethodName
+}
+
+// SpanNameSeparator returns a FormatSpanName naming spans after the service
+// and method of their RPC joined by sep, such as pkg.Service::Method for
+// "::".
+func SpanNameSeparator(sep string) func(*stats.RPCTagInfo) string {
+	return func(rti *stats.RPCTagInfo) string {
+		service, method := splitMethodName(rti.FullMethodName)
+		return service + sep + method
+	}
+}
+
+// splitMethodName returns the service and method of the full method name
+// fullMethod, such as pkg.Service and Method for /pkg.Service/Method.
+func splitMethodName(fullMethod string) (service, method string) {
+	name := strings.TrimPrefix(fullMethod, "/")
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		return name[:i], name[i+1:]
+	}
+	return "", name
+}
+
+// Attributes recording the service and method of an RPC, whatever the name
+// of its span, so traces can be filtered by attribute.
 const (
-	traceContextKey  = "grpc-trace-bin"
-	jaegerContextKey = "uber-trace-id"
+	rpcServiceAttribute = "rpc.service"
+	rpcMethodAttribute  = "rpc.method"
 )
 
+// addMethodAttributes records the service and method of the RPC of
+// fullMethod on span.
+func addMethodAttributes(span *trace.Span, fullMethod string) {
+	if !span.IsRecordingEvents() {
+		return
+	}
+	service, method := splitMethodName(fullMethod)
+	span.AddAttributes(
+		trace.StringAttribute(rpcServiceAttribute, service),
+		trace.StringAttribute(rpcMethodAttribute, method))
+}
+
+// spanName returns the name of the span of the RPC described by rti, as
+// formatted by format if not nil.
+func spanName(format func(*stats.RPCTagInfo) string, rti *stats.RPCTagInfo) string {
+	if format != nil {
+		return format(rti)
+	}
+	return DefaultSpanName(rti)
+}
+
 // TagRPC creates a new trace span for the client side of the RPC.
 //
 // It returns ctx with the new trace span added and a serialization of the
 // SpanContext added to the outgoing gRPC metadata.
 func (c *ClientHandler) traceTagRPC(ctx context.Context, rti *stats.RPCTagInfo) context.Context {
-	name := strings.TrimPrefix(rti.FullMethodName, "/")
-	name = strings.Replace(name, "/", ".", -1)
-	ctx, span := trace.StartSpan(ctx, name,
-		trace.WithSampler(c.StartOptions.Sampler),
-		trace.WithSpanKind(trace.SpanKindClient)) // span is ended by traceHandleRPC
-	traceContextBinary := propagation.Binary(span.SpanContext())
-	return metadata.AppendToOutgoingContext(ctx, traceContextKey, string(traceContextBinary))
+	name := spanName(c.FormatSpanName, rti)
+	sampler, kind := clientStartOptions(ctx, c.sampler(rti.FullMethodName))
+	skipUnsampled := c.SkipUnsampledRoots && sampler != nil && trace.FromContext(ctx) == nil
+	spanCtx, span := trace.StartSpan(ctx, name,
+		trace.WithSampler(sampler),
+		trace.WithSpanKind(kind)) // span is ended by traceHandleRPC
+	if skipUnsampled && !span.SpanContext().IsSampled() {
+		// The sampler decided on the IDs of span, which is dropped: unsampled
+		// spans are exported nowhere.
+		return context.WithValue(ctx, untracedKey{client: true}, true)
+	}
+	ctx = spanCtx
+	defer endSpanOnPanic(span)
+	addMethodAttributes(span, rti.FullMethodName)
+	if t := targetFromContext(ctx); t != "" {
+		span.AddAttributes(trace.StringAttribute(targetAttribute, t))
+	}
+	if n := attemptFromContext(ctx); n > 0 {
+		span.AddAttributes(trace.Int64Attribute(attemptAttribute, n))
+	}
+	if c.TraceID64 {
+		span.AddAttributes(trace.StringAttribute(traceIDAttribute, span.SpanContext().TraceID.String()))
+	}
+	spanStarted(ctx, c.OnSpanStart, c.Logger, span, rti)
+	ctx = newTraceDataContext(ctx, rti.FullMethodName)
+	c.SpanLimits.limit(span, traceDataFromContext(ctx))
+	if c.MessageSpans {
+		ctx = newMessageSpansContext(ctx)
+	}
+	return c.InjectSpanContext(ctx, span.SpanContext())
+}
+
+// propagators returns c.Propagators, or the formats enabled by the Inject
+// fields of c.
+func (c *ClientHandler) propagators() []propag.Propagator {
+	if c.Propagators != nil {
+		return c.Propagators
+	}
+	ps := []propag.Propagator{propag.BinaryPropagator{}}
+	if c.InjectTraceContext {
+		ps = append(ps, propag.TraceContextPropagator{})
+	}
+	if c.InjectB3 {
+		ps = append(ps, propag.B3Propagator{TraceID64: c.TraceID64})
+	}
+	if c.InjectB3Single {
+		ps = append(ps, propag.B3SinglePropagator{TraceID64: c.TraceID64})
+	}
+	if c.InjectJaeger {
+		ps = append(ps, propag.JaegerPropagator{Options: c.Jaeger})
+	}
+	if c.InjectXRay {
+		ps = append(ps, propag.XRayPropagator{})
+	}
+	if c.InjectCloudTrace {
+		ps = append(ps, propag.CloudTracePropagator{})
+	}
+	return ps
+}
+
+// extractors returns ps with the Extractors of the CompositePropagators
+// expanded in place.
+func extractors(ps []propag.Propagator) []propag.Propagator {
+	var out []propag.Propagator
+	for _, p := range ps {
+		if c, ok := p.(propag.CompositePropagator); ok {
+			out = append(out, extractors(c.Extractors)...)
+		} else {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// injectors returns ps with the Injectors of the CompositePropagators
+// expanded in place.
+func injectors(ps []propag.Propagator) []propag.Propagator {
+	var out []propag.Propagator
+	for _, p := range ps {
+		if c, ok := p.(propag.CompositePropagator); ok {
+			out = append(out, injectors(c.Injectors)...)
+		} else {
+			out = append(out, p)
+		}
+	}
+	return out
 }
 
 // TagRPC creates a new trace span for the server side of the RPC.
@@ -57,138 +223,429 @@ func (c *ClientHandler) traceTagRPC(ctx context.Context, rti *stats.RPCTagInfo)
 // It returns ctx, with the new trace span added.
 func (s *ServerHandler) traceTagRPC(ctx context.Context, rti *stats.RPCTagInfo) context.Context {
 	md, _ := metadata.FromIncomingContext(ctx)
-	name := strings.TrimPrefix(rti.FullMethodName, "/")
-	name = strings.Replace(name, "/", ".", -1)
-	traceContext := md[traceContextKey]
-	var (
-		parent     trace.SpanContext
-		haveParent bool
-	)
-	if len(traceContext) > 0 {
-		// Metadata with keys ending in -bin are actually binary. They are base64
-		// encoded before being put on the wire, see:
-		// https://github.com/grpc/grpc-go/blob/08d6261/Documentation/grpc-metadata.md#storing-binary-data-in-metadata
-		traceContextBinary := []byte(traceContext[0])
-		parent, haveParent = propagation.FromBinary(traceContextBinary)
-		if haveParent && !s.IsPublicEndpoint {
-			ctx, _ := trace.StartSpanWithRemoteParent(ctx, name, parent,
-				trace.WithSpanKind(trace.SpanKindServer),
-				trace.WithSampler(s.StartOptions.Sampler),
-			)
-			return ctx
-		}
-	}
-
-	// Propagate Jaeger incoming traces
-	if jaegerContext, ok := md[jaegerContextKey]; ok {
-		if !haveParent && len(jaegerContext) > 0 {
-			parent, haveParent = spanContextFromJaeger(jaegerContext[0])
-			if haveParent && !s.IsPublicEndpoint {
-				ctx, _ := trace.StartSpanWithRemoteParent(ctx, name, parent,
-					trace.WithSpanKind(trace.SpanKindServer),
-					trace.WithSampler(s.StartOptions.Sampler),
-				)
-				return ctx
-			}
+	name := spanName(s.FormatSpanName, rti)
+	parent, format, conflicting, failed, errs := s.extractParent(ctx, rti, md)
+	haveParent := format != ""
+	linkOnly := false
+	if haveParent && s.isPublicEndpoint(ctx, rti) {
+		switch s.untrustedParentPolicy(ctx, rti, parent) {
+		case ChildOfUntrustedParent:
+			// The parent is used as if trusted.
+		case DropUntrustedParent:
+			haveParent = false
+		default:
+			linkOnly = true
 		}
 	}
-
-	ctx, span := trace.StartSpan(ctx, name,
-		trace.WithSpanKind(trace.SpanKindServer),
-		trace.WithSampler(s.StartOptions.Sampler))
+	if haveParent && !s.verifyParent(ctx, rti, md, parent) {
+		if s.OnSignatureFailure == InvalidateUnsignedParent {
+			haveParent = false
+			failed = append(failed, "signature")
+		}
+		errs = append(errs, errInvalidSignature)
+		linkOnly = true
+	}
+	if haveParent && !linkOnly && parent.IsSampled() && !s.parents.allow(ctx, s.SampledParentsPerPeer) {
+		// Let the local sampler decide.
+		linkOnly = true
+	}
+	sampler := s.spanSampler(ctx, rti.FullMethodName)
+	reason := SamplingReasonSampler
+	if !haveParent && len(failed) > 0 {
+		reason = SamplingReasonMalformedParent
+	}
+	if s.RespectUpstreamSamplingDecision && haveParent && !linkOnly {
+		sampler = parentSampler(parent)
+		reason = SamplingReasonParent
+	}
+	priority, havePriority := s.samplingPriority(md)
+	havePriority = havePriority && haveParent && !linkOnly
+	if havePriority {
+		sampler = prioritySampler(priority)
+		reason = SamplingReasonPriority
+	}
+	debugID := s.jaegerDebugID(md)
+	sampler = debugSampler(debugID, sampler)
+	forced := s.forceTrace(md)
+	debugFlag := haveParent && !linkOnly && s.jaegerDebugFlag(parent)
+	if forced || debugFlag {
+		sampler = trace.AlwaysSample()
+	}
+	if debugID != "" || forced || debugFlag {
+		reason = SamplingReasonForced
+		if s.SamplingPriorityKey != "" {
+			priority, havePriority = SamplingPriorityUserKeep, true
+		}
+	}
+	var span *trace.Span
+	if haveParent && !linkOnly {
+		ctx, span = trace.StartSpanWithRemoteParent(ctx, name, parent,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithSampler(sampler),
+		)
+	} else {
+		ctx, span = trace.StartSpan(ctx, name,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithSampler(sampler))
+		if haveParent {
+			span.AddLink(trace.Link{TraceID: parent.TraceID, SpanID: parent.SpanID, Type: trace.LinkTypeChild})
+		} else if len(failed) > 0 {
+			ctx = s.handleExtractionFailure(ctx, span, failed)
+		}
+	}
+	defer endSpanOnPanic(span)
 	if haveParent {
-		span.AddLink(trace.Link{TraceID: parent.TraceID, SpanID: parent.SpanID, Type: trace.LinkTypeChild})
+		s.linkConflictingParents(span, conflicting)
+	}
+	addMethodAttributes(span, rti.FullMethodName)
+	if debugID != "" {
+		span.AddAttributes(trace.StringAttribute(jaegerDebugIDAttribute, debugID))
+	}
+	if forced {
+		span.AddAttributes(trace.BoolAttribute(forcedAttribute, true))
+	}
+	if debugFlag {
+		span.AddAttributes(trace.BoolAttribute(jaegerDebugFlagAttribute, true))
+	}
+	if havePriority {
+		span.AddAttributes(trace.Int64Attribute(samplingPriorityAttribute, int64(priority)))
+		ctx = withSamplingPriority(ctx, priority)
 	}
+	addDeadlineAttribute(ctx, span)
+	trackConnSpan(ctx, span)
+	s.tenantSpanStarted(ctx, span)
+	spanStarted(ctx, s.OnSpanStart, s.Logger, span, rti)
+	s.recordSamplingDecision(ctx, rti, SamplingDecision{Sampled: span.SpanContext().IsSampled(), Reason: reason})
+	if s.Decisions != nil {
+		s.Decisions.add(rti.FullMethodName, format, errs, haveParent && linkOnly, span.SpanContext())
+	}
+	if s.MessageSpans {
+		ctx = newMessageSpansContext(ctx)
+	}
+	ctx = newTraceDataContext(ctx, rti.FullMethodName)
+	s.SpanLimits.limit(span, traceDataFromContext(ctx))
 	return ctx
 }
 
+// trackConnSpan tracks span in the connection of ctx, so it is ended if the
+// connection ends first.
+func trackConnSpan(ctx context.Context, span *trace.Span) {
+	if spans := connSpansFromContext(ctx); spans != nil {
+		spans.add(span)
+	}
+}
+
+// extractParent returns the SpanContext found in the incoming metadata md, and
+// the format it was read from, empty if none. conflicting lists the span
+// contexts of other traces found in md, when s.OnConflictingParents needs all
+// the formats to be read. failed lists the formats present in md that
+// couldn't be used, and errs why.
+func (s *ServerHandler) extractParent(ctx context.Context, rti *stats.RPCTagInfo, md metadata.MD) (parent trace.SpanContext, format string, conflicting []trace.SpanContext, failed []string, errs []error) {
+	var candidates []candidateParent
+	for _, p := range s.extractionOrder() {
+		if sc, ok := p.Extract(md); ok {
+			if v, ok := p.(propag.DuplicateValidator); ok {
+				for _, err := range v.MalformedDuplicates(md) {
+					s.recordInvalidSpanContext(ctx, rti, propag.NameOf(p), err)
+				}
+			}
+			if s.OnConflictingParents == PreferFirstFormat {
+				return sc, propag.NameOf(p), nil, nil, nil
+			}
+			candidates = append(candidates, candidateParent{sc: sc, format: propag.NameOf(p)})
+			continue
+		}
+		if len(candidates) > 0 {
+			continue
+		}
+		v, ok := p.(propag.Validator)
+		if !ok {
+			continue
+		}
+		if err := v.Validate(md); err != nil {
+			format := propag.NameOf(p)
+			s.recordInvalidSpanContext(ctx, rti, format, err)
+			failed = append(failed, format)
+			errs = append(errs, err)
+		}
+	}
+	if len(candidates) > 0 {
+		chosen, conflicting := s.chooseParent(candidates)
+		return chosen.sc, chosen.format, conflicting, nil, nil
+	}
+	return trace.SpanContext{}, "", nil, failed, errs
+}
+
+// parentSampler returns the sampler keeping the sampling decision of parent.
+func parentSampler(parent trace.SpanContext) trace.Sampler {
+	if parent.IsSampled() {
+		return trace.AlwaysSample()
+	}
+	return trace.NeverSample()
+}
+
+// propagators returns s.Propagators, or the default formats read by s.
+func (s *ServerHandler) propagators() []propag.Propagator {
+	if s.Propagators != nil {
+		return s.Propagators
+	}
+	return []propag.Propagator{
+		propag.BinaryPropagator{DecodeBase64: s.DecodeBase64Binary},
+		propag.GRPCWebPropagator{},
+		propag.JaegerPropagator{Options: s.Jaeger},
+		propag.TraceContextPropagator{},
+		// The single header is preferred to the multi-header when both
+		// are sent.
+		propag.B3SinglePropagator{},
+		propag.B3Propagator{},
+		propag.XRayPropagator{},
+		propag.CloudTracePropagator{},
+		propag.OTSpanContextPropagator{},
+	}
+}
+
+// recordInvalidSpanContext records an incoming span context in format that
+// couldn't be used, either because it is malformed or because its trace or
+// span ID is invalid. err tells why, and is logged if
+// s.LogInvalidSpanContexts is set.
+func (s *ServerHandler) recordInvalidSpanContext(ctx context.Context, rti *stats.RPCTagInfo, format string, err error) {
+	if s.LogInvalidSpanContexts {
+		warningf(s.Logger, "opencensus: ignoring %s span context of %s: %v", format, rti.FullMethodName, err)
+	}
+	ocstats.RecordWithTags(ctx,
+		[]tag.Mutator{
+			tag.Upsert(KeyServerMethod, methodName(rti.FullMethodName)),
+			tag.Upsert(KeyServerPropagationFormat, format),
+		},
+		ServerInvalidSpanContexts.M(1))
+}
+
 // JaegerTracePropagateUnaryInterceptor propagates incoming Jaeger trace to gRPC client
 func JaegerTracePropagateUnaryInterceptor() grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-		if md, ok := metadata.FromIncomingContext(ctx); ok {
-			if trace, ok := md[jaegerContextKey]; ok && len(trace) > 0 {
-				ctx = metadata.AppendToOutgoingContext(ctx, jaegerContextKey, trace[0])
-			}
-		}
-		return handler(ctx, req)
+		return handler(forwardJaegerContext(ctx), req)
 	}
 }
 
 // JaegerTracePropagateStreamInterceptor propagates incoming Jaeger trace to gRPC client
 func JaegerTracePropagateStreamInterceptor() grpc.StreamServerInterceptor {
 	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
-		var newCtx = stream.Context()
-		if md, ok := metadata.FromIncomingContext(stream.Context()); ok {
-			if trace, ok := md[jaegerContextKey]; ok && len(trace) > 0 {
-				newCtx = metadata.AppendToOutgoingContext(newCtx, jaegerContextKey, trace[0])
-			}
-		}
 		wrapped := grpc_middleware.WrapServerStream(stream)
-		wrapped.WrappedContext = newCtx
+		wrapped.WrappedContext = forwardJaegerContext(stream.Context())
 		return handler(srv, wrapped)
 	}
 }
 
-func spanContextFromJaeger(jv string) (parent trace.SpanContext, ok bool) {
-	parts := strings.Split(jv, ":")
-	if len(parts) == 4 {
-		b, err := hexDecodePadded(parts[0])
-		if err != nil {
-			return parent, false
-		}
-		if len(b) <= 8 {
-			// The lower 64-bits.
-			start := 8 + (8 - len(b))
-			copy(parent.TraceID[start:], b)
-		} else {
-			start := 16 - len(b)
-			copy(parent.TraceID[start:], b)
-		}
+// JaegerTraceInjectUnaryClientInterceptor injects the span context of the
+// current span of ctx into the outgoing uber-trace-id metadata, for clients
+// not using ClientHandler.
+func JaegerTraceInjectUnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(injectJaegerContext(ctx), method, req, reply, cc, opts...)
+	}
+}
 
-		b, err = hexDecodePadded(parts[1])
-		if err != nil {
-			return parent, false
-		}
-		start := 8 - len(b)
-		copy(parent.SpanID[start:], b)
-		if parts[3] == "1" {
-			parent.TraceOptions = trace.TraceOptions(1)
-		} else {
-			parent.TraceOptions = trace.TraceOptions(0)
-		}
+// JaegerTraceInjectStreamClientInterceptor injects the span context of the
+// current span of ctx into the outgoing uber-trace-id metadata, for clients
+// not using ClientHandler.
+func JaegerTraceInjectStreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(injectJaegerContext(ctx), desc, cc, method, opts...)
 	}
+}
 
-	return parent, true
+// injectJaegerContext returns ctx with the span context of its current span,
+// if any, in the outgoing uber-trace-id metadata.
+func injectJaegerContext(ctx context.Context) context.Context {
+	span := trace.FromContext(ctx)
+	if span == nil {
+		return ctx
+	}
+	md, _ := metadata.FromOutgoingContext(ctx)
+	md = md.Copy()
+	propag.JaegerPropagator{}.Inject(span.SpanContext(), md)
+	return metadata.NewOutgoingContext(ctx, md)
 }
 
-func hexDecodePadded(h string) ([]byte, error) {
-	if len(h)%2 != 0 {
-		h = fmt.Sprintf("0%s", h)
+// forwardJaegerContext returns ctx with the incoming uber-trace-id metadata
+// copied to the outgoing metadata, replacing any value already there.
+func forwardJaegerContext(ctx context.Context) context.Context {
+	in, _ := metadata.FromIncomingContext(ctx)
+	vs := propag.Lookup(in, jaegerContextKey)
+	if len(vs) == 0 {
+		return ctx
 	}
-	return hex.DecodeString(h)
+	md, _ := metadata.FromOutgoingContext(ctx)
+	md = md.Copy()
+	md.Set(jaegerContextKey, vs[0])
+	return metadata.NewOutgoingContext(ctx, md)
 }
 
-func traceHandleRPC(ctx context.Context, rs stats.RPCStats) {
-	span := trace.FromContext(ctx)
-	// TODO: compressed and uncompressed sizes are not populated in every message.
+// The OpenCensus trace API doesn't allow setting the start and end times of a
+// span, which are the times TagRPC and the end of the RPC are processed. The
+// times gRPC reports in stats.Begin and stats.End are recorded in these
+// attributes, as Unix times in nanoseconds, and should be preferred when
+// measuring latency.
+const (
+	beginTimeAttribute = "grpc.begin_time"
+	endTimeAttribute   = "grpc.end_time"
+)
+
+// Attributes recording the kind of RPC reported by stats.Begin: both are false
+// for unary RPCs, and set for bidirectional streams.
+const (
+	clientStreamAttribute = "grpc.client_stream"
+	serverStreamAttribute = "grpc.server_stream"
+)
+
+// Attributes recording the wire length of the received header and trailer,
+// and the size of the sent ones, see metadataSize: gRPC doesn't report the
+// wire length of the header and trailer it sends.
+const (
+	inHeaderWireLengthAttribute  = "grpc.in_header_wire_length"
+	inTrailerWireLengthAttribute = "grpc.in_trailer_wire_length"
+	outHeaderSizeAttribute       = "grpc.out_header_size"
+	outTrailerSizeAttribute      = "grpc.out_trailer_size"
+)
+
+// traceOptions holds the handler settings used by traceHandleRPC.
+type traceOptions struct {
+	classifyContextErrors bool
+	errorCodes            map[codes.Code]bool
+	peerAddress           peerAddressOptions
+	transportAttributes   bool
+	payloads              *PayloadAnnotations
+	status                statusOptions
+	disableMessageEvents  bool
+	messageEventLimit     MessageEventLimit
+	messageGapThreshold   time.Duration
+	spanLimits            SpanLimits
+	onEnd                 func(ctx context.Context, span *trace.Span, end *stats.End)
+}
+
+func traceHandleRPC(ctx context.Context, rs stats.RPCStats, opts traceOptions) {
+	raw := trace.FromContext(ctx)
+	span := traceDataFromContext(ctx).span(raw)
 	switch rs := rs.(type) {
 	case *stats.Begin:
 		span.AddAttributes(
 			trace.BoolAttribute("Client", rs.Client),
-			trace.BoolAttribute("FailFast", rs.FailFast))
+			trace.BoolAttribute("FailFast", rs.FailFast),
+			trace.BoolAttribute(clientStreamAttribute, rs.IsClientStream),
+			trace.BoolAttribute(serverStreamAttribute, rs.IsServerStream),
+			trace.Int64Attribute(beginTimeAttribute, rs.BeginTime.UnixNano()))
+		if rs.IsTransparentRetryAttempt {
+			span.AddAttributes(trace.BoolAttribute(transparentRetryAttribute, true))
+		}
+		if m := messageSpansFromContext(ctx); m != nil {
+			m.begin(rs.IsClientStream || rs.IsServerStream)
+		}
+		if d := traceDataFromContext(ctx); d != nil && rs.Client {
+			d.setBegin(rs.BeginTime)
+		}
+	case *stats.InHeader:
+		span.Annotate([]trace.Attribute{trace.Int64Attribute("wire_length", int64(rs.WireLength))}, "Received header")
+		span.AddAttributes(trace.Int64Attribute(inHeaderWireLengthAttribute, int64(rs.WireLength)))
+		if addr, ok := opts.peerAddress.format(rs.RemoteAddr); ok && !rs.Client {
+			span.AddAttributes(trace.StringAttribute(peerAddressAttribute, addr))
+		}
+		if opts.transportAttributes && !rs.Client {
+			span.AddAttributes(transportAttributes(rs.LocalAddr, rs.RemoteAddr, false)...)
+		}
+	case *stats.OutHeader:
+		span.Annotate(nil, "Sent header")
+		span.AddAttributes(trace.Int64Attribute(outHeaderSizeAttribute, metadataSize(rs.Header)))
+		if d := traceDataFromContext(ctx); d != nil && rs.Client {
+			if wait, ok := d.sinceBegin(); ok {
+				span.AddAttributes(trace.Int64Attribute(transportWaitAttribute, wait.Milliseconds()))
+			}
+		}
+		if opts.transportAttributes && rs.Client {
+			span.AddAttributes(transportAttributes(rs.LocalAddr, rs.RemoteAddr, true)...)
+		}
+	case *stats.InTrailer:
+		span.Annotate([]trace.Attribute{trace.Int64Attribute("wire_length", int64(rs.WireLength))}, "Received trailer")
+		span.AddAttributes(trace.Int64Attribute(inTrailerWireLengthAttribute, int64(rs.WireLength)))
+		if d := traceDataFromContext(ctx); d != nil {
+			d.setTrailerReceived()
+		}
+	case *stats.OutTrailer:
+		span.Annotate(nil, "Sent trailer")
+		if !rs.Client {
+			span.AddAttributes(trace.Int64Attribute(outTrailerSizeAttribute, metadataSize(rs.Trailer)))
+		}
 	case *stats.InPayload:
-		span.AddMessageReceiveEvent(0 /* TODO: messageID */, int64(rs.Length), int64(rs.WireLength))
+		opts.payloads.annotate(span, rs.Payload, "Received message")
+		uncompressed, compressed := payloadSizes(rs.Length, rs.CompressedLength, rs.WireLength)
+		if m := messageSpansFromContext(ctx); m != nil {
+			m.message(ctx, span, rs.Client, false, uncompressed)
+		}
+		if d := traceDataFromContext(ctx); d != nil && opts.messageGapThreshold > 0 {
+			d.receiveGap(span, rs.RecvTime, opts.messageGapThreshold)
+		}
+		if opts.disableMessageEvents {
+			return
+		}
+		if d := traceDataFromContext(ctx); d != nil {
+			d.addMessageReceiveEvent(span, opts.messageEventLimit, uncompressed, compressed)
+		}
 	case *stats.OutPayload:
-		span.AddMessageSendEvent(0, int64(rs.Length), int64(rs.WireLength))
+		opts.payloads.annotate(span, rs.Payload, "Sent message")
+		uncompressed, compressed := payloadSizes(rs.Length, rs.CompressedLength, rs.WireLength)
+		if m := messageSpansFromContext(ctx); m != nil {
+			m.message(ctx, span, rs.Client, true, uncompressed)
+		}
+		if d := traceDataFromContext(ctx); d != nil && opts.messageGapThreshold > 0 {
+			d.sendGap(span, rs.SentTime, opts.messageGapThreshold)
+		}
+		if opts.disableMessageEvents {
+			return
+		}
+		if d := traceDataFromContext(ctx); d != nil {
+			d.addMessageSendEvent(span, opts.messageEventLimit, uncompressed, compressed)
+		}
 	case *stats.End:
+		// Only the server spans are tracked by their connection: a client
+		// RPC made with the context of a server RPC inherits its connSpans.
+		if spans := connSpansFromContext(ctx); spans != nil && !rs.Client && !spans.remove(raw) {
+			// The span was already ended when its connection ended.
+			return
+		}
+		var st trace.Status
 		if rs.Error != nil {
-			s, ok := status.FromError(rs.Error)
-			if ok {
-				span.SetStatus(trace.Status{Code: int32(s.Code()), Message: s.Message()})
-			} else {
-				span.SetStatus(trace.Status{Code: int32(codes.Internal), Message: rs.Error.Error()})
+			st = opts.status.status(rs.Error)
+			if s, ok := status.FromError(rs.Error); ok {
+				annotateStatusDetails(span, s)
+			}
+			if opts.classifyContextErrors {
+				st = classifyContextError(ctx, span, rs, st)
+			}
+			annotateDeadlineExceeded(ctx, span, rs, st)
+			if opts.errorCodes == nil || opts.errorCodes[codes.Code(st.Code)] {
+				span.SetStatus(st)
 			}
 		}
+		// The attributes recorded at the end of the RPC are always recorded:
+		// they are added to raw, exempt from the SpanLimits.
+		d := traceDataFromContext(ctx)
+		raw.AddAttributes(
+			trace.StringAttribute(endReasonAttribute, endReason(ctx, rs, d != nil && d.hasTrailer())),
+			trace.Int64Attribute(endTimeAttribute, rs.EndTime.UnixNano()),
+			trace.Int64Attribute(statusCodeAttribute, int64(st.Code)),
+			trace.StringAttribute(statusNameAttribute, statusCodeToString(status.New(codes.Code(st.Code), ""))))
+		if !opts.disableMessageEvents {
+			opts.messageEventLimit.addMessageCounts(raw, traceDataFromContext(ctx))
+		}
+		if d := traceDataFromContext(ctx); d != nil && opts.messageGapThreshold > 0 {
+			d.addMaxGaps(raw)
+		}
+		if m := messageSpansFromContext(ctx); m != nil {
+			m.end()
+		}
+		opts.spanLimits.addDropped(ctx, raw, d, rs.Client)
+		if opts.onEnd != nil {
+			opts.onEnd(ctx, span, rs)
+		}
 		span.End()
 	}
 }
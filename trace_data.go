@@ -0,0 +1,128 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.opencensus.io/trace"
+)
+
+type traceDataKey struct{}
+
+// rpcTraceData holds the per-RPC state of the trace instrumentation. Streams
+// may send and receive messages concurrently, so the state is only accessed
+// with mu held, apart from ids.
+type rpcTraceData struct {
+	// ids are the message IDs of the RPC. They are incremented with mu held,
+	// so the message events are added in the order of their IDs, but may be
+	// read without it.
+	ids messageIDs
+
+	mu sync.Mutex
+
+	// lastSent and lastReceived are the times of the last message sent and
+	// received, and maxSendGap and maxReceiveGap the longest time between
+	// two messages, tracked when a MessageGapThreshold is set.
+	lastSent, lastReceived    time.Time
+	maxSendGap, maxReceiveGap time.Duration
+
+	// begin is the time the RPC began, as reported by stats.Begin.
+	begin time.Time
+
+	// trailerReceived is true once a client RPC received the trailer of the
+	// server.
+	trailerReceived bool
+
+	// method is the full name of the method of the RPC.
+	method string
+
+	// limits counts the events of the span checked against SpanLimits, and
+	// limited is the span limited by them, set once by TagRPC.
+	limits  spanLimitCounts
+	limited *trace.Span
+}
+
+func newTraceDataContext(ctx context.Context, method string) context.Context {
+	return context.WithValue(ctx, traceDataKey{}, &rpcTraceData{method: method})
+}
+
+func traceDataFromContext(ctx context.Context) *rpcTraceData {
+	d, _ := ctx.Value(traceDataKey{}).(*rpcTraceData)
+	return d
+}
+
+// addMessageSendEvent adds a send event with the next sent message ID to
+// span, unless limit drops it. The event is added with mu held, so the events
+// of the span are in the order of their IDs.
+func (d *rpcTraceData) addMessageSendEvent(span *trace.Span, limit MessageEventLimit, uncompressed, compressed int64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	id := d.ids.nextSent()
+	if limit.keep(id) {
+		span.AddMessageSendEvent(id, uncompressed, compressed)
+	}
+}
+
+// addMessageReceiveEvent adds a receive event with the next received message
+// ID to span, unless limit drops it. The event is added with mu held, so the
+// events of the span are in the order of their IDs.
+func (d *rpcTraceData) addMessageReceiveEvent(span *trace.Span, limit MessageEventLimit, uncompressed, compressed int64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	id := d.ids.nextReceived()
+	if limit.keep(id) {
+		span.AddMessageReceiveEvent(id, uncompressed, compressed)
+	}
+}
+
+// setBegin records the time the RPC began at.
+func (d *rpcTraceData) setBegin(at time.Time) {
+	d.mu.Lock()
+	d.begin = at
+	d.mu.Unlock()
+}
+
+// sinceBegin returns the time elapsed since the RPC began. ok is false if the
+// begin time isn't known.
+func (d *rpcTraceData) sinceBegin() (elapsed time.Duration, ok bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.begin.IsZero() {
+		return 0, false
+	}
+	return time.Since(d.begin), true
+}
+
+// setTrailerReceived records that the RPC received the trailer of the server.
+func (d *rpcTraceData) setTrailerReceived() {
+	d.mu.Lock()
+	d.trailerReceived = true
+	d.mu.Unlock()
+}
+
+// hasTrailer reports whether the RPC received the trailer of the server.
+func (d *rpcTraceData) hasTrailer() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.trailerReceived
+}
+
+// messageCounts returns the number of messages sent and received so far.
+func (d *rpcTraceData) messageCounts() (sent, received int64) {
+	return d.ids.counts()
+}
@@ -0,0 +1,117 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/akhenakh/ocgrpc_propagation/propagationtest"
+	"go.opencensus.io/trace"
+	"google.golang.org/grpc/stats"
+)
+
+// startTracedRPC returns the context of a sampled span with the trace data
+// of an RPC, as TagRPC creates it.
+func startTracedRPC(t *testing.T) context.Context {
+	t.Helper()
+	ctx, _ := trace.StartSpan(context.Background(), "rpc", trace.WithSampler(trace.AlwaysSample()))
+	return newTraceDataContext(ctx, "/svc/Method")
+}
+
+// sendAndReceive reports n sent and n received messages for the RPC of ctx
+// from 2*n goroutines, like a bidirectional stream calling SendMsg and RecvMsg
+// concurrently.
+func sendAndReceive(ctx context.Context, n int, opts traceOptions) {
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			traceHandleRPC(ctx, &stats.OutPayload{Length: 10, WireLength: 15, SentTime: time.Now()}, opts)
+		}()
+		go func() {
+			defer wg.Done()
+			traceHandleRPC(ctx, &stats.InPayload{Client: true, Length: 20, WireLength: 25, RecvTime: time.Now()}, opts)
+		}()
+	}
+	wg.Wait()
+}
+
+func TestConcurrentMessageEvents(t *testing.T) {
+	e := propagationtest.Install(t)
+	ctx := startTracedRPC(t)
+	const n = 50
+	sendAndReceive(ctx, n, traceOptions{messageGapThreshold: time.Nanosecond})
+	traceHandleRPC(ctx, &stats.End{EndTime: time.Now()}, traceOptions{})
+
+	spans := e.Spans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	next := map[trace.MessageEventType]int64{trace.MessageEventTypeSent: 1, trace.MessageEventTypeRecv: 1}
+	for _, ev := range spans[0].MessageEvents {
+		if ev.MessageID != next[ev.EventType] {
+			t.Fatalf("got message event %d of type %v, want %d: IDs must be sequential in the order of the events", ev.MessageID, ev.EventType, next[ev.EventType])
+		}
+		next[ev.EventType]++
+	}
+	if sent, recv := next[trace.MessageEventTypeSent]-1, next[trace.MessageEventTypeRecv]-1; sent != n || recv != n {
+		t.Errorf("got %d sent and %d received message events, want %d of each", sent, recv, n)
+	}
+}
+
+func TestConcurrentMessageEventLimit(t *testing.T) {
+	e := propagationtest.Install(t)
+	ctx := startTracedRPC(t)
+	const n = 50
+	opts := traceOptions{messageEventLimit: MessageEventLimit{First: 5, Every: 10}}
+	sendAndReceive(ctx, n, opts)
+	traceHandleRPC(ctx, &stats.End{EndTime: time.Now()}, opts)
+
+	spans := e.Spans()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	// The first 5 messages of each direction, then messages 15, 25, 35
+	// and 45.
+	if got, want := len(spans[0].MessageEvents), 2*(5+4); got != want {
+		t.Errorf("got %d message events, want %d", got, want)
+	}
+	if got := spans[0].Attributes[sentMessagesAttribute]; got != int64(n) {
+		t.Errorf("%s = %v, want %d", sentMessagesAttribute, got, n)
+	}
+	if got := spans[0].Attributes[receivedMessagesAttribute]; got != int64(n) {
+		t.Errorf("%s = %v, want %d", receivedMessagesAttribute, got, n)
+	}
+}
+
+func TestConcurrentTraceDataAccessors(t *testing.T) {
+	d := &rpcTraceData{}
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(4)
+		go func() { defer wg.Done(); d.setBegin(time.Now()) }()
+		go func() { defer wg.Done(); d.sinceBegin() }()
+		go func() { defer wg.Done(); d.setTrailerReceived() }()
+		go func() { defer wg.Done(); d.hasTrailer(); d.messageCounts() }()
+	}
+	wg.Wait()
+	if !d.hasTrailer() {
+		t.Error("hasTrailer() = false after setTrailerReceived")
+	}
+}
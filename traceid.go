@@ -0,0 +1,92 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"context"
+
+	"go.opencensus.io/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// DefaultTraceIDKey is the header or trailer key conventionally used to
+// return the trace ID to callers, see ServerHandler.TraceIDKey.
+const DefaultTraceIDKey = "x-trace-id"
+
+// traceIDReturnKey is the context key of the traceIDReturn of an RPC.
+type traceIDReturnKey struct{}
+
+// traceIDReturn is how the trace ID of an RPC is returned to its caller.
+type traceIDReturn struct {
+	md       metadata.MD
+	inHeader bool
+}
+
+// traceIDTagRPC records in ctx how the trace ID of the span of ctx is
+// returned to the caller, if s returns the trace IDs. The trace IDs of
+// unsampled spans aren't returned, since their traces aren't exported.
+func (s *ServerHandler) traceIDTagRPC(ctx context.Context) context.Context {
+	if s.TraceIDKey == "" {
+		return ctx
+	}
+	span := trace.FromContext(ctx)
+	if span == nil || !span.SpanContext().IsSampled() {
+		return ctx
+	}
+	return context.WithValue(ctx, traceIDReturnKey{}, traceIDReturn{
+		md:       metadata.Pairs(s.TraceIDKey, span.SpanContext().TraceID.String()),
+		inHeader: s.TraceIDInHeader,
+	})
+}
+
+// TraceIDUnaryInterceptor returns the trace ID of each RPC to the caller, as
+// set by ServerHandler.TraceIDKey and TraceIDInHeader. Stats handlers can't
+// set response metadata, so it is required along the ServerHandler.
+func TraceIDUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		r, ok := ctx.Value(traceIDReturnKey{}).(traceIDReturn)
+		if !ok {
+			return handler(ctx, req)
+		}
+		if r.inHeader {
+			grpc.SetHeader(ctx, r.md)
+			return handler(ctx, req)
+		}
+		resp, err := handler(ctx, req)
+		grpc.SetTrailer(ctx, r.md)
+		return resp, err
+	}
+}
+
+// TraceIDStreamInterceptor returns the trace ID of each streaming RPC to the
+// caller, as set by ServerHandler.TraceIDKey and TraceIDInHeader. Stats
+// handlers can't set response metadata, so it is required along the
+// ServerHandler.
+func TraceIDStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		r, ok := stream.Context().Value(traceIDReturnKey{}).(traceIDReturn)
+		if !ok {
+			return handler(srv, stream)
+		}
+		if r.inHeader {
+			stream.SetHeader(r.md)
+			return handler(srv, stream)
+		}
+		err := handler(srv, stream)
+		stream.SetTrailer(r.md)
+		return err
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"go.opencensus.io/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// checkHealth calls the health service of a server built from c, and returns
+// the header and trailer of the call.
+func checkHealth(t *testing.T, c Config) (header, trailer metadata.MD) {
+	t.Helper()
+	lis := bufconn.Listen(1 << 16)
+	srv := grpc.NewServer(c.ServerOptions()...)
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_, err = healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{},
+		grpc.Header(&header), grpc.Trailer(&trailer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return header, trailer
+}
+
+func TestTraceIDReturned(t *testing.T) {
+	sampled := Config{DefaultSampler: trace.AlwaysSample(), TraceIDKey: DefaultTraceIDKey}
+	header, trailer := checkHealth(t, sampled)
+	if len(header[DefaultTraceIDKey]) != 0 {
+		t.Errorf("trace ID returned in the header %v", header)
+	}
+	if vs := trailer[DefaultTraceIDKey]; len(vs) != 1 || len(vs[0]) != 32 {
+		t.Errorf("trailer %v, want a trace ID in %s", trailer, DefaultTraceIDKey)
+	}
+
+	sampled.TraceIDInHeader = true
+	header, trailer = checkHealth(t, sampled)
+	if vs := header[DefaultTraceIDKey]; len(vs) != 1 || len(vs[0]) != 32 {
+		t.Errorf("header %v, want a trace ID in %s", header, DefaultTraceIDKey)
+	}
+	if len(trailer[DefaultTraceIDKey]) != 0 {
+		t.Errorf("trace ID returned in the trailer %v", trailer)
+	}
+
+	unsampled := Config{DefaultSampler: trace.NeverSample(), TraceIDKey: DefaultTraceIDKey}
+	header, trailer = checkHealth(t, unsampled)
+	if len(header[DefaultTraceIDKey]) != 0 || len(trailer[DefaultTraceIDKey]) != 0 {
+		t.Errorf("trace ID of an unsampled RPC returned: header %v, trailer %v", header, trailer)
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"net"
+	"strconv"
+
+	"go.opencensus.io/trace"
+)
+
+// Attributes recording the connection of an RPC when the TransportAttributes
+// of a handler is set, named after the OpenTelemetry conventions.
+const (
+	transportAttribute = "net.transport"
+	peerPortAttribute  = "net.peer.port"
+	hostIPAttribute    = "net.host.ip"
+	hostPortAttribute  = "net.host.port"
+)
+
+// transportAttributes returns the attributes describing a connection from
+// its local and remote addresses. The remote address is only described when
+// withRemote is true, since the address of callers is recorded according to
+// ServerHandler.PeerAddress.
+func transportAttributes(local, remote net.Addr, withRemote bool) []trace.Attribute {
+	var attrs []trace.Attribute
+	if local != nil {
+		attrs = append(attrs, trace.StringAttribute(transportAttribute, transportName(local.Network())))
+		if ip, port, ok := splitAddr(local); ok {
+			attrs = append(attrs,
+				trace.StringAttribute(hostIPAttribute, ip),
+				trace.Int64Attribute(hostPortAttribute, port))
+		}
+	}
+	if withRemote && remote != nil {
+		if ip, port, ok := splitAddr(remote); ok {
+			attrs = append(attrs,
+				trace.StringAttribute(peerAddressAttribute, ip),
+				trace.Int64Attribute(peerPortAttribute, port))
+		}
+	}
+	return attrs
+}
+
+// transportName returns the net.transport value of a net.Addr network.
+func transportName(network string) string {
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+		return "ip_tcp"
+	case "udp", "udp4", "udp6":
+		return "ip_udp"
+	case "unix", "unixpacket":
+		return "unix"
+	case "pipe", "bufconn":
+		return "inproc"
+	}
+	return "other"
+}
+
+// splitAddr returns the IP and port of addr. ok is false if addr isn't an IP
+// address with a port.
+func splitAddr(addr net.Addr) (ip string, port int64, ok bool) {
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		return a.IP.String(), int64(a.Port), true
+	case *net.UDPAddr:
+		return a.IP.String(), int64(a.Port), true
+	}
+	host, p, err := net.SplitHostPort(addr.String())
+	if err != nil || net.ParseIP(host) == nil {
+		return "", 0, false
+	}
+	port, err = strconv.ParseInt(p, 10, 32)
+	if err != nil {
+		return "", 0, false
+	}
+	return host, port, true
+}
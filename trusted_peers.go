@@ -0,0 +1,90 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import (
+	"context"
+	"net"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/stats"
+)
+
+// TrustedNetworks returns a PublicEndpointFunc trusting the trace contexts of
+// callers within the networks cidrs, such as "10.0.0.0/8": their RPCs get
+// child spans, while the trace contexts of other callers, possibly spoofed,
+// are only linked to. Callers without an IP address, such as over Unix
+// sockets, aren't trusted.
+func TrustedNetworks(cidrs ...string) (PublicEndpointFunc, error) {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, network)
+	}
+	return func(ctx context.Context, _ *stats.RPCTagInfo) bool {
+		p, ok := peer.FromContext(ctx)
+		if !ok || p.Addr == nil {
+			return true
+		}
+		ip := peerIP(p.Addr)
+		if ip == nil {
+			return true
+		}
+		for _, network := range networks {
+			if network.Contains(ip) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+// TrustedTLSNames returns a PublicEndpointFunc trusting the trace contexts of
+// callers whose verified client certificate has one of names among its DNS or
+// URI SANs, such as "spiffe://cluster.local/ns/prod/sa/checkout": their RPCs
+// get child spans, while the trace contexts of other callers are only linked
+// to.
+func TrustedTLSNames(names ...string) PublicEndpointFunc {
+	trusted := make(map[string]bool, len(names))
+	for _, name := range names {
+		trusted[name] = true
+	}
+	return func(ctx context.Context, _ *stats.RPCTagInfo) bool {
+		p, ok := peer.FromContext(ctx)
+		if !ok {
+			return true
+		}
+		info, ok := p.AuthInfo.(credentials.TLSInfo)
+		if !ok || len(info.State.VerifiedChains) == 0 || len(info.State.VerifiedChains[0]) == 0 {
+			return true
+		}
+		cert := info.State.VerifiedChains[0][0]
+		for _, name := range cert.DNSNames {
+			if trusted[name] {
+				return false
+			}
+		}
+		for _, uri := range cert.URIs {
+			if trusted[uri.String()] {
+				return false
+			}
+		}
+		return true
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2018, OpenCensus Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocgrpc
+
+import "go.opencensus.io/stats/view"
+
+// AllServerViews are all the server views provided by this package, including
+// the ones that aren't in DefaultServerViews.
+var AllServerViews = []*view.View{
+	ServerReceivedBytesPerRPCView,
+	ServerSentBytesPerRPCView,
+	ServerReceivedCompressedBytesPerRPCView,
+	ServerSentCompressedBytesPerRPCView,
+	ServerLatencyView,
+	ServerCompletedRPCsView,
+	ServerEndReasonsView,
+	ServerReceivedMessagesPerRPCView,
+	ServerSentMessagesPerRPCView,
+	ServerReceivedHeaderBytesView,
+	ServerSentHeaderBytesView,
+	ServerSentTrailerBytesView,
+	ServerStreamDurationView,
+	ServerStreamSentMessagesView,
+	ServerStreamReceivedMessagesView,
+	ServerActiveRPCsView,
+	ServerInvalidSpanContextsView,
+	ServerBaggageLimitedItemsView,
+	ServerForwardedMetadataLimitedItemsView,
+	ServerSpanLimitDropsView,
+	ServerSamplingDecisionsView,
+}
+
+// AllClientViews are all the client views provided by this package, including
+// the ones that aren't in DefaultClientViews.
+var AllClientViews = []*view.View{
+	ClientSentBytesPerRPCView,
+	ClientReceivedBytesPerRPCView,
+	ClientSentCompressedBytesPerRPCView,
+	ClientReceivedCompressedBytesPerRPCView,
+	ClientRoundtripLatencyView,
+	ClientCompletedRPCsView,
+	ClientEndReasonsView,
+	ClientRoundtripLatencyByTargetView,
+	ClientCompletedRPCsByTargetView,
+	ClientSentMessagesPerRPCView,
+	ClientReceivedMessagesPerRPCView,
+	ClientSentHeaderBytesView,
+	ClientReceivedHeaderBytesView,
+	ClientReceivedTrailerBytesView,
+	ClientStreamDurationView,
+	ClientStreamSentMessagesView,
+	ClientStreamReceivedMessagesView,
+	ClientRetriesView,
+	ClientActiveRPCsView,
+	ClientOpenedConnectionsView,
+	ClientOpenConnectionsView,
+	ClientConnectionDurationView,
+	ClientServerLatencyView,
+	ClientSpanLimitDropsView,
+}
+
+// RegisterServerViews registers AllServerViews. View names map to valid
+// Prometheus metric names, such as grpc_io_server_server_latency, once
+// exported.
+func RegisterServerViews() error {
+	return view.Register(AllServerViews...)
+}
+
+// RegisterClientViews registers AllClientViews.
+func RegisterClientViews() error {
+	return view.Register(AllClientViews...)
+}
+
+// RegisterAllViews registers AllServerViews and AllClientViews.
+func RegisterAllViews() error {
+	if err := RegisterServerViews(); err != nil {
+		return err
+	}
+	return RegisterClientViews()
+}